@@ -1,21 +1,39 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"os"
 	"server/internal/config"
+	"server/internal/gateway"
 	"server/internal/handlers"
 	"server/internal/middleware"
+	"server/internal/oidc"
+	"server/internal/ratelimit"
 	"server/internal/repositories"
 	"server/internal/routes"
+	"server/internal/scheduler"
 	"server/internal/services"
 	"server/internal/utils"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	promoteAdmin := flag.String("promote-admin", "", "promote an existing user (by email) to admin and exit")
+	flag.Parse()
+
 	utils.LoadEnv()
+	if err := utils.InitJWTKeys(); err != nil {
+		log.Fatal("failed to load jwt keys: ", err)
+	}
+	if err := utils.InitTwoFactorKey(); err != nil {
+		log.Fatal("failed to load two-factor key: ", err)
+	}
+	if err := utils.InitRenderTokenSecret(); err != nil {
+		log.Fatal("failed to load submission token secret: ", err)
+	}
 	config.InitRedis()
 	config.InitMailer()
 	config.InitDatabase()
@@ -23,24 +41,67 @@ func main() {
 	config.InitMidtrans()
 
 	db := config.DB
+	middleware.UseRateLimiter(ratelimit.New(config.RedisClient))
 
 	// middleware config
 	r := gin.Default()
+	// tanpa daftar proxy, X-Forwarded-For diabaikan dan ClientIP memakai alamat koneksi langsung
+	if err := r.SetTrustedProxies(utils.GetTrustedProxies()); err != nil {
+		log.Fatal("invalid TRUSTED_PROXIES: ", err)
+	}
 	r.Use(
+		middleware.RequestID(),
 		middleware.Logger(),
 		middleware.Recovery(),
 		middleware.CORS(),
-		middleware.RateLimiter(5, 10),
+		middleware.RateLimit(middleware.PolicyGlobal),
 		middleware.LimitFileSize(12<<20),
-		middleware.APIKeyGateway([]string{"/api/payments"}),
 	)
 
 	// ========== layer ==========
+	// ================== AUDIT LOG ===================
+	auditRepo := repositories.NewAuditRepository(db)
+	auditService := services.NewAuditService(auditRepo, repositories.NewFormRepository(db))
+	auditHandler := handlers.NewAuditHandler(auditService)
+
 	// ================== AUTH ========================
 	authRepo := repositories.NewAuthRepository(db)
-	authService := services.NewAuthService(authRepo)
+	authService := services.NewAuthService(authRepo, oidc.LoadProviders(), auditService)
 	authHandler := handlers.NewAuthHandler(authService)
 
+	if migrated, err := authService.MigrateTwoFactorSecrets(); err != nil {
+		log.Fatal("failed to encrypt two-factor secrets: ", err)
+	} else if migrated > 0 {
+		log.Printf("encrypted %d two-factor secrets", migrated)
+	}
+
+	// ================== API KEYS ====================
+	apiKeyRepo := repositories.NewAPIKeyRepository(db)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, authRepo)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	middleware.UseAPIKeys(apiKeyService)
+
+	// =================== RBAC =======================
+	rbacRepo := repositories.NewRBACRepository(db)
+	rbacService := services.NewRBACService(rbacRepo, authRepo)
+	rbacHandler := handlers.NewRBACHandler(rbacService)
+
+	if err := rbacService.SeedDefaults(); err != nil {
+		log.Fatal("failed to seed role permissions: ", err)
+	}
+	if email := os.Getenv("ADMIN_EMAIL"); email != "" {
+		if err := rbacService.BootstrapAdmin(email, os.Getenv("ADMIN_PASSWORD"), os.Getenv("ADMIN_FULLNAME")); err != nil {
+			log.Fatal("failed to bootstrap admin: ", err)
+		}
+	}
+	// CLI: go run ./cmd -promote-admin user@example.com
+	if *promoteAdmin != "" {
+		if err := rbacService.BootstrapAdmin(*promoteAdmin, "", ""); err != nil {
+			log.Fatal("failed to promote admin: ", err)
+		}
+		return
+	}
+
 	// =================== USER =======================
 	userRepo := repositories.NewUserRepository(db)
 	userService := services.NewUserService(userRepo)
@@ -51,40 +112,75 @@ func main() {
 	queueService := services.NewQueueService(queueRepo)
 	queueHandler := handlers.NewQueueHandler(queueService)
 
+	// ===================== QUOTA ====================
+	quotaRepo := repositories.NewQuotaRepository(db)
+	quotaService := services.NewQuotaService(quotaRepo)
+
 	// ===================== FORM =====================
 	formRepo := repositories.NewFormRepository(db)
-	formService := services.NewFormService(formRepo)
+	formService := services.NewFormService(formRepo, auditService)
 	formHandler := handlers.NewFormHandler(formService)
 
+	// =================== ADMIN SUBSCRIPTION ===========
+	subscriptionRepo := repositories.NewAdminSubscriptionRepository(db)
+	subscriptionService := services.NewAdminSubscriptionService(subscriptionRepo, auditService)
+	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService)
+
 	// ===================== PAYMENT ===================
 	paymentRepo := repositories.NewPaymentRepository(db)
-	paymentService := services.NewPaymentService(paymentRepo, subscriptionRepo, authRepo)
+	paymentGateway := gateway.New(os.Getenv("PAYMENT_GATEWAY"))
+	paymentService := services.NewPaymentService(paymentRepo, subscriptionRepo, authRepo, paymentGateway, auditService)
 	paymentHandler := handlers.NewPaymentHandler(paymentService)
 
+	// ===================== METRICS ===================
+	metricsRepo := repositories.NewMetricsRepository(db)
+	metricsService := services.NewMetricsService(metricsRepo)
+	metricsHandler := handlers.NewMetricsHandler(metricsService)
+
 	// ===================== ANALYTICS =================
 	analyticsRepo := repositories.NewAnalyticsRepository(db)
-	analyticsService := services.NewAnalyticsService(analyticsRepo)
+	analyticsService := services.NewAnalyticsService(analyticsRepo, formRepo)
 	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
 
 	// ===================== SUBMISSION ================
 	submissionRepo := repositories.NewSubmissionRepository(db)
-	submissionService := services.NewSubmissionService(submissionRepo)
+	submissionService := services.NewSubmissionService(submissionRepo, formRepo, quotaService)
 	submissionHandler := handlers.NewSubmissionHandler(submissionService)
 
-	// =================== ADMIN SUBSCRIPTION ===========
-	subscriptionRepo := repositories.NewSubscriptionRepository(db)
-	subscriptionService := services.NewSubscriptionService(subscriptionRepo)
-	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService)
-
 	// ========== Route Binding ==========
 	routes.AuthRoutes(r, authHandler)
-	routes.UserRoutes(r, userHandler)
-	routes.PaymentRoutes(r, paymentHandler)
-	routes.FormRoutes(r, formHandler)
-	routes.QueueRoutes(r, queueHandler)
-	routes.AnalyticsRoutes(r, analyticsHandler)
-	routes.SubmissionRoutes(r, submissionHandler)
-	routes.SubscriptionRoutes(r, subscriptionHandler)
+	if issuerURL := os.Getenv("OIDC_MOCK_ISSUER_URL"); issuerURL != "" && oidc.MockEnabled() {
+		mockIssuer, err := oidc.NewMockIssuer(issuerURL)
+		if err != nil {
+			log.Fatal("failed to start mock oidc issuer: ", err)
+		}
+		routes.MockOIDCRoutes(r, mockIssuer)
+	}
+	routes.UserRoutes(r, userHandler, rbacService)
+	routes.APIKeyRoutes(r, apiKeyHandler, rbacService)
+	routes.PaymentRoutes(r, paymentHandler, rbacService)
+	routes.MetricsRoutes(r, metricsHandler, rbacService)
+	routes.FormRoutes(r, formHandler, quotaService, rbacService)
+	routes.QueueRoutes(r, queueHandler, rbacService)
+	routes.AnalyticsRoutes(r, analyticsHandler, quotaService, rbacService)
+	routes.SubmissionRoutes(r, submissionHandler, quotaService, rbacService)
+	routes.SubscriptionRoutes(r, subscriptionHandler, rbacService)
+	routes.RBACRoutes(r, rbacHandler, rbacService)
+	routes.AuditRoutes(r, auditHandler, rbacService)
+
+	// ========== Scheduled Jobs ==========
+	jobs := scheduler.New()
+	jobs.Register("apply-scheduled-tier-changes", 15*time.Minute, subscriptionService.ApplyScheduledTierChanges)
+	jobs.Register("deactivate-expired-subscriptions", 15*time.Minute, subscriptionService.DeactivateExpiredSubscriptions)
+	jobs.Register("reset-monthly-tokens", time.Hour, subscriptionService.ResetMonthlyTokens)
+	jobs.Register("expire-pending-payments", 30*time.Minute, paymentService.ExpirePendingPayments)
+	jobs.Register("reconcile-payments", 10*time.Minute, paymentService.ReconcilePayments)
+	jobs.Register("purge-expired-tokens", 6*time.Hour, authService.PurgeExpiredTokens)
+	jobs.Register("purge-deleted-accounts", time.Hour, userService.PurgeScheduledAccounts)
+	jobs.Register("enforce-submission-retention", 6*time.Hour, submissionService.EnforceRetention)
+	jobs.Register("apply-form-schedules", time.Minute, formService.ApplyFormSchedules)
+	jobs.Register("send-form-reminders", 15*time.Minute, formService.SendClosingReminders)
+	jobs.Start()
 
 	// ========== Start Server ==========
 	port := os.Getenv("PORT")
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"server/internal/models"
@@ -48,20 +49,40 @@ func InitDatabase() {
 	if err := DB.AutoMigrate(
 		&models.User{},
 		&models.Token{},
+		&models.PasswordReset{},
+		&models.TwoFactorRecoveryCode{},
+		&models.UserIdentity{},
+		&models.APIKey{},
+		&models.SecurityEvent{},
+		&models.AuditLog{},
+		&models.RolePermission{},
 		&models.UserSubscription{},
 		&models.SubscriptionTier{},
+		&models.TokenUsage{},
 		&models.Payment{},
+		&models.Voucher{},
+		&models.VoucherRedemption{},
+		&models.Invoice{},
+		&models.PaymentMismatch{},
+		&models.Refund{},
+		&models.InvoiceSequence{},
 		&models.Form{},
+		&models.FormVersion{},
 		&models.FormSetting{},
+		&models.FormInvitee{},
 		&models.FormSection{},
 		&models.Question{},
 		&models.Option{},
 		&models.Submission{},
 		&models.Answer{},
+		&models.RetentionLog{},
 		&models.Queue{},
 	); err != nil {
 		panic("Migration failed: " + err.Error())
 	}
+	if err := migrateCheckConstraints(); err != nil {
+		panic("Migration failed: " + err.Error())
+	}
 
 	sqlDB, err := DB.DB()
 	if err != nil {
@@ -73,3 +94,39 @@ func InitDatabase() {
 
 	fmt.Println("Database connection established successfully.")
 }
+
+// migrateCheckConstraints membuat ulang CHECK constraint yang daftar nilainya berubah. AutoMigrate hanya
+// membuat constraint yang belum ada, sehingga tabel lama tetap menolak nilai baru seperti partially_refunded.
+func migrateCheckConstraints() error {
+	constraints := []struct {
+		model    interface{}
+		name     string
+		required string // nilai yang harus sudah ada di definisi constraint
+	}{
+		{&models.Payment{}, "chk_payments_status", "partially_refunded"},
+	}
+
+	migrator := DB.Migrator()
+	for _, c := range constraints {
+		var clause string
+		if err := DB.Raw(`SELECT CHECK_CLAUSE FROM information_schema.CHECK_CONSTRAINTS
+			WHERE CONSTRAINT_SCHEMA = DATABASE() AND CONSTRAINT_NAME = ?`, c.name).
+			Scan(&clause).Error; err != nil {
+			return err
+		}
+		if strings.Contains(clause, c.required) {
+			continue
+		}
+
+		if clause != "" {
+			if err := migrator.DropConstraint(c.model, c.name); err != nil {
+				return err
+			}
+		}
+		if err := migrator.CreateConstraint(c.model, c.name); err != nil {
+			return err
+		}
+		fmt.Printf("Recreated constraint %s.\n", c.name)
+	}
+	return nil
+}
@@ -2,14 +2,22 @@ package dto
 
 // AUTHENTICATION
 type RegisterRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=5"`
-	Fullname string `json:"fullname" binding:"required,min=5"`
+	Email             string `json:"email" binding:"required,email"`
+	Password          string `json:"password" binding:"required,min=5"`
+	Fullname          string `json:"fullname" binding:"required,min=5"`
+	VerificationToken string `json:"verificationToken" binding:"required"` // dari response verify-otp
 }
 
 type AuthResponse struct {
 	AccessToken  string `json:"accessToken"`
 	RefreshToken string `json:"refreshToken"`
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int    `json:"expiresIn"` // masa berlaku access token (detik)
+}
+
+// refresh token dari body, untuk client non-browser yang tidak memakai cookie
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"`
 }
 
 type LoginRequest struct {
@@ -26,6 +34,90 @@ type VerifyOTPRequest struct {
 	OTP   string `json:"otp" binding:"required,len=6"`
 }
 
+type VerifyOTPResponse struct {
+	VerificationToken string `json:"verificationToken"`
+	ExpiresIn         int    `json:"expiresIn"` // detik
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required,min=5"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required,min=5"`
+}
+
+type UnlockAccountRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// TWO-FACTOR
+type TwoFactorChallengeResponse struct {
+	ChallengeToken string `json:"challengeToken"`
+	ExpiresIn      int    `json:"expiresIn"` // detik
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"omitempty,len=6,numeric"`
+	RecoveryCode   string `json:"recoveryCode"`
+}
+
+type TwoFactorEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauthUri"`
+	QRCode     string `json:"qrCode"` // data URI PNG
+	ExpiresIn  int    `json:"expiresIn"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type TwoFactorDisableRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// OIDC LOGIN
+type OIDCProviderResponse struct {
+	Name     string `json:"name"`
+	LoginURL string `json:"loginUrl"`
+}
+
+type OIDCCallbackRequest struct {
+	Code  string `form:"code" binding:"required"`
+	State string `form:"state" binding:"required"`
+}
+
+// metadata request yang dicatat bersama sesi
+type RequestMeta struct {
+	IPAddress string
+	UserAgent string
+	ActorID   string // kosong untuk request tanpa login atau proses sistem
+	RequestID string
+}
+
+type SessionResponse struct {
+	ID         string `json:"id"`
+	UserAgent  string `json:"userAgent"`
+	IPAddress  string `json:"ipAddress"`
+	StartedAt  string `json:"startedAt"`
+	LastUsedAt string `json:"lastUsedAt"`
+	ExpiresAt  string `json:"expiresAt"`
+	Current    bool   `json:"current"`
+}
+
 type UserInfoResponse struct {
 	UserID   string `json:"userId"`
 	Email    string `json:"email"`
@@ -61,14 +153,79 @@ type TierResponse struct {
 }
 
 type UserSubscriptionResponse struct {
-	UserID    string `json:"userId"`
-	Email     string `json:"email"`
-	Fullname  string `json:"fullname"`
-	Avatar    string `json:"avatar"`
-	TierName  string `json:"tierName"`
-	IsActive  bool   `json:"isActive"`
-	ExpiresAt string `json:"expiresAt"`
-	Remaining int    `json:"remainingTokens"`
+	UserID        string `json:"userId"`
+	Email         string `json:"email"`
+	Fullname      string `json:"fullname"`
+	Avatar        string `json:"avatar"`
+	TierName      string `json:"tierName"`
+	IsActive      bool   `json:"isActive"`
+	ExpiresAt     string `json:"expiresAt"`
+	Remaining     int    `json:"remainingTokens"`
+	ScheduledTier string `json:"scheduledTier,omitempty"` // tier downgrade yang berlaku di periode berikutnya
+	AutoRenew     bool   `json:"autoRenew"`
+	CanceledAt    string `json:"canceledAt,omitempty"`
+}
+
+type TokenUsageResponse struct {
+	ID           uint   `json:"id"`
+	Action       string `json:"action"`
+	Tokens       int    `json:"tokens"`
+	BalanceAfter int    `json:"balanceAfter"`
+	ReferenceID  string `json:"referenceId,omitempty"`
+	CreatedAt    string `json:"createdAt"`
+}
+
+type TokenUsageListResponse struct {
+	Usages []TokenUsageResponse `json:"usages"`
+	Total  int64                `json:"total"`
+	Page   int                  `json:"page"`
+	Limit  int                  `json:"limit"`
+}
+
+// VOUCHERS
+type CreateVoucherRequest struct {
+	Code                  string   `json:"code" binding:"required,min=3,max=50"`
+	Description           string   `json:"description"`
+	DiscountType          string   `json:"discountType" binding:"required,oneof=percentage fixed"`
+	DiscountValue         float64  `json:"discountValue" binding:"required,gt=0"`
+	MaxDiscount           *float64 `json:"maxDiscount" binding:"omitempty,gt=0"`
+	StartsAt              *string  `json:"startsAt"`  // ISO 8601 format
+	ExpiresAt             *string  `json:"expiresAt"` // ISO 8601 format
+	MaxRedemptions        *int     `json:"maxRedemptions" binding:"omitempty,gte=1"`
+	MaxRedemptionsPerUser *int     `json:"maxRedemptionsPerUser" binding:"omitempty,gte=1"`
+	TierIDs               []uint   `json:"tierIds"`
+	IsActive              *bool    `json:"isActive"`
+}
+
+type UpdateVoucherRequest struct {
+	ID                    uint     `json:"id" binding:"required"`
+	Code                  string   `json:"code" binding:"required,min=3,max=50"`
+	Description           string   `json:"description"`
+	DiscountType          string   `json:"discountType" binding:"required,oneof=percentage fixed"`
+	DiscountValue         float64  `json:"discountValue" binding:"required,gt=0"`
+	MaxDiscount           *float64 `json:"maxDiscount" binding:"omitempty,gt=0"`
+	StartsAt              *string  `json:"startsAt"`
+	ExpiresAt             *string  `json:"expiresAt"`
+	MaxRedemptions        *int     `json:"maxRedemptions" binding:"omitempty,gte=1"`
+	MaxRedemptionsPerUser *int     `json:"maxRedemptionsPerUser" binding:"omitempty,gte=1"`
+	TierIDs               []uint   `json:"tierIds"`
+	IsActive              *bool    `json:"isActive"`
+}
+
+type VoucherResponse struct {
+	ID                    uint     `json:"id"`
+	Code                  string   `json:"code"`
+	Description           string   `json:"description"`
+	DiscountType          string   `json:"discountType"`
+	DiscountValue         float64  `json:"discountValue"`
+	MaxDiscount           *float64 `json:"maxDiscount"`
+	StartsAt              *string  `json:"startsAt"`
+	ExpiresAt             *string  `json:"expiresAt"`
+	MaxRedemptions        *int     `json:"maxRedemptions"`
+	MaxRedemptionsPerUser *int     `json:"maxRedemptionsPerUser"`
+	RedeemedCount         int      `json:"redeemedCount"`
+	TierIDs               []uint   `json:"tierIds"`
+	IsActive              bool     `json:"isActive"`
 }
 
 // PAYMENT
@@ -79,28 +236,35 @@ type CreatePaymentRequest struct {
 }
 
 type CreatePaymentResponse struct {
-	PaymentID string `json:"paymentId"`
-	SnapToken string `json:"snapToken"`
-	SnapURL   string `json:"snapUrl"`
-}
-
-type MidtransNotificationRequest struct {
-	OrderID           string `json:"order_id"`
-	TransactionStatus string `json:"transaction_status"`
-	PaymentType       string `json:"payment_type"`
-	FraudStatus       string `json:"fraud_status"`
+	PaymentID string  `json:"paymentId"`
+	Type      string  `json:"type"`
+	Subtotal  float64 `json:"subtotal"`
+	Credit    float64 `json:"credit"`
+	Discount  float64 `json:"discount"`
+	Tax       float64 `json:"tax"`
+	Total     float64 `json:"total"`
+	Status    string  `json:"status"` // "paid" bila total 0 dan tidak perlu checkout
+	SnapToken string  `json:"snapToken,omitempty"`
+	SnapURL   string  `json:"snapUrl,omitempty"`
 }
 
 type PaymentDetailResponse struct {
-	ID       string  `json:"id"`
-	UserID   string  `json:"userId"`
-	TierID   uint    `json:"tierId"`
-	Subtotal float64 `json:"subtotal"`
-	Tax      float64 `json:"tax"`
-	Total    float64 `json:"total"`
-	Method   string  `json:"method"`
-	Status   string  `json:"status"`
-	PaidAt   string  `json:"paidAt"`
+	ID          string  `json:"id"`
+	UserID      string  `json:"userId"`
+	TierID      uint    `json:"tierId"`
+	Type        string  `json:"type"`
+	Subtotal    float64 `json:"subtotal"`
+	Credit      float64 `json:"credit"`
+	Discount    float64 `json:"discount"`
+	VoucherCode string  `json:"voucherCode,omitempty"`
+	Tax         float64 `json:"tax"`
+	Total       float64 `json:"total"`
+	Refunded    float64 `json:"refunded"`
+	Method      string  `json:"method"`
+	Status      string  `json:"status"`
+	PaidAt      string  `json:"paidAt"`
+
+	Refunds []RefundResponse `json:"refunds"`
 }
 
 type PaymentResponse struct {
@@ -110,12 +274,34 @@ type PaymentResponse struct {
 	Fullname      string  `json:"fullname"`
 	TierID        uint    `json:"tierId"`
 	TierName      string  `json:"tierName"`
+	Type          string  `json:"type"`
 	Subtotal      float64 `json:"subtotal"`
+	Credit        float64 `json:"credit"`
+	Discount      float64 `json:"discount"`
 	Tax           float64 `json:"tax"`
 	Total         float64 `json:"total"`
 	PaymentMethod string  `json:"method"`
 	Status        string  `json:"status"`
 	PaidAt        string  `json:"paidAt"`
+	InvoiceID     string  `json:"invoiceId,omitempty"`
+	InvoiceNumber string  `json:"invoiceNumber,omitempty"`
+}
+
+type InvoiceResponse struct {
+	ID            string  `json:"id"`
+	Number        string  `json:"number"`
+	PaymentID     string  `json:"paymentId"`
+	CustomerName  string  `json:"customerName"`
+	CustomerEmail string  `json:"customerEmail"`
+	TierName      string  `json:"tierName"`
+	Method        string  `json:"method"`
+	Subtotal      float64 `json:"subtotal"`
+	Credit        float64 `json:"credit"`
+	Discount      float64 `json:"discount"`
+	TaxRate       float64 `json:"taxRate"`
+	Tax           float64 `json:"tax"`
+	Total         float64 `json:"total"`
+	IssuedAt      string  `json:"issuedAt"`
 }
 
 type PaymentListResponse struct {
@@ -125,6 +311,113 @@ type PaymentListResponse struct {
 	Limit    int               `json:"limit"`
 }
 
+// Amount kosong berarti refund penuh atas sisa nominal yang belum direfund
+type RefundPaymentRequest struct {
+	Amount *float64 `json:"amount" binding:"omitempty,gt=0"`
+	Reason string   `json:"reason" binding:"required"`
+}
+
+type RefundResponse struct {
+	ID                 uint    `json:"id"`
+	PaymentID          string  `json:"paymentId"`
+	Type               string  `json:"type"`
+	Amount             float64 `json:"amount"`
+	Reason             string  `json:"reason"`
+	SubscriptionAction string  `json:"subscriptionAction"`
+	Status             string  `json:"status"`
+	CreatedAt          string  `json:"createdAt"`
+}
+
+type PaymentMismatchResponse struct {
+	ID            uint   `json:"id"`
+	PaymentID     string `json:"paymentId"`
+	UserEmail     string `json:"userEmail"`
+	Kind          string `json:"kind"`
+	LocalStatus   string `json:"localStatus"`
+	GatewayStatus string `json:"gatewayStatus"`
+	Detail        string `json:"detail"`
+	Resolved      bool   `json:"resolved"`
+	ResolvedAt    string `json:"resolvedAt,omitempty"`
+	Note          string `json:"note,omitempty"`
+	CreatedAt     string `json:"createdAt"`
+}
+
+type PaymentMismatchListResponse struct {
+	Mismatches []PaymentMismatchResponse `json:"mismatches"`
+	Total      int64                     `json:"total"`
+	Page       int                       `json:"page"`
+	Limit      int                       `json:"limit"`
+}
+
+type ResolveMismatchRequest struct {
+	Note string `json:"note" binding:"required"`
+}
+
+// METRICS
+type MetricsQuery struct {
+	From     string `form:"from"` // YYYY-MM-DD, default 12 bulan terakhir
+	To       string `form:"to"`
+	Interval string `form:"interval" binding:"omitempty,oneof=day week month"`
+	GroupBy  string `form:"groupBy" binding:"omitempty,oneof=tier method"`
+}
+
+type TierMRRResponse struct {
+	TierName      string  `json:"tierName"`
+	Subscriptions int64   `json:"subscriptions"`
+	MRR           float64 `json:"mrr"`
+}
+
+type MRRResponse struct {
+	MRR                 float64           `json:"mrr"`
+	ActiveSubscriptions int64             `json:"activeSubscriptions"`
+	ByTier              []TierMRRResponse `json:"byTier"`
+}
+
+type RevenuePointResponse struct {
+	Period   string  `json:"period"`
+	Key      string  `json:"key"`
+	Revenue  float64 `json:"revenue"`
+	Payments int64   `json:"payments"`
+}
+
+type RevenueResponse struct {
+	GroupBy  string                 `json:"groupBy"`
+	Interval string                 `json:"interval"`
+	Total    float64                `json:"total"`
+	Points   []RevenuePointResponse `json:"points"`
+}
+
+type SubscriptionFlowPointResponse struct {
+	Period   string `json:"period"`
+	New      int64  `json:"new"`
+	Churned  int64  `json:"churned"`
+	Canceled int64  `json:"canceled"`
+}
+
+type SubscriptionFlowResponse struct {
+	Interval string                          `json:"interval"`
+	Points   []SubscriptionFlowPointResponse `json:"points"`
+}
+
+type ConversionResponse struct {
+	From            string  `json:"from"`
+	To              string  `json:"to"`
+	RegisteredUsers int64   `json:"registeredUsers"`
+	PayingUsers     int64   `json:"payingUsers"`
+	ConversionRate  float64 `json:"conversionRate"`
+}
+
+type TokenConsumptionPointResponse struct {
+	Period   string `json:"period"`
+	TierName string `json:"tierName"`
+	Tokens   int64  `json:"tokens"`
+}
+
+type TokenConsumptionResponse struct {
+	Interval string                          `json:"interval"`
+	Points   []TokenConsumptionPointResponse `json:"points"`
+}
+
 // USER
 type UpdateProfileRequest struct {
 	Fullname string `json:"fullname" binding:"required,min=3"`
@@ -132,10 +425,38 @@ type UpdateProfileRequest struct {
 }
 
 type UserProfileResponse struct {
-	ID       string `json:"id"`
-	Email    string `json:"email"`
-	Fullname string `json:"fullname"`
-	Avatar   string `json:"avatar"`
+	ID                  string `json:"id"`
+	Email               string `json:"email"`
+	Fullname            string `json:"fullname"`
+	Avatar              string `json:"avatar"`
+	DeletionScheduledAt string `json:"deletionScheduledAt,omitempty"`
+}
+
+// ACCOUNT DATA
+type AccountDeletionRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type AccountDeletionResponse struct {
+	DeletionScheduledAt string `json:"deletionScheduledAt"`
+}
+
+// data respon pada export hanya berisi jawaban, tanpa IP/user agent/session responden
+type ExportSubmission struct {
+	ID          string         `json:"id"`
+	FormID      string         `json:"formId"`
+	FormVersion int            `json:"formVersion,omitempty"`
+	Email       string         `json:"email"`
+	Score       *float64       `json:"score"`
+	SubmittedAt string         `json:"submittedAt"`
+	Flagged     bool           `json:"flagged"`
+	Answers     []ExportAnswer `json:"answers"`
+}
+
+type ExportAnswer struct {
+	QuestionID string  `json:"questionId"`
+	OptionID   *uint   `json:"optionId,omitempty"`
+	TextAnswer *string `json:"textAnswer,omitempty"`
 }
 
 type MyFormResponse struct {
@@ -152,6 +473,31 @@ type MyFormDetailResponse struct {
 	CreatedAt   string `json:"createdAt"`
 }
 
+// API KEYS
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,oneof=forms:read submissions:read forms:write"`
+	ExpiresInDays int      `json:"expiresInDays" binding:"omitempty,min=1,max=365"`
+}
+
+type APIKeyResponse struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	LastUsedAt string   `json:"lastUsedAt,omitempty"`
+	LastUsedIP string   `json:"lastUsedIp,omitempty"`
+	ExpiresAt  string   `json:"expiresAt,omitempty"`
+	RevokedAt  string   `json:"revokedAt,omitempty"`
+	CreatedAt  string   `json:"createdAt"`
+}
+
+// Key hanya dikembalikan sekali saat dibuat
+type APIKeyCreatedResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
 // FORM
 type CreateFormRequest struct {
 	Title       string `json:"title" binding:"required,min=3"`
@@ -189,16 +535,61 @@ type FormSettingResponse struct {
 	MaxSubmissions     *int     `json:"maxSubmissions"`
 	StartAt            *string  `json:"startAt"`
 	EndAt              *string  `json:"endAt"`
+
+	HoneypotEnabled       bool `json:"honeypotEnabled"`
+	MinFillSeconds        int  `json:"minFillSeconds"`
+	RequireRenderToken    bool `json:"requireRenderToken"`
+	ProofOfWorkDifficulty int  `json:"proofOfWorkDifficulty"`
+
+	RetentionDays      int    `json:"retentionDays"`
+	RetentionAction    string `json:"retentionAction"`
+	AnonymousResponses bool   `json:"anonymousResponses"`
+
+	ReminderHoursBefore int     `json:"reminderHoursBefore"`
+	OpenedAt            *string `json:"openedAt"`
+	ClosedAt            *string `json:"closedAt"`
 }
 
+// UpdateFormSettingRequest hanya mengubah field yang dikirim. Karena null tidak bisa dibedakan dari
+// field yang tidak dikirim, batas dan jadwal dikosongkan lewat Clear, misalnya {"clear": ["endAt"]}.
 type UpdateFormSettingRequest struct {
-	ShowResult         bool     `json:"showResult"`
-	MultipleSubmission bool     `json:"multipleSubmission"`
+	ShowResult         *bool    `json:"showResult"`
+	MultipleSubmission *bool    `json:"multipleSubmission"`
 	PassingGrade       *float64 `json:"passingGrade"`
-	Grading            bool     `json:"grading"`
+	Grading            *bool    `json:"grading"`
 	MaxSubmissions     *int     `json:"maxSubmissions"`
-	StartAt            *string  `json:"startAt"` // ISO 8601 format
-	EndAt              *string  `json:"endAt"`   // ISO 8601 format
+	StartAt            *string  `json:"startAt" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"` // ISO 8601 format
+	EndAt              *string  `json:"endAt" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`   // ISO 8601 format
+	Clear              []string `json:"clear" binding:"omitempty,dive,oneof=passingGrade maxSubmissions startAt endAt"`
+
+	HoneypotEnabled       *bool `json:"honeypotEnabled"`
+	MinFillSeconds        *int  `json:"minFillSeconds" binding:"omitempty,min=0,max=3600"`
+	RequireRenderToken    *bool `json:"requireRenderToken"`
+	ProofOfWorkDifficulty *int  `json:"proofOfWorkDifficulty" binding:"omitempty,min=0,max=24"`
+
+	RetentionDays      *int    `json:"retentionDays" binding:"omitempty,min=0,max=3650"`
+	RetentionAction    *string `json:"retentionAction" binding:"omitempty,oneof=anonymize delete"`
+	AnonymousResponses *bool   `json:"anonymousResponses"`
+
+	ReminderHoursBefore *int `json:"reminderHoursBefore" binding:"omitempty,min=0,max=720"`
+}
+
+type AddInviteesRequest struct {
+	Emails []string `json:"emails" binding:"required,min=1,max=500,dive,email"`
+}
+
+type InviteeResponse struct {
+	ID         uint   `json:"id"`
+	Email      string `json:"email"`
+	RemindedAt string `json:"remindedAt,omitempty"`
+	CreatedAt  string `json:"createdAt"`
+}
+
+type RetentionLogResponse struct {
+	Action    string `json:"action"`
+	Affected  int64  `json:"affected"`
+	Cutoff    string `json:"cutoff"`
+	CreatedAt string `json:"createdAt"`
 }
 
 type AddSectionRequest struct {
@@ -262,6 +653,56 @@ type UpdateQuestionRequest struct {
 	ImageURL   *string `json:"imageUrl"`
 }
 
+// FORM VERSIONS
+// struktur form yang dibekukan saat publish
+type FormSnapshot struct {
+	Title       string             `json:"title"`
+	Description string             `json:"description"`
+	Type        string             `json:"type"`
+	Duration    *int               `json:"duration"`
+	Sections    []SectionResponse  `json:"sections"`
+	Questions   []SnapshotQuestion `json:"questions"`
+}
+
+type SnapshotQuestion struct {
+	QuestionResponse
+	SectionID string `json:"sectionId"`
+}
+
+type FormVersionResponse struct {
+	ID          string        `json:"id"`
+	FormID      string        `json:"formId"`
+	Version     int           `json:"version"`
+	Live        bool          `json:"live"`
+	PublishedBy string        `json:"publishedBy,omitempty"`
+	PublishedAt string        `json:"publishedAt"`
+	Snapshot    *FormSnapshot `json:"snapshot,omitempty"`
+}
+
+// Change: added, removed atau changed; Before/After hanya berisi field yang berubah
+type VersionChange struct {
+	Kind   string         `json:"kind"` // form, section, question
+	ID     string         `json:"id,omitempty"`
+	Change string         `json:"change"`
+	Before map[string]any `json:"before,omitempty"`
+	After  map[string]any `json:"after,omitempty"`
+}
+
+type FormVersionDiffResponse struct {
+	From    int             `json:"from"`
+	To      int             `json:"to"`
+	Changes []VersionChange `json:"changes"`
+}
+
+// form live untuk responden, jawaban benar tidak ikut dikirim
+type LiveFormResponse struct {
+	FormID      string        `json:"formId"`
+	Version     int           `json:"version"`
+	ClosesAt    string        `json:"closesAt,omitempty"`
+	ClosingSoon bool          `json:"closingSoon"` // untuk banner "segera ditutup" di form publik
+	Form        *FormSnapshot `json:"form"`
+}
+
 // SUBMISSIONS
 type AnswerRequest struct {
 	QuestionID string  `json:"questionId" binding:"required"`
@@ -269,27 +710,43 @@ type AnswerRequest struct {
 	TextAnswer *string `json:"textAnswer,omitempty"`
 }
 
+// IP dan user agent tidak diambil dari body, tetapi dicatat server dari request (lihat RequestMeta)
 type SubmissionRequest struct {
-	FormID       string          `json:"formId" binding:"required"`
+	FormID       string          `json:"-"` // dari path parameter
 	Email        string          `json:"email"`
-	IPAddress    *string         `json:"ipAddress"`
-	UserAgent    *string         `json:"userAgent"`
 	SessionToken *string         `json:"sessionToken"`
 	Answers      []AnswerRequest `json:"answers" binding:"required,min=1"`
+
+	RenderToken string `json:"renderToken"`
+	PowNonce    string `json:"powNonce"`
+	Website     string `json:"website"` // honeypot, harus kosong
+}
+
+// diterbitkan saat form publik dirender, dipakai untuk pengecekan spam saat submit
+type SubmissionChallengeResponse struct {
+	RenderToken    string `json:"renderToken"`
+	HoneypotField  string `json:"honeypotField,omitempty"`
+	MinFillSeconds int    `json:"minFillSeconds,omitempty"`
+	PowDifficulty  int    `json:"powDifficulty,omitempty"` // cari powNonce: sha256(renderToken + ":" + powNonce) diawali N bit nol
 }
 
 type SubmissionResponse struct {
-	ID        string   `json:"id"`
-	FormID    string   `json:"formId"`
-	Email     string   `json:"email"`
-	Score     *float64 `json:"score"`
-	Timestamp string   `json:"submittedAt"`
+	ID          string   `json:"id"`
+	FormID      string   `json:"formId"`
+	Version     int      `json:"version,omitempty"` // versi form yang diisi, 0 bila sebelum versioning
+	Email       string   `json:"email"`
+	Score       *float64 `json:"score"`
+	Timestamp   string   `json:"submittedAt"`
+	Flagged     bool     `json:"flagged"`
+	FlagReasons []string `json:"flagReasons,omitempty"`
 }
 
 type SubmissionResultResponse struct {
-	FormTitle  string           `json:"formTitle"`
-	TotalScore *float64         `json:"totalScore,omitempty"`
-	Answers    []AnswerResponse `json:"answers"`
+	FormTitle   string           `json:"formTitle"`
+	Version     int              `json:"version,omitempty"`
+	Unversioned bool             `json:"unversioned,omitempty"` // dibuat sebelum versioning, dirender terhadap draft saat ini
+	TotalScore  *float64         `json:"totalScore,omitempty"`
+	Answers     []AnswerResponse `json:"answers"`
 }
 
 type AnswerResponse struct {
@@ -297,3 +754,84 @@ type AnswerResponse struct {
 	Answer   string `json:"answer"`
 	Correct  *bool  `json:"correct,omitempty"` // jika quiz atau exam
 }
+
+// QUEUE
+type QueueResponse struct {
+	ID          string `json:"id"`
+	ResponseID  string `json:"responseId"`
+	QueueNumber int    `json:"queueNumber"`
+	Status      string `json:"status"`
+}
+
+// ANALYTICS
+type FormAnalyticsSummaryResponse struct {
+	FormID           string   `json:"formId"`
+	TotalSubmissions int64    `json:"totalSubmissions"`
+	FlaggedCount     int64    `json:"flaggedCount"` // tidak ikut dihitung pada statistik lain
+	AverageScore     *float64 `json:"averageScore,omitempty"`
+	PassRate         *float64 `json:"passRate,omitempty"` // persen, hanya untuk form dengan grading
+	FirstSubmission  string   `json:"firstSubmission,omitempty"`
+	LastSubmission   string   `json:"lastSubmission,omitempty"`
+}
+
+type OptionAnalytics struct {
+	OptionID uint    `json:"optionId"`
+	Text     string  `json:"text"`
+	Count    int64   `json:"count"`
+	Percent  float64 `json:"percent"`
+}
+
+type QuestionAnalytics struct {
+	QuestionID  string            `json:"questionId"`
+	Text        string            `json:"text"`
+	Type        string            `json:"type"`
+	Responses   int64             `json:"responses"`
+	TextAnswers int64             `json:"textAnswers,omitempty"`
+	Options     []OptionAnalytics `json:"options,omitempty"`
+}
+
+// Version 0 berisi submission yang masuk sebelum form dipublish, dirender terhadap draft saat ini.
+type FormVersionAnalytics struct {
+	Version   int                 `json:"version"`
+	Questions []QuestionAnalytics `json:"questions"`
+}
+
+type FormAnalyticsResponse struct {
+	FormID   string                 `json:"formId"`
+	Versions []FormVersionAnalytics `json:"versions"`
+}
+
+// AUDIT LOG
+type AuditLogQuery struct {
+	ActorID    string `form:"actorId"`
+	Action     string `form:"action"`
+	EntityType string `form:"entityType"`
+	EntityID   string `form:"entityId"`
+	FormID     string `form:"formId"`
+	RequestID  string `form:"requestId"`
+	From       string `form:"from"` // RFC 3339
+	To         string `form:"to"`   // RFC 3339
+	Page       int    `form:"page"`
+	Limit      int    `form:"limit"`
+}
+
+type AuditLogResponse struct {
+	ID         uint           `json:"id"`
+	ActorID    string         `json:"actorId,omitempty"`
+	Action     string         `json:"action"`
+	EntityType string         `json:"entityType"`
+	EntityID   string         `json:"entityId"`
+	FormID     string         `json:"formId,omitempty"`
+	Before     map[string]any `json:"before,omitempty"`
+	After      map[string]any `json:"after,omitempty"`
+	IPAddress  string         `json:"ipAddress"`
+	RequestID  string         `json:"requestId"`
+	CreatedAt  string         `json:"createdAt"`
+}
+
+type AuditLogListResponse struct {
+	Logs  []AuditLogResponse `json:"logs"`
+	Total int64              `json:"total"`
+	Page  int                `json:"page"`
+	Limit int                `json:"limit"`
+}
@@ -0,0 +1,187 @@
+package gateway
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// fakeGateway mensimulasikan payment gateway sepenuhnya di memori untuk development dan testing
+// tanpa koneksi internet. Notifikasi ditandatangani HMAC-SHA256 dengan secret lokal sehingga
+// alur verifikasi webhook tetap berjalan seperti di Midtrans.
+type fakeGateway struct {
+	secret  string
+	baseURL string
+
+	mu           sync.Mutex
+	transactions map[string]*fakeTransaction
+}
+
+type fakeTransaction struct {
+	Amount            int64
+	Refunded          int64
+	RefundKeys        map[string]bool
+	TransactionStatus string
+	FraudStatus       string
+	PaymentType       string
+}
+
+type fakeNotification struct {
+	OrderID           string `json:"order_id"`
+	GrossAmount       string `json:"gross_amount"`
+	TransactionStatus string `json:"transaction_status"`
+	FraudStatus       string `json:"fraud_status"`
+	PaymentType       string `json:"payment_type"`
+	Signature         string `json:"signature"`
+}
+
+func NewFakeGateway(secret, baseURL string) PaymentGateway {
+	if secret == "" {
+		secret = "fake-gateway-secret"
+	}
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+	return &fakeGateway{
+		secret:       secret,
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		transactions: make(map[string]*fakeTransaction),
+	}
+}
+
+func (g *fakeGateway) CreateCheckout(req *CheckoutRequest) (*CheckoutResponse, error) {
+	if req.Amount < 0 {
+		return nil, errors.New("invalid amount")
+	}
+
+	g.mu.Lock()
+	g.transactions[req.OrderID] = &fakeTransaction{
+		Amount:            req.Amount,
+		TransactionStatus: "pending",
+		PaymentType:       "fake",
+	}
+	g.mu.Unlock()
+
+	return &CheckoutResponse{
+		Token:       "fake-" + uuid.NewString(),
+		RedirectURL: fmt.Sprintf("%s/api/v1/payments/fake/%s", g.baseURL, req.OrderID),
+	}, nil
+}
+
+// Simulate mengubah status transaksi tiruan dan mengembalikan payload notifikasi yang sudah
+// ditandatangani. Event: settle, expire, deny (fraud deny), cancel.
+func (g *fakeGateway) Simulate(orderID, event string) ([]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	tx, ok := g.transactions[orderID]
+	if !ok {
+		return nil, ErrTransactionNotFound
+	}
+
+	switch event {
+	case "settle":
+		tx.TransactionStatus, tx.FraudStatus = "settlement", "accept"
+	case "expire":
+		tx.TransactionStatus, tx.FraudStatus = "expire", ""
+	case "deny":
+		tx.TransactionStatus, tx.FraudStatus = "deny", "deny"
+	case "cancel":
+		tx.TransactionStatus, tx.FraudStatus = "cancel", ""
+	default:
+		return nil, fmt.Errorf("unknown fake gateway event: %s", event)
+	}
+
+	return json.Marshal(g.sign(fakeNotification{
+		OrderID:           orderID,
+		GrossAmount:       fmt.Sprintf("%d.00", tx.Amount),
+		TransactionStatus: tx.TransactionStatus,
+		FraudStatus:       tx.FraudStatus,
+		PaymentType:       tx.PaymentType,
+	}))
+}
+
+func (g *fakeGateway) ParseNotification(payload []byte) (*Notification, error) {
+	var n fakeNotification
+	if err := json.Unmarshal(payload, &n); err != nil {
+		return nil, err
+	}
+
+	expected := g.sign(n).Signature
+	if !hmac.Equal([]byte(expected), []byte(n.Signature)) {
+		return nil, ErrInvalidSignature
+	}
+
+	return &Notification{
+		OrderID:           n.OrderID,
+		TransactionStatus: n.TransactionStatus,
+		FraudStatus:       n.FraudStatus,
+		PaymentType:       n.PaymentType,
+		GrossAmount:       n.GrossAmount,
+	}, nil
+}
+
+func (g *fakeGateway) QueryStatus(orderID string) (*Notification, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	tx, ok := g.transactions[orderID]
+	if !ok {
+		return nil, ErrTransactionNotFound
+	}
+
+	return &Notification{
+		OrderID:           orderID,
+		TransactionStatus: tx.TransactionStatus,
+		FraudStatus:       tx.FraudStatus,
+		PaymentType:       tx.PaymentType,
+		GrossAmount:       fmt.Sprintf("%d.00", tx.Amount),
+	}, nil
+}
+
+func (g *fakeGateway) Refund(req *RefundRequest) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	tx, ok := g.transactions[req.OrderID]
+	if !ok {
+		return ErrTransactionNotFound
+	}
+	if tx.TransactionStatus != "settlement" && tx.TransactionStatus != "capture" &&
+		tx.TransactionStatus != "partial_refund" {
+		return errors.New("transaction cannot be refunded in status " + tx.TransactionStatus)
+	}
+	if tx.RefundKeys[req.RefundKey] {
+		// refund dengan key yang sama sudah diproses, sama seperti perilaku Midtrans
+		return nil
+	}
+	if req.Amount <= 0 || tx.Refunded+req.Amount > tx.Amount {
+		return errors.New("refund amount exceeds the settled amount")
+	}
+
+	tx.Refunded += req.Amount
+	if tx.RefundKeys == nil {
+		tx.RefundKeys = make(map[string]bool)
+	}
+	tx.RefundKeys[req.RefundKey] = true
+	if tx.Refunded == tx.Amount {
+		tx.TransactionStatus = "refund"
+	} else {
+		tx.TransactionStatus = "partial_refund"
+	}
+	return nil
+}
+
+func (g *fakeGateway) sign(n fakeNotification) fakeNotification {
+	mac := hmac.New(sha256.New, []byte(g.secret))
+	mac.Write([]byte(n.OrderID + n.TransactionStatus + n.FraudStatus + n.GrossAmount))
+	n.Signature = hex.EncodeToString(mac.Sum(nil))
+	return n
+}
@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"errors"
+	"os"
+
+	"server/internal/config"
+)
+
+var (
+	ErrInvalidSignature    = errors.New("invalid notification signature")
+	ErrTransactionNotFound = errors.New("transaction not found at gateway")
+)
+
+type CheckoutRequest struct {
+	OrderID       string
+	Amount        int64
+	CustomerName  string
+	CustomerEmail string
+}
+
+type CheckoutResponse struct {
+	Token       string
+	RedirectURL string
+}
+
+// Notification adalah status transaksi dari gateway dengan kosakata status Midtrans
+// (settlement, capture, pending, deny, cancel, expire, refund, partial_refund).
+type Notification struct {
+	OrderID           string
+	TransactionStatus string
+	FraudStatus       string
+	PaymentType       string
+	GrossAmount       string
+}
+
+type RefundRequest struct {
+	OrderID   string
+	RefundKey string // idempotency key, dipakai ulang bila refund dikirim ulang
+	Amount    int64
+	Reason    string
+}
+
+type PaymentGateway interface {
+	CreateCheckout(req *CheckoutRequest) (*CheckoutResponse, error)
+	ParseNotification(payload []byte) (*Notification, error)
+	QueryStatus(orderID string) (*Notification, error)
+	Refund(req *RefundRequest) error
+}
+
+// Simulator diimplementasikan gateway yang bisa membuat notifikasi tiruan (hanya fake gateway).
+type Simulator interface {
+	Simulate(orderID, event string) ([]byte, error)
+}
+
+// New memilih implementasi gateway berdasarkan PAYMENT_GATEWAY ("midtrans" atau "fake").
+func New(name string) PaymentGateway {
+	if name == "fake" {
+		return NewFakeGateway(os.Getenv("FAKE_GATEWAY_SECRET"), os.Getenv("FAKE_GATEWAY_BASE_URL"))
+	}
+	return NewMidtransGateway(config.SnapClient, config.CoreClient, os.Getenv("MIDTRANS_SERVER_KEY"))
+}
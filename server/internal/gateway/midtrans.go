@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/midtrans/midtrans-go"
+	"github.com/midtrans/midtrans-go/coreapi"
+	"github.com/midtrans/midtrans-go/snap"
+)
+
+type midtransGateway struct {
+	snapClient snap.Client
+	coreClient coreapi.Client
+	serverKey  string
+}
+
+func NewMidtransGateway(snapClient snap.Client, coreClient coreapi.Client, serverKey string) PaymentGateway {
+	return &midtransGateway{snapClient, coreClient, serverKey}
+}
+
+type midtransNotification struct {
+	OrderID           string `json:"order_id"`
+	StatusCode        string `json:"status_code"`
+	GrossAmount       string `json:"gross_amount"`
+	SignatureKey      string `json:"signature_key"`
+	TransactionStatus string `json:"transaction_status"`
+	PaymentType       string `json:"payment_type"`
+	FraudStatus       string `json:"fraud_status"`
+}
+
+func (g *midtransGateway) CreateCheckout(req *CheckoutRequest) (*CheckoutResponse, error) {
+	snapReq := &snap.Request{
+		TransactionDetails: midtrans.TransactionDetails{
+			OrderID:  req.OrderID,
+			GrossAmt: req.Amount,
+		},
+		CustomerDetail: &midtrans.CustomerDetails{
+			FName: req.CustomerName,
+			Email: req.CustomerEmail,
+		},
+	}
+
+	// midtrans mengembalikan *midtrans.Error, jangan langsung ditampung ke variabel error
+	// karena pointer nil di dalam interface tidak sama dengan nil
+	resp, mErr := g.snapClient.CreateTransaction(snapReq)
+	if mErr != nil {
+		return nil, mErr
+	}
+	if resp == nil || resp.Token == "" {
+		return nil, errors.New("empty response from midtrans")
+	}
+
+	return &CheckoutResponse{
+		Token:       resp.Token,
+		RedirectURL: resp.RedirectURL,
+	}, nil
+}
+
+// ParseNotification memverifikasi signature_key = SHA512(order_id + status_code + gross_amount + server key).
+func (g *midtransGateway) ParseNotification(payload []byte) (*Notification, error) {
+	var n midtransNotification
+	if err := json.Unmarshal(payload, &n); err != nil {
+		return nil, err
+	}
+
+	sum := sha512.Sum512([]byte(n.OrderID + n.StatusCode + n.GrossAmount + g.serverKey))
+	expected := hex.EncodeToString(sum[:])
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(n.SignatureKey)) != 1 {
+		return nil, ErrInvalidSignature
+	}
+
+	return &Notification{
+		OrderID:           n.OrderID,
+		TransactionStatus: n.TransactionStatus,
+		FraudStatus:       n.FraudStatus,
+		PaymentType:       n.PaymentType,
+		GrossAmount:       n.GrossAmount,
+	}, nil
+}
+
+func (g *midtransGateway) QueryStatus(orderID string) (*Notification, error) {
+	resp, mErr := g.coreClient.CheckTransaction(orderID)
+	if mErr != nil {
+		if mErr.StatusCode == 404 {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, mErr
+	}
+	if resp == nil || resp.StatusCode == "404" {
+		return nil, ErrTransactionNotFound
+	}
+
+	return &Notification{
+		OrderID:           resp.OrderID,
+		TransactionStatus: resp.TransactionStatus,
+		FraudStatus:       resp.FraudStatus,
+		PaymentType:       resp.PaymentType,
+		GrossAmount:       resp.GrossAmount,
+	}, nil
+}
+
+func (g *midtransGateway) Refund(req *RefundRequest) error {
+	_, mErr := g.coreClient.RefundTransaction(req.OrderID, &coreapi.RefundReq{
+		RefundKey: req.RefundKey,
+		Amount:    req.Amount,
+		Reason:    req.Reason,
+	})
+	if mErr != nil {
+		return mErr
+	}
+	return nil
+}
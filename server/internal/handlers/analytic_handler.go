@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"net/http"
-	"server/internal/dto"
 	"server/internal/services"
 	"server/internal/utils"
 
@@ -18,5 +17,20 @@ func NewAnalyticsHandler(service services.AnalyticsService) *AnalyticsHandler {
 	return &AnalyticsHandler{service}
 }
 
-func (h *AnalyticsHandler) GetFormAnalytics(c *gin.Context)       {}
-func (h *AnalyticsHandler) GetFormAnalyticSummary(c *gin.Context) {}
+func (h *AnalyticsHandler) GetFormAnalytics(c *gin.Context) {
+	data, err := h.service.GetFormAnalytics(utils.MustGetUserID(c), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Failed to fetch form analytics", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (h *AnalyticsHandler) GetFormAnalyticSummary(c *gin.Context) {
+	data, err := h.service.GetFormAnalyticSummary(utils.MustGetUserID(c), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Failed to fetch analytics summary", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
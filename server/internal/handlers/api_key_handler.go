@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"server/internal/dto"
+	"server/internal/services"
+	"server/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	service services.APIKeyService
+}
+
+func NewAPIKeyHandler(service services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service}
+}
+
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req dto.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid input request", "error": err.Error()})
+		return
+	}
+
+	userID := utils.MustGetUserID(c)
+	res, err := h.service.CreateAPIKey(userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Failed to create api key", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "API key created, copy it now because it will not be shown again", "data": res})
+}
+
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	userID := utils.MustGetUserID(c)
+
+	res, err := h.service.GetAPIKeys(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to fetch api keys", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	userID := utils.MustGetUserID(c)
+
+	if err := h.service.RevokeAPIKey(userID, c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
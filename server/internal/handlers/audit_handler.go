@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+	"server/internal/dto"
+	"server/internal/services"
+	"server/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	service services.AuditService
+}
+
+func NewAuditHandler(service services.AuditService) *AuditHandler {
+	return &AuditHandler{service}
+}
+
+func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
+	var query dto.AuditLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid query", "error": err.Error()})
+		return
+	}
+
+	res, err := h.service.GetAuditLogs(&query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Failed to fetch audit logs", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *AuditHandler) GetFormHistory(c *gin.Context) {
+	userID := utils.MustGetUserID(c)
+	page := utils.StringToInt(c.DefaultQuery("page", "1"))
+	limit := utils.StringToInt(c.DefaultQuery("limit", "20"))
+
+	res, err := h.service.GetFormHistory(userID, c.Param("id"), page, limit)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Failed to fetch form history", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": res})
+}
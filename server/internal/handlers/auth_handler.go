@@ -1,10 +1,17 @@
 package handlers
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"net/url"
+	"os"
 	"server/internal/dto"
+	"server/internal/oidc"
 	"server/internal/services"
 	"server/internal/utils"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,15 +31,12 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid Input Request", "error": err.Error()})
 		return
 	}
-	tokens, err := h.service.UserRegister(&req)
+	tokens, err := h.service.UserRegister(&req, utils.GetRequestMeta(c))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Email Already Registered", "error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Failed to register", "error": err.Error()})
 		return
 	}
-	utils.SetAccessTokenCookie(c, tokens.AccessToken)
-	utils.SetRefreshTokenCookie(c, tokens.RefreshToken)
-
-	c.JSON(http.StatusOK, gin.H{"message": "Register Successfully"})
+	respondWithTokens(c, tokens, "Register Successfully")
 
 }
 
@@ -43,16 +47,25 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	tokens, err := h.service.UserLogin(&req)
+	tokens, challenge, err := h.service.UserLogin(&req, utils.GetRequestMeta(c))
 	if err != nil {
+		var throttled *services.LoginThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"message": err.Error()})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"message": err.Error()})
 		return
 	}
 
-	utils.SetAccessTokenCookie(c, tokens.AccessToken)
-	utils.SetAccessTokenCookie(c, tokens.RefreshToken)
+	// 2FA aktif: cookie session baru diberikan setelah kode diverifikasi di /2fa/login
+	if challenge != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication required", "data": challenge})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Login Successfully"})
+	respondWithTokens(c, tokens, "Login Successfully")
 }
 
 func (h *AuthHandler) SendOTP(c *gin.Context) {
@@ -63,6 +76,10 @@ func (h *AuthHandler) SendOTP(c *gin.Context) {
 	}
 
 	if err := h.service.SendOTP(&req); err != nil {
+		if errors.Is(err, services.ErrOTPLocked) || errors.Is(err, services.ErrOTPCooldown) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"message": err.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
 		return
 	}
@@ -77,12 +94,17 @@ func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.VerifyOTPCode(&req); err != nil {
+	res, err := h.service.VerifyOTPCode(&req)
+	if err != nil {
+		if errors.Is(err, services.ErrOTPLocked) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"message": err.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "OTP Verified succesfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "OTP Verified succesfully", "data": res})
 }
 
 func (h *AuthHandler) AuthMe(c *gin.Context) {
@@ -99,21 +121,298 @@ func (h *AuthHandler) AuthMe(c *gin.Context) {
 }
 
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
-	refreshToken, err := c.Cookie("refreshToken")
+	refreshToken := requestRefreshToken(c)
+	if refreshToken == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized Access", "error": "refresh token missing"})
+		return
+	}
+
+	response, err := h.service.RefreshUserToken(refreshToken, utils.GetRequestMeta(c))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized Access", "error": err.Error()})
+		utils.ClearTokenCookies(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"message": err.Error()})
+		return
+	}
+
+	respondWithTokens(c, response, "Refresh Successfully")
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if refreshToken := requestRefreshToken(c); refreshToken != "" {
+		if err := h.service.Logout(refreshToken); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to logout", "error": err.Error()})
+			return
+		}
+	}
+
+	utils.ClearTokenCookies(c)
+	c.JSON(http.StatusOK, gin.H{"message": "Logout Successfully"})
+}
+
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID := utils.MustGetUserID(c)
+
+	if err := h.service.LogoutAll(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to logout from all devices", "error": err.Error()})
+		return
 	}
 
-	response, err := h.service.RefreshUserToken(refreshToken)
+	utils.ClearTokenCookies(c)
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out from all devices"})
+}
+
+func (h *AuthHandler) GetSessions(c *gin.Context) {
+	userID := utils.MustGetUserID(c)
+	currentToken := requestRefreshToken(c)
+
+	sessions, err := h.service.GetSessions(userID, currentToken)
 	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to fetch sessions", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID := utils.MustGetUserID(c)
+
+	if err := h.service.RevokeSession(userID, c.Param("id")); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
 		return
 	}
 
-	utils.SetAccessTokenCookie(c, response.AccessToken)
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
 
-	utils.SetRefreshTokenCookie(c, response.RefreshToken)
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req dto.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid input request", "error": err.Error()})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Refresh Successfully"})
+	if err := h.service.ForgotPassword(&req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to process request", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the email is registered, a reset link has been sent"})
+}
+
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req dto.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid input request", "error": err.Error()})
+		return
+	}
+
+	if err := h.service.ResetPassword(&req, utils.GetRequestMeta(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully, please login again"})
+}
+
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var req dto.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid input request", "error": err.Error()})
+		return
+	}
+
+	userID := utils.MustGetUserID(c)
+	if err := h.service.ChangePassword(userID, &req, utils.GetRequestMeta(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	// semua sesi dicabut, termasuk sesi ini
+	utils.ClearTokenCookies(c)
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully, please login again"})
+}
+
+func (h *AuthHandler) UnlockAccount(c *gin.Context) {
+	var req dto.UnlockAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid input request", "error": err.Error()})
+		return
+	}
+
+	if err := h.service.UnlockAccount(&req, utils.GetRequestMeta(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked, you can sign in again"})
+}
+
+func (h *AuthHandler) TwoFactorLogin(c *gin.Context) {
+	var req dto.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid input request", "error": err.Error()})
+		return
+	}
+
+	tokens, err := h.service.CompleteTwoFactorLogin(&req, utils.GetRequestMeta(c))
+	if err != nil {
+		if errors.Is(err, services.ErrTwoFactorLocked) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"message": err.Error()})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"message": err.Error()})
+		return
+	}
+
+	respondWithTokens(c, tokens, "Login Successfully")
+}
 
+func (h *AuthHandler) EnrollTwoFactor(c *gin.Context) {
+	userID := utils.MustGetUserID(c)
+
+	res, err := h.service.EnrollTwoFactor(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *AuthHandler) ConfirmTwoFactor(c *gin.Context) {
+	var req dto.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid input request", "error": err.Error()})
+		return
+	}
+
+	userID := utils.MustGetUserID(c)
+	res, err := h.service.ConfirmTwoFactor(userID, &req, utils.GetRequestMeta(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication enabled, store your recovery codes safely", "data": res})
+}
+
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	var req dto.TwoFactorDisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid input request", "error": err.Error()})
+		return
+	}
+
+	userID := utils.MustGetUserID(c)
+	if err := h.service.DisableTwoFactor(userID, &req, utils.GetRequestMeta(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req dto.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid input request", "error": err.Error()})
+		return
+	}
+
+	userID := utils.MustGetUserID(c)
+	res, err := h.service.RegenerateRecoveryCodes(userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *AuthHandler) GetOIDCProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.GetOIDCProviders())
+}
+
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	authURL, err := h.service.StartOIDCLogin(c.Param("provider"))
+	if err != nil {
+		if errors.Is(err, oidc.ErrUnknownProvider) {
+			c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"message": "Failed to start login", "error": err.Error()})
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback dipanggil browser setelah login di provider, lalu diarahkan kembali ke CLIENT_URL.
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	if providerErr := c.Query("error"); providerErr != "" {
+		oidcClientRedirect(c, "/login", url.Values{"error": {providerErr}})
+		return
+	}
+
+	var req dto.OIDCCallbackRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		oidcClientRedirect(c, "/login", url.Values{"error": {"invalid_request"}})
+		return
+	}
+
+	tokens, challenge, err := h.service.CompleteOIDCLogin(c.Param("provider"), &req, utils.GetRequestMeta(c))
+	if err != nil {
+		oidcClientRedirect(c, "/login", url.Values{"error": {err.Error()}})
+		return
+	}
+
+	if challenge != nil {
+		oidcClientRedirect(c, "/login/2fa", url.Values{"challengeToken": {challenge.ChallengeToken}})
+		return
+	}
+
+	utils.SetAccessTokenCookie(c, tokens.AccessToken)
+	utils.SetRefreshTokenCookie(c, tokens.RefreshToken)
+
+	oidcClientRedirect(c, "/", nil)
+}
+
+// parameter dikirim lewat fragment agar tidak ikut tercatat di log server/Referer
+func oidcClientRedirect(c *gin.Context, path string, params url.Values) {
+	target := strings.TrimRight(os.Getenv("CLIENT_URL"), "/") + path
+	if len(params) > 0 {
+		target += "#" + params.Encode()
+	}
+	c.Redirect(http.StatusFound, target)
+}
+
+// respondWithTokens menyimpan token di cookie untuk browser, atau mengembalikannya di body
+// untuk client non-browser (lihat utils.WantsTokenResponse).
+func respondWithTokens(c *gin.Context, tokens *dto.AuthResponse, message string) {
+	if utils.WantsTokenResponse(c) {
+		c.JSON(http.StatusOK, gin.H{"message": message, "data": tokens})
+		return
+	}
+
+	utils.SetAccessTokenCookie(c, tokens.AccessToken)
+	utils.SetRefreshTokenCookie(c, tokens.RefreshToken)
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// requestRefreshToken membaca refresh token dari cookie, header X-Refresh-Token, atau body JSON.
+func requestRefreshToken(c *gin.Context) string {
+	if token, err := c.Cookie("refreshToken"); err == nil && token != "" {
+		return token
+	}
+	if token := c.GetHeader("X-Refresh-Token"); token != "" {
+		return token
+	}
+
+	var req dto.RefreshTokenRequest
+	if c.Request.ContentLength > 0 && c.ShouldBindJSON(&req) == nil {
+		return req.RefreshToken
+	}
+	return ""
 }
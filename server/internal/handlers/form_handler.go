@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"errors"
+	"net/http"
 	"server/internal/dto"
 	"server/internal/services"
 	"server/internal/utils"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,7 +27,7 @@ func (h *FormHandler) CreateNewForm(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.CreateForm(userID, &req); err != nil {
+	if err := h.service.CreateForm(userID, &req, utils.GetRequestMeta(c)); err != nil {
 		c.JSON(500, gin.H{"message": "Failed to create form", "error": err.Error()})
 		return
 	}
@@ -68,15 +71,15 @@ func (h *FormHandler) GetFormSettings(c *gin.Context) {
 }
 
 func (h *FormHandler) UpdateFormSettings(c *gin.Context) {
-	formID := c.Param("formId")
+	formID := c.Param("id")
 
 	var req dto.UpdateFormSettingRequest
 	if !utils.BindAndValidateJSON(c, &req) {
 		return
 	}
 
-	if err := h.service.UpdateFormSettings(formID, &req); err != nil {
-		c.JSON(500, gin.H{"message": "Failed to update form setting", "error": err.Error()})
+	if err := h.service.UpdateFormSettings(utils.MustGetUserID(c), formID, &req, utils.GetRequestMeta(c)); err != nil {
+		c.JSON(formErrorStatus(err), gin.H{"message": "Failed to update form setting", "error": err.Error()})
 		return
 	}
 	c.JSON(200, gin.H{"message": "Form setting updated successfully"})
@@ -88,9 +91,9 @@ func (h *FormHandler) AddFormSection(c *gin.Context) {
 		return
 	}
 
-	data, err := h.service.AddFormSection(&req)
+	data, err := h.service.AddFormSection(utils.MustGetUserID(c), &req, utils.GetRequestMeta(c))
 	if err != nil {
-		c.JSON(500, gin.H{"message": "Failed to add section", "error": err.Error()})
+		c.JSON(formErrorStatus(err), gin.H{"message": "Failed to add section", "error": err.Error()})
 		return
 	}
 
@@ -112,8 +115,8 @@ func (h *FormHandler) UpdateFormSections(c *gin.Context) {
 	if !utils.BindAndValidateJSON(c, &req) {
 		return
 	}
-	if err := h.service.UpdateFormSections(&req); err != nil {
-		c.JSON(500, gin.H{"message": "Failed to update section", "error": err.Error()})
+	if err := h.service.UpdateFormSections(utils.MustGetUserID(c), &req, utils.GetRequestMeta(c)); err != nil {
+		c.JSON(formErrorStatus(err), gin.H{"message": "Failed to update section", "error": err.Error()})
 		return
 	}
 	c.JSON(200, gin.H{"message": "Section updated successfully"})
@@ -121,8 +124,8 @@ func (h *FormHandler) UpdateFormSections(c *gin.Context) {
 
 func (h *FormHandler) DeleteFormSections(c *gin.Context) {
 	sectionID := c.Param("sectionId")
-	if err := h.service.DeleteFormSections(sectionID); err != nil {
-		c.JSON(500, gin.H{"message": "Failed to delete section", "error": err.Error()})
+	if err := h.service.DeleteFormSections(utils.MustGetUserID(c), sectionID, utils.GetRequestMeta(c)); err != nil {
+		c.JSON(formErrorStatus(err), gin.H{"message": "Failed to delete section", "error": err.Error()})
 		return
 	}
 	c.JSON(200, gin.H{"message": "Section deleted successfully"})
@@ -143,8 +146,8 @@ func (h *FormHandler) AddFormQuestion(c *gin.Context) {
 	if !utils.BindAndValidateJSON(c, &req) {
 		return
 	}
-	if err := h.service.AddFormQuestion(&req); err != nil {
-		c.JSON(500, gin.H{"message": "Failed to add question", "error": err.Error()})
+	if err := h.service.AddFormQuestion(utils.MustGetUserID(c), &req, utils.GetRequestMeta(c)); err != nil {
+		c.JSON(formErrorStatus(err), gin.H{"message": "Failed to add question", "error": err.Error()})
 		return
 	}
 	c.JSON(201, gin.H{"message": "Question added successfully"})
@@ -155,8 +158,8 @@ func (h *FormHandler) UpdateQuestion(c *gin.Context) {
 	if !utils.BindAndValidateJSON(c, &req) {
 		return
 	}
-	if err := h.service.UpdateQuestion(&req); err != nil {
-		c.JSON(500, gin.H{"message": "Failed to update question", "error": err.Error()})
+	if err := h.service.UpdateQuestion(utils.MustGetUserID(c), &req, utils.GetRequestMeta(c)); err != nil {
+		c.JSON(formErrorStatus(err), gin.H{"message": "Failed to update question", "error": err.Error()})
 		return
 	}
 	c.JSON(200, gin.H{"message": "Question updated successfully"})
@@ -164,9 +167,113 @@ func (h *FormHandler) UpdateQuestion(c *gin.Context) {
 
 func (h *FormHandler) DeleteQuestion(c *gin.Context) {
 	questionID := c.Param("questionId")
-	if err := h.service.DeleteQuestion(questionID); err != nil {
-		c.JSON(500, gin.H{"message": "Failed to delete question", "error": err.Error()})
+	if err := h.service.DeleteQuestion(utils.MustGetUserID(c), questionID, utils.GetRequestMeta(c)); err != nil {
+		c.JSON(formErrorStatus(err), gin.H{"message": "Failed to delete question", "error": err.Error()})
 		return
 	}
 	c.JSON(200, gin.H{"message": "Question deleted successfully"})
 }
+
+// formErrorStatus memetakan form, section atau question yang tidak ditemukan (termasuk milik user
+// lain) ke 404; error lain tetap 500.
+func formErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrFormNotFound),
+		errors.Is(err, services.ErrSectionNotFound),
+		errors.Is(err, services.ErrQuestionNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *FormHandler) PublishForm(c *gin.Context) {
+	data, err := h.service.PublishForm(utils.MustGetUserID(c), c.Param("id"), utils.GetRequestMeta(c))
+	if err != nil {
+		if errors.Is(err, services.ErrNoChangesToPublish) {
+			c.JSON(http.StatusConflict, gin.H{"message": "Nothing to publish", "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Failed to publish form", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Form published successfully", "data": data})
+}
+
+func (h *FormHandler) GetFormVersions(c *gin.Context) {
+	data, err := h.service.GetFormVersions(utils.MustGetUserID(c), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Failed to fetch form versions", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (h *FormHandler) GetFormVersion(c *gin.Context) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid version number"})
+		return
+	}
+	data, err := h.service.GetFormVersion(utils.MustGetUserID(c), c.Param("id"), version)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Form version not found", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (h *FormHandler) DiffFormVersions(c *gin.Context) {
+	from, errFrom := strconv.Atoi(c.Query("from"))
+	to, errTo := strconv.Atoi(c.Query("to"))
+	if errFrom != nil || errTo != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Query parameters from and to must be version numbers"})
+		return
+	}
+	data, err := h.service.DiffFormVersions(utils.MustGetUserID(c), c.Param("id"), from, to)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Failed to compare form versions", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (h *FormHandler) AddInvitees(c *gin.Context) {
+	var req dto.AddInviteesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "error": err.Error()})
+		return
+	}
+	added, err := h.service.AddInvitees(utils.MustGetUserID(c), c.Param("id"), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInviteeLimitReached) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "Invitee limit reached", "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Failed to add invitees", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Invitees added successfully", "data": gin.H{"added": added}})
+}
+
+func (h *FormHandler) GetInvitees(c *gin.Context) {
+	data, err := h.service.GetInvitees(utils.MustGetUserID(c), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Failed to fetch invitees", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (h *FormHandler) RemoveInvitee(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("inviteeId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid invitee id"})
+		return
+	}
+	if err := h.service.RemoveInvitee(utils.MustGetUserID(c), c.Param("id"), uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Failed to remove invitee", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Invitee removed successfully"})
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"server/internal/dto"
+	"server/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MetricsHandler struct {
+	service services.MetricsService
+}
+
+func NewMetricsHandler(service services.MetricsService) *MetricsHandler {
+	return &MetricsHandler{service}
+}
+
+func bindMetricsQuery(c *gin.Context) (dto.MetricsQuery, bool) {
+	var q dto.MetricsQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid query parameter", "error": err.Error()})
+		return q, false
+	}
+	return q, true
+}
+
+func (h *MetricsHandler) GetMRR(c *gin.Context) {
+	res, err := h.service.GetMRR()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to fetch MRR", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *MetricsHandler) GetRevenue(c *gin.Context) {
+	q, ok := bindMetricsQuery(c)
+	if !ok {
+		return
+	}
+
+	res, err := h.service.GetRevenue(q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Failed to fetch revenue", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *MetricsHandler) GetSubscriptionFlow(c *gin.Context) {
+	q, ok := bindMetricsQuery(c)
+	if !ok {
+		return
+	}
+
+	res, err := h.service.GetSubscriptionFlow(q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Failed to fetch subscription metrics", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *MetricsHandler) GetConversion(c *gin.Context) {
+	q, ok := bindMetricsQuery(c)
+	if !ok {
+		return
+	}
+
+	res, err := h.service.GetConversion(q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Failed to fetch conversion", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *MetricsHandler) GetTokenConsumption(c *gin.Context) {
+	q, ok := bindMetricsQuery(c)
+	if !ok {
+		return
+	}
+
+	res, err := h.service.GetTokenConsumption(q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Failed to fetch token consumption", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
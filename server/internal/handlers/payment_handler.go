@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"server/internal/dto"
+	"server/internal/gateway"
 	"server/internal/services"
 	"server/internal/utils"
 
@@ -25,8 +27,13 @@ func (h *PaymentHandler) CreateNewPayment(c *gin.Context) {
 
 	userID := utils.MustGetUserID(c)
 
-	res, err := h.service.CreatePayment(userID, req)
+	res, err := h.service.CreatePayment(userID, req, utils.GetRequestMeta(c))
 	if err != nil {
+		var voucherErr *services.VoucherError
+		if errors.As(err, &voucherErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "Voucher cannot be applied", "error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create payment", "error": err.Error()})
 		return
 	}
@@ -35,12 +42,18 @@ func (h *PaymentHandler) CreateNewPayment(c *gin.Context) {
 }
 
 func (h *PaymentHandler) HandlePaymentNotification(c *gin.Context) {
-	var notif dto.MidtransNotificationRequest
-	if !utils.BindAndValidateJSON(c, &notif) {
+	// body mentah diteruskan ke gateway untuk verifikasi signature
+	payload, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid notification payload", "error": err.Error()})
 		return
 	}
 
-	if err := h.service.HandlePaymentNotification(notif); err != nil {
+	if err := h.service.HandlePaymentNotification(payload, utils.GetRequestMeta(c)); err != nil {
+		if errors.Is(err, gateway.ErrInvalidSignature) {
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid notification signature", "error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to process payment notification", "error": err.Error()})
 		return
 	}
@@ -72,3 +85,95 @@ func (h *PaymentHandler) GetPaymentDetail(c *gin.Context) {
 
 	c.JSON(http.StatusOK, payment)
 }
+
+// SimulatePayment hanya tersedia saat PAYMENT_GATEWAY=fake
+func (h *PaymentHandler) SimulatePayment(c *gin.Context) {
+	orderID := c.Param("id")
+	event := c.Param("event")
+
+	if err := h.service.SimulatePayment(orderID, event, utils.GetRequestMeta(c)); err != nil {
+		if errors.Is(err, services.ErrSimulationUnavailable) || errors.Is(err, gateway.ErrTransactionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Failed to simulate payment", "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Failed to simulate payment", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Payment event " + event + " simulated"})
+}
+
+func (h *PaymentHandler) GetFakeCheckout(c *gin.Context) {
+	status, err := h.service.GetGatewayStatus(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Checkout not found", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"checkout": status,
+		"events":   []string{"settle", "expire", "deny", "cancel"},
+	})
+}
+
+func (h *PaymentHandler) RunReconciliation(c *gin.Context) {
+	mismatches, err := h.service.ReconcilePayments()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to reconcile payments", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Payment reconciliation completed", "mismatches": mismatches})
+}
+
+func (h *PaymentHandler) GetPaymentMismatches(c *gin.Context) {
+	page := utils.StringToInt(c.DefaultQuery("page", "1"))
+	limit := utils.StringToInt(c.DefaultQuery("limit", "10"))
+
+	var resolved *bool
+	if val := c.Query("resolved"); val != "" {
+		parsed := val == "true"
+		resolved = &parsed
+	}
+
+	res, err := h.service.GetPaymentMismatches(resolved, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to fetch reconciliation report", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *PaymentHandler) ResolvePaymentMismatch(c *gin.Context) {
+	id := utils.StringToInt(c.Param("id"))
+
+	var req dto.ResolveMismatchRequest
+	if !utils.BindAndValidateJSON(c, &req) {
+		return
+	}
+
+	if err := h.service.ResolvePaymentMismatch(uint(id), req.Note, utils.GetRequestMeta(c)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Open mismatch not found", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Mismatch resolved"})
+}
+
+func (h *PaymentHandler) RefundPayment(c *gin.Context) {
+	var req dto.RefundPaymentRequest
+	if !utils.BindAndValidateJSON(c, &req) {
+		return
+	}
+
+	adminID := utils.MustGetUserID(c)
+
+	res, err := h.service.RefundPayment(adminID, c.Param("id"), req, utils.GetRequestMeta(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Failed to refund payment", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, res)
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"net/http"
 	"server/internal/services"
+	"server/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,6 +16,29 @@ func NewQueueHandler(service services.QueueService) *QueueHandler {
 	return &QueueHandler{service}
 }
 
-func (h *QueueHandler) GetAllQueue(c *gin.Context)   {}
-func (h *QueueHandler) ExecuteQueue(c *gin.Context)  {}
-func (h *QueueHandler) CompleteQueue(c *gin.Context) {}
+func (h *QueueHandler) GetAllQueue(c *gin.Context) {
+	data, err := h.service.GetAllQueue(utils.MustGetUserID(c), c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to fetch queue", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (h *QueueHandler) ExecuteQueue(c *gin.Context) {
+	data, err := h.service.ExecuteQueue(utils.MustGetUserID(c), c.Param("responseId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Failed to execute queue", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (h *QueueHandler) CompleteQueue(c *gin.Context) {
+	data, err := h.service.CompleteQueue(utils.MustGetUserID(c), c.Param("responseId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Failed to complete queue", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"server/internal/services"
+	"server/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RBACHandler struct {
+	service services.RBACService
+}
+
+func NewRBACHandler(service services.RBACService) *RBACHandler {
+	return &RBACHandler{service}
+}
+
+func (h *RBACHandler) GetRolePermissions(c *gin.Context) {
+	res, err := h.service.GetRolePermissions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to fetch roles", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *RBACHandler) PromoteUser(c *gin.Context) {
+	h.changeRole(c, services.RoleAdmin, "User promoted to admin")
+}
+
+func (h *RBACHandler) DemoteUser(c *gin.Context) {
+	h.changeRole(c, services.RoleUser, "User demoted to user")
+}
+
+func (h *RBACHandler) changeRole(c *gin.Context, role, message string) {
+	actorID := utils.MustGetUserID(c)
+
+	if err := h.service.ChangeUserRole(actorID, c.Param("id"), role); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Failed to change user role", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
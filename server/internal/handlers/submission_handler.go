@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"net/http"
+	"server/internal/dto"
 	"server/internal/services"
+	"server/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,6 +18,83 @@ func NewSubmissionHandler(service services.SubmissionService) *SubmissionHandler
 	return &SubmissionHandler{service}
 }
 
-func (h *SubmissionHandler) SendFormSubmission(c *gin.Context)   {}
-func (h *SubmissionHandler) GetFormSubmissions(c *gin.Context)   {}
-func (h *SubmissionHandler) GetSubmissionsResult(c *gin.Context) {}
+func (h *SubmissionHandler) GetSubmissionChallenge(c *gin.Context) {
+	data, err := h.service.GetSubmissionChallenge(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Form not found", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (h *SubmissionHandler) SendFormSubmission(c *gin.Context) {
+	var req dto.SubmissionRequest
+	if !utils.BindAndValidateJSON(c, &req) {
+		return
+	}
+	req.FormID = c.Param("id")
+
+	// respon sama untuk submission yang ditandai spam agar bot tidak tahu pengecekan mana yang gagal
+	if err := h.service.SendSubmission(&req, utils.GetRequestMeta(c)); err != nil {
+		if errors.Is(err, services.ErrQuotaExceeded) || errors.Is(err, services.ErrSubscriptionInactive) {
+			c.JSON(http.StatusPaymentRequired, gin.H{"message": "Form owner has no remaining quota", "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Failed to send submission", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Submission sent successfully"})
+}
+
+func (h *SubmissionHandler) GetFormSubmissions(c *gin.Context) {
+	data, err := h.service.GetFormSubmissions(utils.MustGetUserID(c), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Failed to fetch submissions", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (h *SubmissionHandler) GetLiveForm(c *gin.Context) {
+	data, err := h.service.GetLiveForm(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Form not available", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (h *SubmissionHandler) GetRetentionLogs(c *gin.Context) {
+	data, err := h.service.GetRetentionLogs(utils.MustGetUserID(c), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Failed to fetch retention logs", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (h *SubmissionHandler) GetSubmissionsResult(c *gin.Context) {
+	data, err := h.service.GetSubmissionResult(utils.MustGetUserID(c), c.Param("sessionid"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Submission not found", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (h *SubmissionHandler) ExportSubmissions(c *gin.Context) {
+	data, filename, err := h.service.ExportSubmissions(utils.MustGetUserID(c), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Failed to export submissions", "error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, "text/csv", data)
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"server/internal/dto"
 	"server/internal/services"
+	"server/internal/utils"
 
 	"strconv"
 
@@ -26,7 +27,7 @@ func (h *SubscriptionHandler) CreateSubscriptionTier(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.CreateTier(&req); err != nil {
+	if err := h.service.CreateTier(&req, utils.GetRequestMeta(c)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create tier", "error": err.Error()})
 		return
 	}
@@ -42,7 +43,7 @@ func (h *SubscriptionHandler) UpdateSubscriptionTier(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.UpdateTier(&req); err != nil {
+	if err := h.service.UpdateTier(&req, utils.GetRequestMeta(c)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to update tier", "error": err.Error()})
 		return
 	}
@@ -59,7 +60,7 @@ func (h *SubscriptionHandler) DeleteSubscriptionTier(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.DeleteTier(uint(id)); err != nil {
+	if err := h.service.DeleteTier(uint(id), utils.GetRequestMeta(c)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to delete tier", "error": err.Error()})
 		return
 	}
@@ -69,7 +70,7 @@ func (h *SubscriptionHandler) DeleteSubscriptionTier(c *gin.Context) {
 
 // 4. Reset User Token (via cron job)
 func (h *SubscriptionHandler) ResetUserSubscription(c *gin.Context) {
-	if err := h.service.ResetAllUserTokens(); err != nil {
+	if err := h.service.ResetAllUserTokens(utils.GetRequestMeta(c)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to reset user tokens", "error": err.Error()})
 		return
 	}
@@ -100,3 +101,90 @@ func (h *SubscriptionHandler) GetUserDetailSubscriptions(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"data": data})
 }
+
+// 7. Create Voucher
+func (h *SubscriptionHandler) CreateVoucher(c *gin.Context) {
+	var req dto.CreateVoucherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid input", "error": err.Error()})
+		return
+	}
+
+	if err := h.service.CreateVoucher(&req, utils.GetRequestMeta(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Failed to create voucher", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Voucher created successfully"})
+}
+
+// 8. Update Voucher
+func (h *SubscriptionHandler) UpdateVoucher(c *gin.Context) {
+	var req dto.UpdateVoucherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid input", "error": err.Error()})
+		return
+	}
+
+	if err := h.service.UpdateVoucher(&req, utils.GetRequestMeta(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Failed to update voucher", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Voucher updated successfully"})
+}
+
+// 9. Delete Voucher
+func (h *SubscriptionHandler) DeleteVoucher(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("voucherId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid voucher ID"})
+		return
+	}
+
+	if err := h.service.DeleteVoucher(uint(id), utils.GetRequestMeta(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to delete voucher", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Voucher deleted successfully"})
+}
+
+// 10. Get All Vouchers
+func (h *SubscriptionHandler) GetAllVouchers(c *gin.Context) {
+	data, err := h.service.GetAllVouchers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to fetch vouchers", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// 11. Get Voucher Detail
+func (h *SubscriptionHandler) GetVoucherDetail(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("voucherId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid voucher ID"})
+		return
+	}
+
+	data, err := h.service.GetVoucherDetail(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Voucher not found", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// 12. Apply Scheduled Downgrades (via cron job)
+func (h *SubscriptionHandler) ApplyScheduledTierChanges(c *gin.Context) {
+	applied, err := h.service.ApplyScheduledTierChanges()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to apply scheduled tier changes", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Scheduled tier changes applied successfully", "applied": applied})
+}
@@ -69,14 +69,37 @@ func (h *UserHandler) GetMySubscription(c *gin.Context) {
 	c.JSON(200, data)
 }
 
-func (h *UserHandler) UpdateUserSubscription(c *gin.Context) {
+func (h *UserHandler) CancelMySubscription(c *gin.Context) {
 	userID := utils.MustGetUserID(c)
-	used := utils.GetQueryInt(c, "used", 1) // default: 1
-	if err := h.service.UpdateUserSubscriptionToken(userID, used); err != nil {
+	data, err := h.service.CancelSubscription(userID)
+	if err != nil {
+		c.JSON(400, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Subscription canceled, access remains until it expires", "data": data})
+}
+
+func (h *UserHandler) ResumeMySubscription(c *gin.Context) {
+	userID := utils.MustGetUserID(c)
+	data, err := h.service.ResumeSubscription(userID)
+	if err != nil {
 		c.JSON(400, gin.H{"message": err.Error()})
 		return
 	}
-	c.JSON(200, gin.H{"message": "Token usage updated"})
+	c.JSON(200, gin.H{"message": "Subscription resumed", "data": data})
+}
+
+func (h *UserHandler) GetMyTokenUsage(c *gin.Context) {
+	userID := utils.MustGetUserID(c)
+	page := utils.GetQueryInt(c, "page", 1)
+	limit := utils.GetQueryInt(c, "limit", 20)
+
+	data, err := h.service.GetTokenUsage(userID, page, limit)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(200, data)
 }
 
 func (h *UserHandler) GetMyTransactionHistory(c *gin.Context) {
@@ -89,6 +112,28 @@ func (h *UserHandler) GetMyTransactionHistory(c *gin.Context) {
 	c.JSON(200, data)
 }
 
+func (h *UserHandler) GetMyInvoices(c *gin.Context) {
+	userID := utils.MustGetUserID(c)
+	data, err := h.service.GetInvoices(userID)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(200, data)
+}
+
+func (h *UserHandler) DownloadInvoice(c *gin.Context) {
+	userID := utils.MustGetUserID(c)
+	pdf, filename, err := h.service.GetInvoicePDF(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(404, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(200, "application/pdf", pdf)
+}
+
 func (h *UserHandler) GetMyForms(c *gin.Context) {
 	userID := utils.MustGetUserID(c)
 	data, err := h.service.GetMyForms(userID)
@@ -108,3 +153,40 @@ func (h *UserHandler) GetMyFormDetail(c *gin.Context) {
 	}
 	c.JSON(200, data)
 }
+
+func (h *UserHandler) ExportMyData(c *gin.Context) {
+	userID := utils.MustGetUserID(c)
+	archive, filename, err := h.service.ExportData(userID)
+	if err != nil {
+		c.JSON(500, gin.H{"message": "Failed to export data", "error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(200, "application/zip", archive)
+}
+
+func (h *UserHandler) RequestAccountDeletion(c *gin.Context) {
+	userID := utils.MustGetUserID(c)
+	var req dto.AccountDeletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"message": "Invalid request", "error": err.Error()})
+		return
+	}
+
+	res, err := h.service.ScheduleAccountDeletion(userID, &req)
+	if err != nil {
+		c.JSON(400, gin.H{"message": "Failed to schedule account deletion", "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Account deletion scheduled", "data": res})
+}
+
+func (h *UserHandler) CancelAccountDeletion(c *gin.Context) {
+	userID := utils.MustGetUserID(c)
+	if err := h.service.CancelAccountDeletion(userID); err != nil {
+		c.JSON(400, gin.H{"message": "Failed to cancel account deletion", "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Account deletion canceled"})
+}
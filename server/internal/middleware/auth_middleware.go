@@ -2,16 +2,51 @@ package middleware
 
 import (
 	"net/http"
+	"server/internal/services"
 	"server/internal/utils"
 	"slices"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+var apiKeys services.APIKeyService
+
+// UseAPIKeys mengaktifkan personal API key (header X-API-KEY) sebagai alternatif cookie di AuthRequired.
+func UseAPIKeys(service services.APIKeyService) {
+	apiKeys = service
+}
+
 func AuthRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		tokenString, err := c.Cookie("accessToken")
-		if err != nil || tokenString == "" {
+		bearer := utils.BearerToken(c)
+
+		// header X-API-KEY lama (shared key global) diabaikan, hanya personal key berprefix yang diproses.
+		// Personal key juga boleh dikirim sebagai Bearer token.
+		rawKey := c.GetHeader("X-API-KEY")
+		if services.IsPersonalAPIKey(bearer) {
+			rawKey = bearer
+		}
+		if services.IsPersonalAPIKey(rawKey) && apiKeys != nil {
+			key, user, err := apiKeys.Authenticate(rawKey, c.ClientIP())
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": err.Error()})
+				return
+			}
+
+			c.Set("userID", key.UserID.String())
+			c.Set("role", user.Role)
+			c.Set("apiKeyID", key.ID.String())
+			c.Set("apiKeyScopes", strings.Split(key.Scopes, ","))
+			c.Next()
+			return
+		}
+
+		tokenString := bearer
+		if tokenString == "" {
+			tokenString, _ = c.Cookie("accessToken")
+		}
+		if tokenString == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized!! Token missing"})
 			return
 		}
@@ -23,11 +58,23 @@ func AuthRequired() gin.HandlerFunc {
 		}
 
 		c.Set("userID", claims.UserID)
+		c.Set("role", claims.Role)
 
 		c.Next()
 	}
 }
 
+// SessionOnly menolak request yang diautentikasi dengan API key (mis. pengelolaan akun dan sesi).
+func SessionOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, viaKey := c.Get("apiKeyID"); viaKey {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "This endpoint is not available for API keys"})
+			return
+		}
+		c.Next()
+	}
+}
+
 func RoleOnly(allowedRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role := utils.MustGetRole(c)
@@ -1,12 +1,34 @@
 package middleware
 
 import (
+	"fmt"
+	"math"
 	"net/http"
+	"server/internal/ratelimit"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/time/rate"
 )
 
+// policy rate limit per route. Endpoint kredensial dan submission publik dibatasi ketat per IP,
+// endpoint terautentikasi dibatasi per API key / user dengan batas baca lebih longgar dari tulis.
+var (
+	PolicyGlobal     = ratelimit.Policy{Name: "global", Limit: 600, Period: time.Minute}
+	PolicyAuth       = ratelimit.Policy{Name: "auth", Limit: 10, Period: time.Minute}
+	PolicySession    = ratelimit.Policy{Name: "session", Limit: 120, Period: time.Minute}
+	PolicySubmission = ratelimit.Policy{Name: "submission", Limit: 10, Period: time.Minute}
+	PolicyRead       = ratelimit.Policy{Name: "read", Limit: 300, Period: time.Minute}
+	PolicyWrite      = ratelimit.Policy{Name: "write", Limit: 60, Period: time.Minute}
+)
+
+var limiter ratelimit.Limiter
+
+// UseRateLimiter memasang limiter yang dipakai RateLimit; tanpa limiter middleware tidak membatasi apa pun.
+func UseRateLimiter(l ratelimit.Limiter) {
+	limiter = l
+}
+
 func LimitFileSize(maxSize int64) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
@@ -14,14 +36,62 @@ func LimitFileSize(maxSize int64) gin.HandlerFunc {
 	}
 }
 
-func RateLimiter(r rate.Limit, b int) gin.HandlerFunc {
-	limiter := rate.NewLimiter(r, b)
+// RateLimit membatasi request per client dengan satu policy.
+func RateLimit(policy ratelimit.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		applyRateLimit(c, policy)
+	}
+}
+
+// RateLimitByMethod memakai policy read untuk GET/HEAD dan policy write untuk method lain.
+func RateLimitByMethod(read, write ratelimit.Policy) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if !limiter.Allow() {
-			c.JSON(http.StatusTooManyRequests, gin.H{"message": "Too many requests"})
-			c.Abort()
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			applyRateLimit(c, read)
 			return
 		}
+		applyRateLimit(c, write)
+	}
+}
+
+func applyRateLimit(c *gin.Context, policy ratelimit.Policy) {
+	if limiter == nil {
 		c.Next()
+		return
 	}
+
+	res, err := limiter.Allow(rateLimitKey(c), policy)
+	if err != nil {
+		c.Next()
+		return
+	}
+
+	c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+	c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, int(policy.Period.Seconds())))
+
+	if !res.Allowed {
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"message": "Too many requests"})
+		return
+	}
+	c.Next()
+}
+
+// rateLimitKey memilih identitas client: API key, lalu user, lalu IP. Key dan user hanya tersedia
+// bila middleware ini dipasang setelah AuthRequired. ClientIP hanya membaca X-Forwarded-For dari
+// proxy di TRUSTED_PROXIES sehingga header palsu tidak menghasilkan bucket baru.
+func rateLimitKey(c *gin.Context) string {
+	if keyID := c.GetString("apiKeyID"); keyID != "" {
+		return "key:" + keyID
+	}
+	if userID := c.GetString("userID"); userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
 }
@@ -17,12 +17,13 @@ func Logger() gin.HandlerFunc {
 		latency := end.Sub(start)
 
 		status := c.Writer.Status()
-		log.Printf("| %3d | %13v | %15s | %-7s  %s\n",
+		log.Printf("| %3d | %13v | %15s | %-7s  %s | %s\n",
 			status,
 			latency,
 			c.ClientIP(),
 			c.Request.Method,
 			path,
+			c.GetString("requestID"),
 		)
 	}
 }
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"server/internal/services"
+	"server/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChargeTokens memotong token user sebelum handler dijalankan dan mengembalikannya
+// bila handler merespon dengan status error. Harus dipasang setelah AuthRequired.
+func ChargeTokens(quota services.QuotaService, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := utils.MustGetUserID(c)
+		chargeAndRun(c, quota, func() (uint, error) {
+			return quota.Charge(userID, action, c.Param("id"))
+		})
+	}
+}
+
+// ChargeFormOwner sama seperti ChargeTokens tetapi membebankan token ke pemilik form pada
+// parameter :id, dipakai untuk aksi atas form seperti export dan analytics.
+func ChargeFormOwner(quota services.QuotaService, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := utils.MustGetUserID(c)
+		chargeAndRun(c, quota, func() (uint, error) {
+			return quota.ChargeFormOwner(userID, c.Param("id"), action)
+		})
+	}
+}
+
+func chargeAndRun(c *gin.Context, quota services.QuotaService, charge func() (uint, error)) {
+	usageID, err := charge()
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrQuotaExceeded) || errors.Is(err, services.ErrSubscriptionInactive):
+			c.AbortWithStatusJSON(http.StatusPaymentRequired, gin.H{"message": err.Error()})
+		case errors.Is(err, services.ErrFormNotFound):
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		default:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Failed to charge tokens", "error": err.Error()})
+		}
+		return
+	}
+
+	c.Next()
+
+	if c.Writer.Status() >= http.StatusBadRequest {
+		if err := quota.Refund(usageID); err != nil {
+			log.Printf("failed to refund token usage %d: %v", usageID, err)
+		}
+	}
+}
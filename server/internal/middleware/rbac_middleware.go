@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"server/internal/services"
+	"server/internal/utils"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission mengecek permission berdasarkan role terbaru user (bukan role di token),
+// dipasang setelah AuthRequired.
+func RequirePermission(rbac services.RBACService, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := utils.MustGetUserID(c)
+
+		// API key hanya boleh mengakses permission yang punya scope, dan scope itu harus dimiliki key
+		if scopes, viaKey := c.Get("apiKeyScopes"); viaKey {
+			scope := services.APIKeyScopeFor(permission, c.Request.Method)
+			if scope == "" || !slices.Contains(scopes.([]string), scope) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "API key is missing the required scope", "scope": scope})
+				return
+			}
+		}
+
+		allowed, role, err := rbac.HasPermission(userID, permission)
+		if errors.Is(err, services.ErrTwoFactorRequired) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": err.Error(), "code": "two_factor_required"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Unable to resolve user role"})
+			return
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "Forbidden: Access denied"})
+			return
+		}
+
+		c.Set("role", role)
+		c.Next()
+	}
+}
@@ -17,30 +17,128 @@ type User struct {
 	CreatedAt time.Time `gorm:"autoCreateTime"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime"`
 
+	// TOTP 2FA, secret hanya terisi setelah enrollment diverifikasi dan disimpan terenkripsi (AES-GCM)
+	TwoFactorEnabled   bool       `gorm:"not null;default:false" json:"-"`
+	TwoFactorSecret    string     `gorm:"type:varchar(255)" json:"-"`
+	TwoFactorEnabledAt *time.Time `json:"-"`
+
+	// penghapusan akun: dijadwalkan dengan masa tenggang, lalu data pribadi dihapus dan baris user
+	// dianonimkan (tetap ada agar payment untuk keperluan akuntansi tidak kehilangan relasi)
+	DeletionScheduledAt *time.Time `gorm:"index" json:"-"`
+	AnonymizedAt        *time.Time `json:"-"`
+
 	Forms []Form `gorm:"foreignKey:UserID"`
 }
 
+// kode pemulihan 2FA disimpan sebagai hash SHA-256, masing-masing hanya bisa dipakai sekali
+type TwoFactorRecoveryCode struct {
+	ID       uint      `gorm:"primaryKey"`
+	UserID   uuid.UUID `gorm:"type:char(36);not null;index"`
+	CodeHash string    `gorm:"type:char(64);not null;index"`
+	UsedAt   *time.Time
+}
+
+// personal API key milik user. Key hanya ditampilkan sekali saat dibuat; yang disimpan hanya prefix
+// (untuk lookup) dan hash SHA-256 dari key lengkap. Scopes dipisah koma, mis. "forms:read,submissions:read".
+type APIKey struct {
+	ID         uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:char(36);not null;index" json:"userId"`
+	Name       string     `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix     string     `gorm:"type:varchar(16);uniqueIndex;not null" json:"prefix"`
+	KeyHash    string     `gorm:"type:char(64);not null" json:"-"`
+	Scopes     string     `gorm:"type:varchar(255);not null" json:"scopes"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	LastUsedIP string     `gorm:"type:varchar(45)" json:"lastUsedIp"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// log event keamanan (login gagal, lockout, unlock, reuse refresh token, dll).
+// UserID kosong jika event terjadi untuk email yang tidak terdaftar.
+type SecurityEvent struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    *uuid.UUID `gorm:"type:char(36);index"`
+	Email     string     `gorm:"type:varchar(255);index"`
+	Event     string     `gorm:"type:varchar(50);not null;index"`
+	IPAddress string     `gorm:"type:varchar(45)"`
+	UserAgent string     `gorm:"type:varchar(255)"`
+	Detail    string     `gorm:"type:text"`
+	CreatedAt time.Time  `gorm:"index"`
+}
+
+// akun login eksternal (OpenID Connect) yang terhubung ke user, unik per provider + subject
+type UserIdentity struct {
+	ID          uuid.UUID `gorm:"type:char(36);primaryKey"`
+	UserID      uuid.UUID `gorm:"type:char(36);not null;index"`
+	Provider    string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_identity_provider_subject"`
+	Subject     string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_identity_provider_subject"`
+	Email       string    `gorm:"type:varchar(255)"`
+	LastLoginAt *time.Time
+	CreatedAt   time.Time
+}
+
+// refresh token disimpan sebagai hash SHA-256. Satu login membentuk satu family (sesi), setiap rotasi
+// membuat token baru dalam family yang sama dan soft delete token lama agar pemakaian ulang bisa dideteksi.
 type Token struct {
-	ID        uuid.UUID      `gorm:"type:char(36);primaryKey" json:"id"`
-	UserID    uuid.UUID      `gorm:"type:char(36);index;not null" json:"userId"`
-	Token     string         `gorm:"type:text;not null" json:"token"`
-	ExpiredAt time.Time      `json:"expiredAt"`
-	CreatedAt time.Time      `gorm:"autoCreateTime"`
-	UpdatedAt time.Time      `gorm:"autoUpdateTime"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               uuid.UUID      `gorm:"type:char(36);primaryKey" json:"id"`
+	UserID           uuid.UUID      `gorm:"type:char(36);index;not null" json:"userId"`
+	FamilyID         uuid.UUID      `gorm:"type:char(36);index;not null" json:"familyId"`
+	TokenHash        string         `gorm:"type:char(64);uniqueIndex;not null" json:"-"`
+	UserAgent        string         `gorm:"type:varchar(255)" json:"userAgent"`
+	IPAddress        string         `gorm:"type:varchar(45)" json:"ipAddress"`
+	SessionStartedAt time.Time      `json:"sessionStartedAt"`
+	LastUsedAt       time.Time      `json:"lastUsedAt"`
+	ExpiredAt        time.Time      `json:"expiredAt"`
+	CreatedAt        time.Time      `gorm:"autoCreateTime"`
+	UpdatedAt        time.Time      `gorm:"autoUpdateTime"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// tabel permission per role, role yang valid adalah role yang punya minimal satu baris di sini
+type RolePermission struct {
+	Role       string `gorm:"type:varchar(30);primaryKey"`
+	Permission string `gorm:"type:varchar(50);primaryKey"`
+}
+
+// token reset password disimpan sebagai hash SHA-256 dan hanya bisa dipakai sekali
+type PasswordReset struct {
+	ID        uuid.UUID `gorm:"type:char(36);primaryKey"`
+	UserID    uuid.UUID `gorm:"type:char(36);not null;index"`
+	TokenHash string    `gorm:"type:char(64);uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
 }
 
 type UserSubscription struct {
-	ID                 uint      `gorm:"primaryKey"`
-	UserID             uuid.UUID `gorm:"type:uuid;not null;uniqueIndex"`
-	SubscriptionTierID uint      `gorm:"not null"`
-	StartedAt          time.Time `gorm:"not null"`
-	ExpiresAt          time.Time `gorm:"not null"`
-	IsActive           bool      `gorm:"default:true"`
-	RemainingTokens    int       `gorm:"not null;default:0"`
+	ID                 uint       `gorm:"primaryKey"`
+	UserID             uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex"`
+	SubscriptionTierID uint       `gorm:"not null"`
+	StartedAt          time.Time  `gorm:"not null"`
+	ExpiresAt          time.Time  `gorm:"not null"`
+	IsActive           bool       `gorm:"default:true"`
+	RemainingTokens    int        `gorm:"not null;default:0"`
+	TokensResetAt      *time.Time // terakhir kali saldo token direset bulanan
+	ScheduledTierID    *uint      // tier hasil downgrade yang berlaku saat periode berjalan berakhir
+	AutoRenew          bool       `gorm:"not null;default:true"`
+	CanceledAt         *time.Time // dibatalkan user, akses tetap berjalan sampai ExpiresAt
+
+	User             User              `gorm:"foreignKey:UserID"`
+	SubscriptionTier SubscriptionTier  `gorm:"foreignKey:SubscriptionTierID"`
+	ScheduledTier    *SubscriptionTier `gorm:"foreignKey:ScheduledTierID"`
+}
 
-	User             User             `gorm:"foreignKey:UserID"`
-	SubscriptionTier SubscriptionTier `gorm:"foreignKey:SubscriptionTierID"`
+// ledger pemakaian token, setiap charge maupun refund dicatat sebagai baris baru
+type TokenUsage struct {
+	ID           uint      `gorm:"primaryKey"`
+	UserID       uuid.UUID `gorm:"type:char(36);not null;index"`
+	Action       string    `gorm:"type:varchar(50);not null;index"`
+	Tokens       int       `gorm:"not null"` // positif = charge, negatif = refund
+	BalanceAfter int       `gorm:"not null"`
+	ReferenceID  string    `gorm:"type:varchar(64)"`
+	RefundOfID   *uint     `gorm:"uniqueIndex"` // diisi pada baris refund, menunjuk baris charge yang dikembalikan
+	CreatedAt    time.Time `gorm:"index"`
 }
 
 type SubscriptionTier struct {
@@ -56,16 +154,112 @@ type Payment struct {
 	ID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
 	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
 	TierID    uint      `gorm:"not null"`
+	VoucherID *uint     `gorm:"index"`
+	Type      string    `gorm:"type:varchar(20);default:'new';check:type IN ('new','renewal','upgrade','downgrade');not null"`
 	Subtotal  float64   `gorm:"not null"`
+	Credit    float64   `gorm:"not null;default:0"` // prorata sisa paket lama saat upgrade
+	Discount  float64   `gorm:"not null;default:0"`
 	Tax       float64   `gorm:"not null"`
 	Total     float64   `gorm:"not null"`
+	Refunded  float64   `gorm:"not null;default:0"`
 	Method    string    `gorm:"type:varchar(50);not null"`
-	Status    string    `gorm:"type:varchar(20);default:'pending';check:status IN ('pending','paid','failed');not null"`
+	Status    string    `gorm:"type:varchar(20);default:'pending';check:status IN ('pending','paid','failed','refunded','partially_refunded');not null"`
 	PaidAt    *time.Time
 	CreatedAt time.Time
+	CheckedAt *time.Time `gorm:"index"` // terakhir dicocokkan ke gateway oleh job, nil = belum pernah
+
+	User    User
+	Tier    SubscriptionTier `gorm:"foreignKey:TierID"`
+	Voucher *Voucher         `gorm:"foreignKey:VoucherID"`
+	Invoice *Invoice         `gorm:"foreignKey:PaymentID"`
+	Refunds []Refund         `gorm:"foreignKey:PaymentID"`
+}
+
+// ledger refund, satu baris untuk setiap refund (penuh maupun sebagian) yang dikirim ke gateway
+type Refund struct {
+	ID                 uint      `gorm:"primaryKey"`
+	PaymentID          uuid.UUID `gorm:"type:char(36);not null;index"`
+	UserID             uuid.UUID `gorm:"type:char(36);not null;index"`
+	AdminID            uuid.UUID `gorm:"type:char(36);not null"`
+	RefundKey          string    `gorm:"type:varchar(100);uniqueIndex;not null"`
+	Type               string    `gorm:"type:varchar(10);not null;check:type IN ('full','partial')"`
+	Amount             float64   `gorm:"not null"`
+	Reason             string    `gorm:"type:text;not null"`
+	SubscriptionAction string    `gorm:"type:varchar(20);not null"` // revoked, shortened, schedule_canceled, none
+	// pending dicatat sebelum refund dikirim ke gateway, completed setelah payment ikut diperbarui
+	Status    string `gorm:"type:varchar(20);not null;default:'completed';check:status IN ('pending','completed')"`
+	CreatedAt time.Time
+}
+
+// laporan rekonsiliasi: selisih status payment lokal dengan status transaksi di gateway
+type PaymentMismatch struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement"`
+	PaymentID     uuid.UUID `gorm:"type:char(36);not null;index"`
+	Kind          string    `gorm:"type:varchar(30);not null;check:kind IN ('paid_at_gateway','failed_at_gateway','paid_locally')"`
+	LocalStatus   string    `gorm:"type:varchar(20);not null"`
+	GatewayStatus string    `gorm:"type:varchar(30);not null"`
+	Detail        string    `gorm:"type:text"`
+	Resolved      bool      `gorm:"not null;default:false;index"`
+	ResolvedAt    *time.Time
+	Note          string `gorm:"type:text"`
+	CreatedAt     time.Time
 
-	User User
-	Tier SubscriptionTier `gorm:"foreignKey:TierID"`
+	Payment Payment `gorm:"foreignKey:PaymentID"`
+}
+
+// invoice diterbitkan sekali untuk setiap payment yang lunas, data customer & tier disalin
+// saat terbit agar dokumen tidak berubah ketika profil atau harga tier diubah
+type Invoice struct {
+	ID            uuid.UUID `gorm:"type:char(36);primaryKey"`
+	Number        string    `gorm:"type:varchar(30);uniqueIndex;not null"`
+	PaymentID     uuid.UUID `gorm:"type:char(36);uniqueIndex;not null"`
+	UserID        uuid.UUID `gorm:"type:char(36);not null;index"`
+	CustomerName  string    `gorm:"type:varchar(255);not null"`
+	CustomerEmail string    `gorm:"type:varchar(255);not null"`
+	TierName      string    `gorm:"type:varchar(50);not null"`
+	Method        string    `gorm:"type:varchar(50)"`
+	Subtotal      float64   `gorm:"not null"`
+	Credit        float64   `gorm:"not null;default:0"`
+	Discount      float64   `gorm:"not null;default:0"`
+	TaxRate       float64   `gorm:"not null;default:0"`
+	Tax           float64   `gorm:"not null"`
+	Total         float64   `gorm:"not null"`
+	IssuedAt      time.Time `gorm:"not null"`
+}
+
+// counter nomor invoice per tahun, dikunci saat penerbitan agar nomor berurutan tanpa celah
+type InvoiceSequence struct {
+	Year       int `gorm:"primaryKey;autoIncrement:false"`
+	LastNumber int `gorm:"not null;default:0"`
+}
+
+type Voucher struct {
+	ID                    uint     `gorm:"primaryKey"`
+	Code                  string   `gorm:"type:varchar(50);uniqueIndex;not null"`
+	Description           string   `gorm:"type:text"`
+	DiscountType          string   `gorm:"type:varchar(20);not null;check:discount_type IN ('percentage','fixed')"`
+	DiscountValue         float64  `gorm:"not null"`
+	MaxDiscount           *float64 // batas potongan maksimum untuk tipe percentage
+	StartsAt              *time.Time
+	ExpiresAt             *time.Time
+	MaxRedemptions        *int // batas pemakaian global, nil = tanpa batas
+	MaxRedemptionsPerUser *int // batas pemakaian per user, nil = tanpa batas
+	RedeemedCount         int  `gorm:"not null;default:0"`
+	IsActive              bool `gorm:"default:true"`
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+
+	// kosong berarti voucher berlaku untuk semua tier
+	Tiers []SubscriptionTier `gorm:"many2many:voucher_tiers"`
+}
+
+type VoucherRedemption struct {
+	ID        uint      `gorm:"primaryKey"`
+	VoucherID uint      `gorm:"not null;index"`
+	UserID    uuid.UUID `gorm:"type:char(36);not null;index"`
+	PaymentID uuid.UUID `gorm:"type:char(36);not null;uniqueIndex"`
+	Amount    float64   `gorm:"not null"`
+	CreatedAt time.Time
 }
 
 type Form struct {
@@ -78,11 +272,26 @@ type Form struct {
 	Duration    *int
 	CreatedAt   time.Time
 
+	// versi yang sedang live untuk responden; nil berarti form belum pernah dipublish
+	PublishedVersionID *uuid.UUID `gorm:"type:char(36)"`
+
 	Setting     FormSetting   `gorm:"foreignKey:FormID"`
 	FormSection []FormSection `gorm:"foreignKey:FormID"`
 	Questions   []Question    `gorm:"foreignKey:FormID"`
 	Submissions []Submission  `gorm:"foreignKey:FormID"`
 }
+
+// snapshot struktur form (section, pertanyaan, opsi) yang dibekukan saat publish dan tidak pernah diubah.
+// Tabel form/section/question/option sendiri berperan sebagai draft.
+type FormVersion struct {
+	ID          uuid.UUID  `gorm:"type:char(36);primaryKey"`
+	FormID      uuid.UUID  `gorm:"type:char(36);not null;uniqueIndex:idx_form_version"`
+	Version     int        `gorm:"not null;uniqueIndex:idx_form_version"`
+	Snapshot    string     `gorm:"type:longtext;not null"` // JSON dto.FormSnapshot
+	PublishedBy *uuid.UUID `gorm:"type:char(36)"`
+	PublishedAt time.Time
+}
+
 type FormSetting struct {
 	ID                 uint      `gorm:"primaryKey"`
 	FormID             uuid.UUID `gorm:"type:char(36);not null;uniqueIndex"`
@@ -93,6 +302,33 @@ type FormSetting struct {
 	MaxSubmissions     *int      `gorm:"default:100"`   // jumlah responden yang dapat mengisi form
 	StartAt            *time.Time
 	EndAt              *time.Time
+
+	// proteksi spam untuk submission publik; submission yang gagal pengecekan ditandai (flagged), bukan dibuang
+	HoneypotEnabled       bool `gorm:"default:true"`  // field tersembunyi yang hanya diisi bot
+	MinFillSeconds        int  `gorm:"default:0"`     // waktu minimum sejak form dirender, 0 = nonaktif
+	RequireRenderToken    bool `gorm:"default:false"` // submission wajib membawa render token yang valid
+	ProofOfWorkDifficulty int  `gorm:"default:0"`     // jumlah bit nol di awal hash, 0 = nonaktif
+
+	// retensi data responden; submission lebih lama dari RetentionDays dihapus atau dianonimkan oleh job terjadwal
+	RetentionDays      int    `gorm:"default:0"` // 0 = disimpan selamanya
+	RetentionAction    string `gorm:"type:varchar(20);default:'anonymize';check:retention_action IN ('anonymize','delete')"`
+	AnonymousResponses bool   `gorm:"default:false"` // email, IP, user agent dan session token responden tidak pernah disimpan
+
+	// penjadwalan StartAt/EndAt dijalankan oleh job; penanda diisi saat form dibuka/ditutup/diingatkan
+	// agar email tidak terkirim dua kali, dan dikosongkan lagi bila jadwalnya diubah
+	ReminderHoursBefore int `gorm:"default:24"` // pengingat ke invitee sekian jam sebelum EndAt, 0 = nonaktif; tidak dikirim untuk form anonim
+	OpenedAt            *time.Time
+	ClosedAt            *time.Time
+	RemindedAt          *time.Time
+}
+
+// daftar email yang diundang mengisi form, menerima pengingat sebelum form ditutup
+type FormInvitee struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	FormID     uuid.UUID `gorm:"type:char(36);not null;uniqueIndex:idx_form_invitee"`
+	Email      string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_form_invitee"`
+	RemindedAt *time.Time
+	CreatedAt  time.Time
 }
 
 type FormSection struct {
@@ -137,6 +373,13 @@ type Submission struct {
 	UserAgent    *string `gorm:"type:text"`
 	SessionToken *string `gorm:"type:char(36);index"`
 
+	Flagged     bool   `gorm:"default:false;index"`
+	FlagReasons string `gorm:"type:varchar(255)"` // dipisah koma, mis. "honeypot,too_fast"
+
+	AnonymizedAt *time.Time // terisi setelah data identitas responden dihapus oleh kebijakan retensi
+
+	FormVersionID *uuid.UUID `gorm:"type:char(36);index"` // versi form saat submission dibuat
+
 	Answers []Answer `gorm:"foreignKey:SubmissionID"`
 }
 
@@ -148,10 +391,36 @@ type Answer struct {
 	TextAnswer   *string
 }
 
+// audit log append-only untuk mutasi form, subscription, payment dan event autentikasi.
+// Before/After hanya berisi field yang berubah (JSON), repository tidak menyediakan update/delete.
+type AuditLog struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement"`
+	ActorID    *uuid.UUID `gorm:"type:char(36);index"` // nil = sistem (job terjadwal / webhook gateway)
+	Action     string     `gorm:"type:varchar(50);not null;index"`
+	EntityType string     `gorm:"type:varchar(30);not null;index:idx_audit_entity"`
+	EntityID   string     `gorm:"type:varchar(64);index:idx_audit_entity"`
+	FormID     *uuid.UUID `gorm:"type:char(36);index"` // terisi untuk entitas milik form, dipakai riwayat per form
+	Before     string     `gorm:"type:text"`
+	After      string     `gorm:"type:text"`
+	IPAddress  string     `gorm:"type:varchar(45)"`
+	RequestID  string     `gorm:"type:varchar(64);index"`
+	CreatedAt  time.Time  `gorm:"index"`
+}
+
+// jejak audit setiap eksekusi kebijakan retensi pada sebuah form
+type RetentionLog struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	FormID    uuid.UUID `gorm:"type:char(36);not null;index"`
+	Action    string    `gorm:"type:varchar(20);not null"` // anonymize, delete
+	Affected  int64     `gorm:"not null"`
+	Cutoff    time.Time `gorm:"not null"` // submission dengan submitted_at sebelum waktu ini yang diproses
+	CreatedAt time.Time
+}
+
 // opsional untuk fitur form diagnosa
 type Queue struct {
 	ID         uuid.UUID `gorm:"type:char(36);primaryKey"`
 	ResponseID uuid.UUID `gorm:"type:char(36);not null;index"`
 	QueuNumber int
-	Status     string `gorm:"type:varchar(20);default:'pending';check:status IN ('waiting','progress','done');not null"`
+	Status     string `gorm:"type:varchar(20);default:'waiting';check:status IN ('waiting','progress','done');not null"`
 }
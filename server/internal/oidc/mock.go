@@ -0,0 +1,179 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const mockKeyID = "mock-1"
+
+type mockAuthorization struct {
+	ClientID      string
+	RedirectURI   string
+	Nonce         string
+	CodeChallenge string
+	Email         string
+	ExpiresAt     time.Time
+}
+
+// MockIssuer adalah OpenID Connect issuer minimal untuk development dan testing lokal.
+// /authorize langsung menyetujui login (email dari query login_hint) tanpa halaman consent.
+type MockIssuer struct {
+	issuer string
+	key    *rsa.PrivateKey
+	mu     sync.Mutex
+	codes  map[string]mockAuthorization
+}
+
+func NewMockIssuer(issuer string) (*MockIssuer, error) {
+	if !MockEnabled() {
+		return nil, errors.New("mock oidc issuer requires OIDC_MOCK_ENABLED=true outside production")
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return &MockIssuer{
+		issuer: strings.TrimRight(issuer, "/"),
+		key:    key,
+		codes:  make(map[string]mockAuthorization),
+	}, nil
+}
+
+// ServeHTTP menangani path relatif terhadap issuer (prefix route harus di-strip lebih dulu).
+func (m *MockIssuer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		writeJSON(w, http.StatusOK, discoveryDocument{
+			Issuer:                m.issuer,
+			AuthorizationEndpoint: m.issuer + "/authorize",
+			TokenEndpoint:         m.issuer + "/token",
+			JWKSURI:               m.issuer + "/jwks",
+		})
+	case "/jwks":
+		writeJSON(w, http.StatusOK, map[string]any{"keys": []jsonWebKey{{
+			Kid: mockKeyID,
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+		}}})
+	case "/authorize":
+		m.authorize(w, r)
+	case "/token":
+		m.token(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (m *MockIssuer) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirectURI := q.Get("redirect_uri")
+	if q.Get("response_type") != "code" || redirectURI == "" || q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	email := q.Get("login_hint")
+	if email == "" {
+		email = "mock.user@example.com"
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	code := hex.EncodeToString(b)
+
+	m.mu.Lock()
+	m.codes[code] = mockAuthorization{
+		ClientID:      q.Get("client_id"),
+		RedirectURI:   redirectURI,
+		Nonce:         q.Get("nonce"),
+		CodeChallenge: q.Get("code_challenge"),
+		Email:         strings.ToLower(email),
+		ExpiresAt:     time.Now().Add(time.Minute),
+	}
+	m.mu.Unlock()
+
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	params := target.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	target.RawQuery = params.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+func (m *MockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	m.mu.Lock()
+	auth, ok := m.codes[code]
+	delete(m.codes, code)
+	m.mu.Unlock()
+
+	if !ok || time.Now().After(auth.ExpiresAt) ||
+		auth.ClientID != r.PostForm.Get("client_id") ||
+		auth.RedirectURI != r.PostForm.Get("redirect_uri") ||
+		subtle.ConstantTimeCompare([]byte(CodeChallenge(r.PostForm.Get("code_verifier"))), []byte(auth.CodeChallenge)) != 1 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, Claims{
+		Email:         auth.Email,
+		EmailVerified: true,
+		Name:          strings.Split(auth.Email, "@")[0],
+		Nonce:         auth.Nonce,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.issuer,
+			Subject:   "mock|" + auth.Email,
+			Audience:  jwt.ClaimStrings{auth.ClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+		},
+	})
+	token.Header["kid"] = mockKeyID
+
+	idToken, err := token.SignedString(m.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": idToken,
+		"id_token":     idToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+const memorySweepEvery = 1000
+
+type memoryLimiter struct {
+	mu    sync.Mutex
+	tats  map[string]time.Time
+	calls int
+}
+
+// NewMemoryLimiter membuat limiter per proses; batasnya tidak dibagi antar instance.
+func NewMemoryLimiter() Limiter {
+	return &memoryLimiter{tats: make(map[string]time.Time)}
+}
+
+func (l *memoryLimiter) Allow(key string, policy Policy) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	key = policy.Name + ":" + key
+
+	res, tat := gcra(now, l.tats[key], policy)
+	if res.Allowed {
+		l.tats[key] = tat
+	}
+
+	// bersihkan key yang bucket-nya sudah penuh kembali agar map tidak tumbuh terus
+	l.calls++
+	if l.calls%memorySweepEvery == 0 {
+		for k, t := range l.tats {
+			if t.Before(now) {
+				delete(l.tats, k)
+			}
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Policy mengizinkan Limit request per Period, dengan burst sebesar Limit (GCRA).
+type Policy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// emissionInterval adalah jarak ideal antar request agar rata-rata tidak melebihi Limit per Period.
+func (p Policy) emissionInterval() time.Duration {
+	return p.Period / time.Duration(p.Limit)
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration // sampai bucket kembali penuh
+	RetryAfter time.Duration // hanya terisi jika ditolak
+}
+
+type Limiter interface {
+	Allow(key string, policy Policy) (Result, error)
+}
+
+// New memakai Redis sebagai limiter utama (terdistribusi antar instance) dan limiter in-memory
+// sebagai fallback selama Redis tidak bisa diakses.
+func New(client *redis.Client) Limiter {
+	return &fallbackLimiter{
+		primary:  NewRedisLimiter(client),
+		fallback: NewMemoryLimiter(),
+	}
+}
+
+type fallbackLimiter struct {
+	primary  Limiter
+	fallback Limiter
+
+	mu         sync.Mutex
+	lastLogged time.Time
+}
+
+func (l *fallbackLimiter) Allow(key string, policy Policy) (Result, error) {
+	res, err := l.primary.Allow(key, policy)
+	if err == nil {
+		return res, nil
+	}
+
+	l.mu.Lock()
+	if time.Since(l.lastLogged) > time.Minute {
+		log.Printf("rate limiter: redis unavailable, using in-memory fallback: %v", err)
+		l.lastLogged = time.Now()
+	}
+	l.mu.Unlock()
+
+	return l.fallback.Allow(key, policy)
+}
+
+// gcra menghitung keputusan dari theoretical arrival time (tat) tersimpan; dipakai limiter in-memory,
+// dengan logika yang sama seperti script Redis.
+func gcra(now, tat time.Time, policy Policy) (Result, time.Time) {
+	emission := policy.emissionInterval()
+	tolerance := emission * time.Duration(policy.Limit)
+
+	if tat.Before(now) {
+		tat = now
+	}
+	newTat := tat.Add(emission)
+	allowAt := newTat.Add(-tolerance)
+
+	if now.Before(allowAt) {
+		return Result{
+			Allowed:    false,
+			Limit:      policy.Limit,
+			Remaining:  0,
+			ResetAfter: tat.Sub(now),
+			RetryAfter: allowAt.Sub(now),
+		}, tat
+	}
+
+	return Result{
+		Allowed:    true,
+		Limit:      policy.Limit,
+		Remaining:  int((tolerance - newTat.Sub(now)) / emission),
+		ResetAfter: newTat.Sub(now),
+	}, newTat
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGCRA(t *testing.T) {
+	policy := Policy{Name: "test", Limit: 3, Period: 3 * time.Second}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	type step struct {
+		at            time.Duration // sejak start
+		wantAllowed   bool
+		wantRemaining int
+		wantRetry     time.Duration
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "burst up to limit then reject",
+			steps: []step{
+				{at: 0, wantAllowed: true, wantRemaining: 2},
+				{at: 0, wantAllowed: true, wantRemaining: 1},
+				{at: 0, wantAllowed: true, wantRemaining: 0},
+				{at: 0, wantAllowed: false, wantRetry: time.Second},
+			},
+		},
+		{
+			name: "one slot refills after one emission interval",
+			steps: []step{
+				{at: 0, wantAllowed: true, wantRemaining: 2},
+				{at: 0, wantAllowed: true, wantRemaining: 1},
+				{at: 0, wantAllowed: true, wantRemaining: 0},
+				{at: time.Second, wantAllowed: true, wantRemaining: 0},
+				{at: time.Second, wantAllowed: false, wantRetry: time.Second},
+			},
+		},
+		{
+			name: "bucket is full again after a whole period",
+			steps: []step{
+				{at: 0, wantAllowed: true, wantRemaining: 2},
+				{at: 0, wantAllowed: true, wantRemaining: 1},
+				{at: 0, wantAllowed: true, wantRemaining: 0},
+				{at: 10 * time.Second, wantAllowed: true, wantRemaining: 2},
+			},
+		},
+		{
+			name: "steady rate at the emission interval is never rejected",
+			steps: []step{
+				{at: 0, wantAllowed: true, wantRemaining: 2},
+				{at: time.Second, wantAllowed: true, wantRemaining: 2},
+				{at: 2 * time.Second, wantAllowed: true, wantRemaining: 2},
+				{at: 3 * time.Second, wantAllowed: true, wantRemaining: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tat time.Time
+			for i, s := range tt.steps {
+				res, next := gcra(start.Add(s.at), tat, policy)
+				if res.Allowed != s.wantAllowed {
+					t.Fatalf("step %d: allowed = %v, want %v", i, res.Allowed, s.wantAllowed)
+				}
+				if res.Allowed {
+					if res.Remaining != s.wantRemaining {
+						t.Errorf("step %d: remaining = %d, want %d", i, res.Remaining, s.wantRemaining)
+					}
+					tat = next
+				} else if res.RetryAfter != s.wantRetry {
+					t.Errorf("step %d: retry after = %v, want %v", i, res.RetryAfter, s.wantRetry)
+				}
+				if res.Limit != policy.Limit {
+					t.Errorf("step %d: limit = %d, want %d", i, res.Limit, policy.Limit)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryLimiterSeparatesKeysAndPolicies(t *testing.T) {
+	limiter := NewMemoryLimiter()
+	auth := Policy{Name: "auth", Limit: 2, Period: time.Minute}
+	read := Policy{Name: "read", Limit: 2, Period: time.Minute}
+
+	tests := []struct {
+		name   string
+		key    string
+		policy Policy
+		want   bool
+	}{
+		{"first request", "ip:1", auth, true},
+		{"second request", "ip:1", auth, true},
+		{"over the limit", "ip:1", auth, false},
+		{"other key has its own bucket", "ip:2", auth, true},
+		{"other policy has its own bucket", "ip:1", read, true},
+	}
+
+	for _, tt := range tests {
+		res, err := limiter.Allow(tt.key, tt.policy)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if res.Allowed != tt.want {
+			t.Errorf("%s: allowed = %v, want %v", tt.name, res.Allowed, tt.want)
+		}
+	}
+}
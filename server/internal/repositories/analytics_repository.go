@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// statistik submission sebuah form; submission yang ditandai spam dihitung terpisah
+type SubmissionStatsRow struct {
+	Total        int64
+	Flagged      int64
+	Scored       int64
+	AverageScore *float64
+	Passed       int64
+	FirstAt      *time.Time
+	LastAt       *time.Time
+}
+
+type AnswerCountRow struct {
+	FormVersionID *string // nil untuk submission yang masuk sebelum form dipublish
+	QuestionID    string
+	OptionID      *uint
+	Total         int64
+}
+
+type AnalyticsRepository interface {
+	GetSubmissionStats(formID string, passingGrade float64) (*SubmissionStatsRow, error)
+	GetAnswerCounts(formID string) ([]AnswerCountRow, error)
+}
+
+type analyticsRepository struct {
+	db *gorm.DB
+}
+
+func NewAnalyticsRepository(db *gorm.DB) AnalyticsRepository {
+	return &analyticsRepository{db}
+}
+
+func (r *analyticsRepository) GetSubmissionStats(formID string, passingGrade float64) (*SubmissionStatsRow, error) {
+	var row SubmissionStatsRow
+	err := r.db.Raw(`
+		SELECT COUNT(*) AS total,
+			COALESCE(SUM(flagged), 0) AS flagged,
+			COALESCE(SUM(flagged = 0 AND score IS NOT NULL), 0) AS scored,
+			AVG(CASE WHEN flagged = 0 THEN score END) AS average_score,
+			COALESCE(SUM(flagged = 0 AND score >= ?), 0) AS passed,
+			MIN(submitted_at) AS first_at,
+			MAX(submitted_at) AS last_at
+		FROM submissions
+		WHERE form_id = ?`, passingGrade, formID).
+		Scan(&row).Error
+	return &row, err
+}
+
+// GetAnswerCounts menghitung jawaban per versi form, per pertanyaan dan per opsi dari submission yang
+// tidak ditandai spam. Jawaban teks dikelompokkan dengan OptionID nil.
+func (r *analyticsRepository) GetAnswerCounts(formID string) ([]AnswerCountRow, error) {
+	var rows []AnswerCountRow
+	err := r.db.Raw(`
+		SELECT s.form_version_id, a.question_id, a.option_id, COUNT(*) AS total
+		FROM answers a
+		JOIN submissions s ON s.id = a.submission_id
+		WHERE s.form_id = ? AND s.flagged = ?
+		GROUP BY s.form_version_id, a.question_id, a.option_id`, formID, false).
+		Scan(&rows).Error
+	return rows, err
+}
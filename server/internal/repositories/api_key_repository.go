@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"server/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	CreateAPIKey(key *models.APIKey) error
+	GetAPIKeyByPrefix(prefix string) (*models.APIKey, error)
+	GetUserAPIKeys(userID string) ([]models.APIKey, error)
+	CountActiveAPIKeys(userID string, now time.Time) (int64, error)
+	RevokeAPIKey(userID, keyID string, at time.Time) (int64, error)
+	TouchAPIKey(keyID, ip string, at time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db}
+}
+
+func (r *apiKeyRepository) CreateAPIKey(key *models.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *apiKeyRepository) GetAPIKeyByPrefix(prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.Where("prefix = ?", prefix).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) GetUserAPIKeys(userID string) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *apiKeyRepository) CountActiveAPIKeys(userID string, now time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *apiKeyRepository) RevokeAPIKey(userID, keyID string, at time.Time) (int64, error) {
+	result := r.db.Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", keyID, userID).
+		Update("revoked_at", at)
+	return result.RowsAffected, result.Error
+}
+
+func (r *apiKeyRepository) TouchAPIKey(keyID, ip string, at time.Time) error {
+	return r.db.Model(&models.APIKey{}).Where("id = ?", keyID).
+		Updates(map[string]any{"last_used_at": at, "last_used_ip": ip}).Error
+}
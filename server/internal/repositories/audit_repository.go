@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"server/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditFilter adalah filter query audit log, field kosong diabaikan.
+type AuditFilter struct {
+	ActorID    string
+	Action     string
+	EntityType string
+	EntityID   string
+	FormID     string
+	RequestID  string
+	From       *time.Time
+	To         *time.Time
+}
+
+// AuditRepository sengaja hanya menyediakan insert dan query agar log tetap append-only.
+type AuditRepository interface {
+	Create(entry *models.AuditLog) error
+	Find(filter AuditFilter, limit, offset int) ([]models.AuditLog, int64, error)
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db}
+}
+
+func (r *auditRepository) Create(entry *models.AuditLog) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *auditRepository) Find(filter AuditFilter, limit, offset int) ([]models.AuditLog, int64, error) {
+	var logs []models.AuditLog
+	var count int64
+
+	db := r.db.Model(&models.AuditLog{})
+	if filter.ActorID != "" {
+		db = db.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		db = db.Where("action = ?", filter.Action)
+	}
+	if filter.EntityType != "" {
+		db = db.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		db = db.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.FormID != "" {
+		db = db.Where("form_id = ?", filter.FormID)
+	}
+	if filter.RequestID != "" {
+		db = db.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.From != nil {
+		db = db.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		db = db.Where("created_at < ?", *filter.To)
+	}
+
+	if err := db.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+	return logs, count, nil
+}
@@ -1,18 +1,40 @@
 package repositories
 
 import (
+	"errors"
 	"server/internal/models"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type AuthRepository interface {
 	CreateNewUser(user *models.User) error
-	DeleteRefreshToken(token string) error
 	StoreRefreshToken(token *models.Token) error
 	GetUserByID(userID string) (*models.User, error)
 	GetUserByEmail(email string) (*models.User, error)
-	FindRefreshToken(token string) (*models.Token, error)
+	FindRefreshTokenByHash(tokenHash string) (*models.Token, error)
+	RotateRefreshToken(oldTokenID string, newToken *models.Token) error
+	RevokeTokenFamily(familyID string) error
+	RevokeUserSession(userID, familyID string) (int64, error)
+	RevokeAllUserTokens(userID string) error
+	GetActiveSessions(userID string) ([]models.Token, error)
+	DeleteExpiredTokens(now time.Time) (int64, error)
+	CreatePasswordReset(reset *models.PasswordReset) error
+	GetPasswordResetByHash(tokenHash string) (*models.PasswordReset, error)
+	ResetPassword(resetID, userID, hashedPassword string) error
+	UpdatePassword(userID, hashedPassword string) error
+	EnableTwoFactor(userID, secret string, codes []models.TwoFactorRecoveryCode) error
+	DisableTwoFactor(userID string) error
+	FindPlaintextTwoFactorSecrets() ([]models.User, error)
+	UpdateTwoFactorSecret(userID, secret string) error
+	ReplaceRecoveryCodes(userID string, codes []models.TwoFactorRecoveryCode) error
+	UseRecoveryCode(userID, codeHash string) (bool, error)
+	GetIdentity(provider, subject string) (*models.UserIdentity, error)
+	CreateIdentity(identity *models.UserIdentity) error
+	CreateUserWithIdentity(user *models.User, identity *models.UserIdentity) error
+	TouchIdentity(identityID string, at time.Time) error
+	CreateSecurityEvent(event *models.SecurityEvent) error
 }
 
 type authRepository struct {
@@ -27,10 +49,6 @@ func (r *authRepository) CreateNewUser(user *models.User) error {
 	return r.db.Create(user).Error
 }
 
-func (r *authRepository) DeleteRefreshToken(token string) error {
-	return r.db.Where("token = ?", token).Delete(&models.Token{}).Error
-}
-
 func (r *authRepository) StoreRefreshToken(token *models.Token) error {
 	return r.db.Create(token).Error
 }
@@ -51,10 +69,195 @@ func (r *authRepository) GetUserByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *authRepository) FindRefreshToken(token string) (*models.Token, error) {
+// FindRefreshTokenByHash ikut mencari token yang sudah dicabut/dirotasi (soft delete) untuk deteksi reuse.
+func (r *authRepository) FindRefreshTokenByHash(tokenHash string) (*models.Token, error) {
 	var t models.Token
-	if err := r.db.Where("token = ?", token).First(&t).Error; err != nil {
+	if err := r.db.Unscoped().Where("token_hash = ?", tokenHash).First(&t).Error; err != nil {
 		return nil, err
 	}
 	return &t, nil
 }
+
+// RotateRefreshToken mencabut token lama dan menyimpan penggantinya dalam satu transaksi. Bila token lama
+// ternyata sudah dicabut oleh request lain, gorm.ErrRecordNotFound dikembalikan (dianggap reuse).
+func (r *authRepository) RotateRefreshToken(oldTokenID string, newToken *models.Token) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ?", oldTokenID).Delete(&models.Token{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Create(newToken).Error
+	})
+}
+
+func (r *authRepository) RevokeTokenFamily(familyID string) error {
+	return r.db.Where("family_id = ?", familyID).Delete(&models.Token{}).Error
+}
+
+func (r *authRepository) RevokeUserSession(userID, familyID string) (int64, error) {
+	result := r.db.Where("user_id = ? AND family_id = ?", userID, familyID).Delete(&models.Token{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *authRepository) RevokeAllUserTokens(userID string) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.Token{}).Error
+}
+
+func (r *authRepository) GetActiveSessions(userID string) ([]models.Token, error) {
+	var tokens []models.Token
+	err := r.db.Where("user_id = ? AND expired_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *authRepository) DeleteExpiredTokens(now time.Time) (int64, error) {
+	result := r.db.Unscoped().Where("expired_at < ?", now).Delete(&models.Token{})
+	return result.RowsAffected, result.Error
+}
+
+// CreatePasswordReset menyimpan token reset baru dan menandai token lama user yang belum terpakai
+// sebagai used, sehingga hanya link terakhir yang berlaku.
+func (r *authRepository) CreatePasswordReset(reset *models.PasswordReset) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.PasswordReset{}).
+			Where("user_id = ? AND used_at IS NULL", reset.UserID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(reset).Error
+	})
+}
+
+func (r *authRepository) GetPasswordResetByHash(tokenHash string) (*models.PasswordReset, error) {
+	var reset models.PasswordReset
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&reset).Error; err != nil {
+		return nil, err
+	}
+	return &reset, nil
+}
+
+// ResetPassword memakai token reset (sekali pakai), mengganti password dan mencabut semua refresh token user.
+func (r *authRepository) ResetPassword(resetID, userID, hashedPassword string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.PasswordReset{}).
+			Where("id = ? AND used_at IS NULL", resetID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("reset token already used")
+		}
+
+		return updatePasswordAndRevokeTokens(tx, userID, hashedPassword)
+	})
+}
+
+func (r *authRepository) UpdatePassword(userID, hashedPassword string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return updatePasswordAndRevokeTokens(tx, userID, hashedPassword)
+	})
+}
+
+func updatePasswordAndRevokeTokens(tx *gorm.DB, userID, hashedPassword string) error {
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).
+		Update("password", hashedPassword).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ?", userID).Delete(&models.Token{}).Error
+}
+
+func (r *authRepository) EnableTwoFactor(userID, secret string, codes []models.TwoFactorRecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"two_factor_enabled":    true,
+			"two_factor_secret":     secret,
+			"two_factor_enabled_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, userID, codes)
+	})
+}
+
+func (r *authRepository) DisableTwoFactor(userID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"two_factor_enabled":    false,
+			"two_factor_secret":     "",
+			"two_factor_enabled_at": nil,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.TwoFactorRecoveryCode{}).Error
+	})
+}
+
+// FindPlaintextTwoFactorSecrets mengambil secret TOTP yang tersimpan sebelum enkripsi diterapkan.
+func (r *authRepository) FindPlaintextTwoFactorSecrets() ([]models.User, error) {
+	var users []models.User
+	err := r.db.Select("id", "two_factor_secret").
+		Where("two_factor_secret <> '' AND two_factor_secret NOT LIKE ?", "enc:%").
+		Find(&users).Error
+	return users, err
+}
+
+func (r *authRepository) UpdateTwoFactorSecret(userID, secret string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userID).Update("two_factor_secret", secret).Error
+}
+
+func (r *authRepository) ReplaceRecoveryCodes(userID string, codes []models.TwoFactorRecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codes)
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID string, codes []models.TwoFactorRecoveryCode) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+		return err
+	}
+	return tx.Create(&codes).Error
+}
+
+// UseRecoveryCode menandai kode sebagai terpakai secara atomik, false jika kode tidak ada atau sudah dipakai.
+func (r *authRepository) UseRecoveryCode(userID, codeHash string) (bool, error) {
+	result := r.db.Model(&models.TwoFactorRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Limit(1).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *authRepository) GetIdentity(provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	if err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *authRepository) CreateIdentity(identity *models.UserIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+// CreateUserWithIdentity membuat user baru dari login eksternal beserta identity-nya dalam satu transaksi.
+func (r *authRepository) CreateUserWithIdentity(user *models.User, identity *models.UserIdentity) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return tx.Create(identity).Error
+	})
+}
+
+func (r *authRepository) TouchIdentity(identityID string, at time.Time) error {
+	return r.db.Model(&models.UserIdentity{}).Where("id = ?", identityID).Update("last_login_at", at).Error
+}
+
+func (r *authRepository) CreateSecurityEvent(event *models.SecurityEvent) error {
+	return r.db.Create(event).Error
+}
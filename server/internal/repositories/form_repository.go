@@ -2,8 +2,12 @@ package repositories
 
 import (
 	"server/internal/models"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type FormRepository interface {
@@ -11,15 +15,40 @@ type FormRepository interface {
 	FindAllByUserID(userID string) ([]models.Form, error)
 	FindByID(id string) (*models.Form, error)
 	GetFormSetting(formID string) (*models.FormSetting, error)
-	UpdateFormSetting(setting *models.FormSetting) error
+	UpdateFormSetting(formID string, columns map[string]any) error
 	AddSection(section *models.FormSection) error
 	GetQuestionsByFormID(formID string) ([]models.Question, error)
 	DeleteSection(sectionID string) error
 	UpdateSection(section *models.FormSection) error
 	GetSectionsByFormID(formID string) ([]models.FormSection, error)
+	GetSectionByID(sectionID string) (*models.FormSection, error)
+	GetQuestionByID(id string) (*models.Question, error)
 	AddQuestion(q *models.Question) error
 	UpdateQuestion(q *models.Question) error
 	DeleteQuestion(id string) error
+
+	GetFormStructure(formID string) (*models.Form, error)
+	PublishVersion(version *models.FormVersion) error
+	GetVersions(formID string) ([]models.FormVersion, error)
+	GetVersion(formID string, version int) (*models.FormVersion, error)
+	GetVersionByID(id string) (*models.FormVersion, error)
+
+	SetFormActive(formID string, active bool) error
+	FindFormsDueToOpen(now time.Time) ([]models.Form, error)
+	FindFormsDueToClose(now time.Time) ([]models.Form, error)
+	FindFormsDueForReminder(now time.Time) ([]models.Form, error)
+	MarkFormOpened(formID string, at time.Time) error
+	MarkFormClosed(formID string, at time.Time) error
+	MarkFormReminded(formID string, at time.Time) error
+	GetFormOwner(formID string) (*models.User, error)
+
+	AddInvitees(formID string, emails []string) (int64, error)
+	GetInvitees(formID string) ([]models.FormInvitee, error)
+	CountInviteesByOwner(userID string) (int64, error)
+	DeleteInvitee(formID string, id uint) error
+	ResetInviteeReminders(formID string) error
+	GetPendingInvitees(formID string) ([]models.FormInvitee, error)
+	MarkInviteeReminded(id uint, at time.Time) error
 }
 
 type formRepository struct {
@@ -52,10 +81,12 @@ func (r *formRepository) GetFormSetting(formID string) (*models.FormSetting, err
 	return &setting, err
 }
 
-func (r *formRepository) UpdateFormSetting(setting *models.FormSetting) error {
+// UpdateFormSetting menulis semua kolom agar toggle bernilai false ikut tersimpan
+// UpdateFormSetting hanya menulis kolom yang diberikan; map dipakai agar nilai false/0 tetap tersimpan.
+func (r *formRepository) UpdateFormSetting(formID string, columns map[string]any) error {
 	return r.db.Model(&models.FormSetting{}).
-		Where("form_id = ?", setting.FormID).
-		Updates(setting).Error
+		Where("form_id = ?", formID).
+		Updates(columns).Error
 }
 
 func (r *formRepository) AddSection(section *models.FormSection) error {
@@ -68,6 +99,12 @@ func (r *formRepository) GetSectionsByFormID(formID string) ([]models.FormSectio
 	return sections, err
 }
 
+func (r *formRepository) GetSectionByID(sectionID string) (*models.FormSection, error) {
+	var section models.FormSection
+	err := r.db.First(&section, "id = ?", sectionID).Error
+	return &section, err
+}
+
 func (r *formRepository) UpdateSection(section *models.FormSection) error {
 	return r.db.Model(&models.FormSection{}).
 		Where("id = ?", section.ID).
@@ -87,6 +124,12 @@ func (r *formRepository) GetQuestionsByFormID(formID string) ([]models.Question,
 	return questions, err
 }
 
+func (r *formRepository) GetQuestionByID(id string) (*models.Question, error) {
+	var q models.Question
+	err := r.db.Preload("Options").First(&q, "id = ?", id).Error
+	return &q, err
+}
+
 func (r *formRepository) AddQuestion(q *models.Question) error {
 	return r.db.Create(q).Error
 }
@@ -98,3 +141,175 @@ func (r *formRepository) UpdateQuestion(q *models.Question) error {
 func (r *formRepository) DeleteQuestion(id string) error {
 	return r.db.Delete(&models.Question{}, "id = ?", id).Error
 }
+
+// GetFormStructure memuat draft form lengkap (section, pertanyaan, opsi) untuk dibekukan saat publish.
+func (r *formRepository) GetFormStructure(formID string) (*models.Form, error) {
+	var form models.Form
+	err := r.db.
+		Preload("FormSection", func(db *gorm.DB) *gorm.DB { return db.Order("`order` asc") }).
+		Preload("Questions", func(db *gorm.DB) *gorm.DB { return db.Order("`order` asc") }).
+		Preload("Questions.Options", func(db *gorm.DB) *gorm.DB { return db.Order("id asc") }).
+		First(&form, "id = ?", formID).Error
+	return &form, err
+}
+
+// PublishVersion menyimpan snapshot dengan nomor versi berikutnya dan menjadikannya versi live.
+// Baris form dikunci agar dua publish bersamaan tidak mendapat nomor versi yang sama.
+func (r *formRepository) PublishVersion(version *models.FormVersion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var form models.Form
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&form, "id = ?", version.FormID).Error; err != nil {
+			return err
+		}
+
+		var latest int
+		if err := tx.Model(&models.FormVersion{}).Where("form_id = ?", version.FormID).
+			Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+		version.Version = latest + 1
+
+		if err := tx.Create(version).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Form{}).Where("id = ?", version.FormID).
+			Update("published_version_id", version.ID).Error
+	})
+}
+
+func (r *formRepository) GetVersions(formID string) ([]models.FormVersion, error) {
+	var versions []models.FormVersion
+	err := r.db.Omit("snapshot").Where("form_id = ?", formID).Order("version desc").Find(&versions).Error
+	return versions, err
+}
+
+func (r *formRepository) GetVersion(formID string, version int) (*models.FormVersion, error) {
+	var v models.FormVersion
+	err := r.db.Where("form_id = ? AND version = ?", formID, version).First(&v).Error
+	return &v, err
+}
+
+func (r *formRepository) GetVersionByID(id string) (*models.FormVersion, error) {
+	var v models.FormVersion
+	err := r.db.First(&v, "id = ?", id).Error
+	return &v, err
+}
+
+func (r *formRepository) SetFormActive(formID string, active bool) error {
+	return r.db.Model(&models.Form{}).Where("id = ?", formID).Update("is_active", active).Error
+}
+
+func (r *formRepository) FindFormsDueToOpen(now time.Time) ([]models.Form, error) {
+	var forms []models.Form
+	err := r.db.Joins("Setting").
+		Where("Setting.start_at <= ? AND Setting.opened_at IS NULL", now).
+		Where("Setting.end_at IS NULL OR Setting.end_at > ?", now).
+		Find(&forms).Error
+	return forms, err
+}
+
+func (r *formRepository) FindFormsDueToClose(now time.Time) ([]models.Form, error) {
+	var forms []models.Form
+	err := r.db.Joins("Setting").
+		Where("Setting.end_at <= ? AND Setting.closed_at IS NULL", now).
+		Find(&forms).Error
+	return forms, err
+}
+
+// FindFormsDueForReminder mencari form aktif yang sudah masuk jendela pengingat sebelum EndAt.
+func (r *formRepository) FindFormsDueForReminder(now time.Time) ([]models.Form, error) {
+	var forms []models.Form
+	err := r.db.Joins("Setting").
+		Where("forms.is_active = ? AND Setting.reminded_at IS NULL AND Setting.reminder_hours_before > 0", true).
+		Where("Setting.anonymous_responses = ?", false).
+		Where("Setting.end_at > ? AND TIMESTAMPADD(HOUR, -Setting.reminder_hours_before, Setting.end_at) <= ?", now, now).
+		Find(&forms).Error
+	return forms, err
+}
+
+func (r *formRepository) MarkFormOpened(formID string, at time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Form{}).Where("id = ?", formID).Update("is_active", true).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.FormSetting{}).Where("form_id = ?", formID).Update("opened_at", at).Error
+	})
+}
+
+func (r *formRepository) MarkFormClosed(formID string, at time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Form{}).Where("id = ?", formID).Update("is_active", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.FormSetting{}).Where("form_id = ?", formID).Update("closed_at", at).Error
+	})
+}
+
+func (r *formRepository) MarkFormReminded(formID string, at time.Time) error {
+	return r.db.Model(&models.FormSetting{}).Where("form_id = ?", formID).Update("reminded_at", at).Error
+}
+
+func (r *formRepository) GetFormOwner(formID string) (*models.User, error) {
+	var user models.User
+	err := r.db.Joins("JOIN forms ON forms.user_id = users.id").
+		Where("forms.id = ?", formID).First(&user).Error
+	return &user, err
+}
+
+// AddInvitees menambahkan email yang belum terdaftar, email duplikat diabaikan.
+func (r *formRepository) AddInvitees(formID string, emails []string) (int64, error) {
+	id, err := uuid.Parse(formID)
+	if err != nil {
+		return 0, err
+	}
+	invitees := make([]models.FormInvitee, 0, len(emails))
+	for _, email := range emails {
+		invitees = append(invitees, models.FormInvitee{FormID: id, Email: strings.ToLower(strings.TrimSpace(email))})
+	}
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&invitees)
+	return res.RowsAffected, res.Error
+}
+
+func (r *formRepository) GetInvitees(formID string) ([]models.FormInvitee, error) {
+	var invitees []models.FormInvitee
+	err := r.db.Where("form_id = ?", formID).Order("created_at asc").Find(&invitees).Error
+	return invitees, err
+}
+
+// CountInviteesByOwner menghitung seluruh invitee di semua form milik user.
+func (r *formRepository) CountInviteesByOwner(userID string) (int64, error) {
+	var total int64
+	err := r.db.Model(&models.FormInvitee{}).
+		Joins("JOIN forms ON forms.id = form_invitees.form_id").
+		Where("forms.user_id = ?", userID).
+		Count(&total).Error
+	return total, err
+}
+
+func (r *formRepository) DeleteInvitee(formID string, id uint) error {
+	res := r.db.Where("form_id = ? AND id = ?", formID, id).Delete(&models.FormInvitee{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *formRepository) ResetInviteeReminders(formID string) error {
+	return r.db.Model(&models.FormInvitee{}).Where("form_id = ?", formID).Update("reminded_at", nil).Error
+}
+
+// GetPendingInvitees mengembalikan invitee yang belum diingatkan dan belum mengisi form.
+func (r *formRepository) GetPendingInvitees(formID string) ([]models.FormInvitee, error) {
+	var invitees []models.FormInvitee
+	err := r.db.Where("form_id = ? AND reminded_at IS NULL", formID).
+		Where("NOT EXISTS (SELECT 1 FROM submissions WHERE submissions.form_id = form_invitees.form_id AND submissions.email = form_invitees.email)").
+		Find(&invitees).Error
+	return invitees, err
+}
+
+func (r *formRepository) MarkInviteeReminded(id uint, at time.Time) error {
+	return r.db.Model(&models.FormInvitee{}).Where("id = ?", id).Update("reminded_at", at).Error
+}
@@ -0,0 +1,176 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// kolom pengelompokan revenue yang diizinkan, nilainya disisipkan langsung ke SQL
+var revenueGroupColumns = map[string]string{
+	"tier":   "t.name",
+	"method": "p.method",
+}
+
+// status payment yang dihitung sebagai pendapatan, nominal refund dikurangkan dari total
+var revenueStatuses = []string{"paid", "partially_refunded", "refunded"}
+
+type TierMRRRow struct {
+	TierName      string
+	Subscriptions int64
+	MRR           float64
+}
+
+type RevenueRow struct {
+	Period   string
+	GroupKey string
+	Revenue  float64
+	Payments int64
+}
+
+type PeriodCountRow struct {
+	Period string
+	Total  int64
+}
+
+type TokenConsumptionRow struct {
+	Period   string
+	TierName string
+	Tokens   int64
+}
+
+type MetricsRepository interface {
+	GetMRRByTier(now time.Time) ([]TierMRRRow, error)
+	GetRevenue(from, to time.Time, periodFormat, groupBy string) ([]RevenueRow, error)
+	CountNewSubscriptions(from, to time.Time, periodFormat string) ([]PeriodCountRow, error)
+	CountChurnedSubscriptions(from, to time.Time, periodFormat string) ([]PeriodCountRow, error)
+	CountCanceledSubscriptions(from, to time.Time, periodFormat string) ([]PeriodCountRow, error)
+	CountRegisteredUsers(from, to time.Time) (int64, int64, error)
+	GetTokenConsumptionByTier(from, to time.Time, periodFormat string) ([]TokenConsumptionRow, error)
+}
+
+type metricsRepository struct {
+	db *gorm.DB
+}
+
+func NewMetricsRepository(db *gorm.DB) MetricsRepository {
+	return &metricsRepository{db}
+}
+
+// GetMRRByTier menormalisasi harga tier ke 30 hari untuk setiap langganan yang masih aktif.
+func (r *metricsRepository) GetMRRByTier(now time.Time) ([]TierMRRRow, error) {
+	var rows []TierMRRRow
+	err := r.db.Raw(`
+		SELECT t.name AS tier_name,
+			COUNT(us.id) AS subscriptions,
+			COALESCE(SUM(t.price * 30 / NULLIF(t.duration, 0)), 0) AS mrr
+		FROM user_subscriptions us
+		JOIN subscription_tiers t ON t.id = us.subscription_tier_id
+		WHERE us.is_active = ? AND us.expires_at > ?
+		GROUP BY t.id, t.name
+		ORDER BY mrr DESC`, true, now).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// GetRevenue mengelompokkan pendapatan bersih per periode dan per tier atau metode pembayaran.
+func (r *metricsRepository) GetRevenue(from, to time.Time, periodFormat, groupBy string) ([]RevenueRow, error) {
+	groupExpr, ok := revenueGroupColumns[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid revenue grouping %q", groupBy)
+	}
+
+	var rows []RevenueRow
+	err := r.db.Raw(`
+		SELECT DATE_FORMAT(p.paid_at, ?) AS period,
+			`+groupExpr+` AS group_key,
+			COALESCE(SUM(p.total - p.refunded), 0) AS revenue,
+			COUNT(p.id) AS payments
+		FROM payments p
+		JOIN subscription_tiers t ON t.id = p.tier_id
+		WHERE p.status IN ? AND p.paid_at BETWEEN ? AND ?
+		GROUP BY 1, 2
+		ORDER BY 1, 2`, periodFormat, revenueStatuses, from, to).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// CountNewSubscriptions menghitung pembelian langganan pertama yang lunas.
+func (r *metricsRepository) CountNewSubscriptions(from, to time.Time, periodFormat string) ([]PeriodCountRow, error) {
+	var rows []PeriodCountRow
+	err := r.db.Raw(`
+		SELECT DATE_FORMAT(paid_at, ?) AS period, COUNT(*) AS total
+		FROM payments
+		WHERE type = ? AND status IN ? AND paid_at BETWEEN ? AND ?
+		GROUP BY 1
+		ORDER BY 1`, periodFormat, "new", revenueStatuses, from, to).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// CountChurnedSubscriptions menghitung langganan yang berakhir tanpa diperpanjang. Perpanjangan
+// menggeser expires_at ke depan, jadi langganan yang expires_at-nya sudah lewat dianggap churn.
+func (r *metricsRepository) CountChurnedSubscriptions(from, to time.Time, periodFormat string) ([]PeriodCountRow, error) {
+	now := time.Now()
+	if to.After(now) {
+		to = now
+	}
+
+	var rows []PeriodCountRow
+	err := r.db.Raw(`
+		SELECT DATE_FORMAT(expires_at, ?) AS period, COUNT(*) AS total
+		FROM user_subscriptions
+		WHERE expires_at BETWEEN ? AND ?
+		GROUP BY 1
+		ORDER BY 1`, periodFormat, from, to).
+		Scan(&rows).Error
+	return rows, err
+}
+
+func (r *metricsRepository) CountCanceledSubscriptions(from, to time.Time, periodFormat string) ([]PeriodCountRow, error) {
+	var rows []PeriodCountRow
+	err := r.db.Raw(`
+		SELECT DATE_FORMAT(canceled_at, ?) AS period, COUNT(*) AS total
+		FROM user_subscriptions
+		WHERE canceled_at BETWEEN ? AND ?
+		GROUP BY 1
+		ORDER BY 1`, periodFormat, from, to).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// CountRegisteredUsers mengembalikan jumlah user yang mendaftar pada rentang waktu
+// dan berapa di antaranya yang pernah membayar.
+func (r *metricsRepository) CountRegisteredUsers(from, to time.Time) (int64, int64, error) {
+	var result struct {
+		Registered int64
+		Paying     int64
+	}
+	err := r.db.Raw(`
+		SELECT COUNT(*) AS registered,
+			COUNT(CASE WHEN EXISTS (
+				SELECT 1 FROM payments p WHERE p.user_id = u.id AND p.status IN ?
+			) THEN 1 END) AS paying
+		FROM users u
+		WHERE u.role = ? AND u.created_at BETWEEN ? AND ?`, revenueStatuses, "user", from, to).
+		Scan(&result).Error
+	return result.Registered, result.Paying, err
+}
+
+// GetTokenConsumptionByTier menjumlahkan token bersih (charge dikurangi refund) per tier langganan user saat ini.
+func (r *metricsRepository) GetTokenConsumptionByTier(from, to time.Time, periodFormat string) ([]TokenConsumptionRow, error) {
+	var rows []TokenConsumptionRow
+	err := r.db.Raw(`
+		SELECT DATE_FORMAT(tu.created_at, ?) AS period,
+			COALESCE(t.name, 'none') AS tier_name,
+			COALESCE(SUM(tu.tokens), 0) AS tokens
+		FROM token_usages tu
+		LEFT JOIN user_subscriptions us ON us.user_id = tu.user_id
+		LEFT JOIN subscription_tiers t ON t.id = us.subscription_tier_id
+		WHERE tu.created_at BETWEEN ? AND ?
+		GROUP BY 1, 2
+		ORDER BY 1, 2`, periodFormat, from, to).
+		Scan(&rows).Error
+	return rows, err
+}
@@ -1,20 +1,45 @@
 package repositories
 
 import (
+	"errors"
+	"fmt"
 	"server/internal/models"
 	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrVoucherLimitReached     = errors.New("voucher redemption limit reached")
+	ErrVoucherUserLimitReached = errors.New("voucher already used the maximum number of times")
+	ErrPaymentNotPending       = errors.New("payment is no longer pending")
 )
 
 type PaymentRepository interface {
-	ExpireOldPendingPayments() (int64, error)
+	FindStalePendingPayments(createdBefore time.Time, limit int) ([]models.Payment, error)
+	ExpirePendingPayments(ids []string) (int64, error)
+	MarkPaymentsChecked(ids []string, at time.Time) error
 	CreatePayment(payment *models.Payment) error
+	CreatePaymentWithVoucher(payment *models.Payment, redemption *models.VoucherRedemption) error
 	UpdatePayment(payment *models.Payment) error
+	SettlePayment(payment *models.Payment, subscription *models.UserSubscription, invoice *models.Invoice) error
+	FailPayment(payment *models.Payment) error
 	GetPaymentByID(id string) (*models.Payment, error)
 	GetPaymentByOrderID(orderID string) (*models.Payment, error)
 	CreateUserSubscription(subscription *models.UserSubscription) error
 	GetAllUserPayments(query string, limit, offset int) ([]models.Payment, int64, error)
+	FindPaymentsToReconcile(pendingBefore, paidSince time.Time, limit int) ([]models.Payment, error)
+	HasOpenMismatch(paymentID string) (bool, error)
+	CreatePaymentMismatch(mismatch *models.PaymentMismatch) error
+	GetPaymentMismatches(resolved *bool, limit, offset int) ([]models.PaymentMismatch, int64, error)
+	ResolvePaymentMismatch(id uint, note string) error
+	CountRefunds(paymentID string) (int64, error)
+	FindPendingRefund(paymentID string) (*models.Refund, error)
+	CreateRefund(refund *models.Refund) error
+	DeleteRefund(id uint) error
+	RecordRefund(payment *models.Payment, refund *models.Refund, subscription *models.UserSubscription) error
 }
 
 type paymentRepository struct {
@@ -29,9 +54,87 @@ func (r *paymentRepository) CreatePayment(payment *models.Payment) error {
 	return r.db.Create(payment).Error
 }
 
+// CreatePaymentWithVoucher menyimpan payment sekaligus mencatat pemakaian voucher dalam satu transaksi.
+// Baris voucher dikunci (SELECT ... FOR UPDATE) agar batas pemakaian global dan per user tidak terlewati
+// ketika ada beberapa checkout bersamaan.
+func (r *paymentRepository) CreatePaymentWithVoucher(payment *models.Payment, redemption *models.VoucherRedemption) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var voucher models.Voucher
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&voucher, redemption.VoucherID).Error; err != nil {
+			return err
+		}
+
+		if voucher.MaxRedemptions != nil && voucher.RedeemedCount >= *voucher.MaxRedemptions {
+			return ErrVoucherLimitReached
+		}
+
+		if voucher.MaxRedemptionsPerUser != nil {
+			var used int64
+			if err := tx.Model(&models.VoucherRedemption{}).
+				Where("voucher_id = ? AND user_id = ?", voucher.ID, redemption.UserID).
+				Count(&used).Error; err != nil {
+				return err
+			}
+			if used >= int64(*voucher.MaxRedemptionsPerUser) {
+				return ErrVoucherUserLimitReached
+			}
+		}
+
+		if err := tx.Create(payment).Error; err != nil {
+			return err
+		}
+
+		redemption.PaymentID = payment.ID
+		if err := tx.Create(redemption).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Voucher{}).
+			Where("id = ?", voucher.ID).
+			Update("redeemed_count", gorm.Expr("redeemed_count + 1")).Error
+	})
+}
+
+// releaseVoucherRedemption mengembalikan kuota voucher yang dipakai payment yang gagal.
+func releaseVoucherRedemption(tx *gorm.DB, paymentID string) error {
+	var redemption models.VoucherRedemption
+	err := tx.Where("payment_id = ?", paymentID).First(&redemption).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Delete(&redemption).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.Voucher{}).
+		Where("id = ? AND redeemed_count > 0", redemption.VoucherID).
+		Update("redeemed_count", gorm.Expr("redeemed_count - 1")).Error
+}
+
+// lockPendingPayment mengunci baris payment (SELECT ... FOR UPDATE) dan memastikan statusnya masih pending.
+// Webhook, rekonsiliasi dan job expire bisa memproses payment yang sama bersamaan; hanya yang pertama
+// mendapat kunci yang mengubah status, sisanya mendapat ErrPaymentNotPending.
+func lockPendingPayment(tx *gorm.DB, paymentID uuid.UUID) error {
+	var current models.Payment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "status").
+		First(&current, "id = ?", paymentID).Error; err != nil {
+		return err
+	}
+	if current.Status != "pending" {
+		return ErrPaymentNotPending
+	}
+	return nil
+}
+
 func (r *paymentRepository) GetPaymentByID(id string) (*models.Payment, error) {
 	var payment models.Payment
-	if err := r.db.First(&payment, "id = ?", id).Error; err != nil {
+	if err := r.db.Preload("Voucher").Preload("Refunds").First(&payment, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 	return &payment, nil
@@ -49,6 +152,61 @@ func (r *paymentRepository) UpdatePayment(payment *models.Payment) error {
 	return r.db.Save(payment).Error
 }
 
+// SettlePayment menyimpan payment yang lunas, langganan user yang sudah diterapkan paketnya dan invoice-nya
+// dalam satu transaksi. Payment yang sudah tidak pending ditolak dengan ErrPaymentNotPending sehingga paket
+// tidak diterapkan dua kali. Counter tahunan dikunci dengan SELECT ... FOR UPDATE sehingga nomor invoice
+// berurutan tanpa celah: nomor hanya terpakai bila invoice ikut tersimpan.
+func (r *paymentRepository) SettlePayment(payment *models.Payment, subscription *models.UserSubscription, invoice *models.Invoice) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockPendingPayment(tx, payment.ID); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Save(payment).Error; err != nil {
+			return err
+		}
+
+		if subscription.ID == 0 {
+			if err := tx.Omit(clause.Associations).Create(subscription).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Omit(clause.Associations).Save(subscription).Error; err != nil {
+			return err
+		}
+
+		year := invoice.IssuedAt.Year()
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.InvoiceSequence{Year: year}).Error; err != nil {
+			return err
+		}
+
+		var seq models.InvoiceSequence
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&seq, "year = ?", year).Error; err != nil {
+			return err
+		}
+		seq.LastNumber++
+		if err := tx.Model(&seq).Update("last_number", seq.LastNumber).Error; err != nil {
+			return err
+		}
+
+		invoice.Number = fmt.Sprintf("INV-%d-%06d", year, seq.LastNumber)
+		return tx.Create(invoice).Error
+	})
+}
+
+// FailPayment menggagalkan payment yang masih pending dan mengembalikan kuota voucher-nya dalam satu
+// transaksi. Status failed bersifat final, pelunasan yang datang terlambat tidak diterapkan lagi.
+func (r *paymentRepository) FailPayment(payment *models.Payment) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockPendingPayment(tx, payment.ID); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Save(payment).Error; err != nil {
+			return err
+		}
+		return releaseVoucherRedemption(tx, payment.ID.String())
+	})
+}
+
 func (r *paymentRepository) GetAllUserPayments(query string, limit, offset int) ([]models.Payment, int64, error) {
 	var payments []models.Payment
 	var count int64
@@ -73,16 +231,177 @@ func (r *paymentRepository) GetAllUserPayments(query string, limit, offset int)
 	return payments, count, nil
 }
 
-func (r *paymentRepository) ExpireOldPendingPayments() (int64, error) {
-	threshold := time.Now().Add(-24 * time.Hour)
+// FindStalePendingPayments mengambil payment pending yang dibuat sebelum createdBefore, paling lama dulu.
+func (r *paymentRepository) FindStalePendingPayments(createdBefore time.Time, limit int) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := r.db.Where("status = ? AND created_at <= ?", "pending", createdBefore).
+		Order("checked_at ASC, created_at ASC").
+		Limit(limit).
+		Find(&payments).Error
+	return payments, err
+}
+
+// ExpirePendingPayments menggagalkan payment yang masih pending dan mengembalikan kuota voucher
+// yang dipakai payment tersebut. Payment yang statusnya sudah berubah dilewati.
+func (r *paymentRepository) ExpirePendingPayments(ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
 
-	result := r.db.Model(&models.Payment{}).
-		Where("status = ? AND paid_at <= ?", "pending", threshold).
-		Update("status", "failed")
+	var expired int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Payment{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ? AND status = ?", ids, "pending").
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
 
-	return result.RowsAffected, result.Error
+		result := tx.Model(&models.Payment{}).
+			Where("id IN ? AND status = ?", ids, "pending").
+			Update("status", "failed")
+		if result.Error != nil {
+			return result.Error
+		}
+		expired = result.RowsAffected
+
+		var redemptions []models.VoucherRedemption
+		if err := tx.Where("payment_id IN ?", ids).Find(&redemptions).Error; err != nil {
+			return err
+		}
+		for _, rd := range redemptions {
+			if err := tx.Model(&models.Voucher{}).
+				Where("id = ? AND redeemed_count > 0", rd.VoucherID).
+				Update("redeemed_count", gorm.Expr("redeemed_count - 1")).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("payment_id IN ?", ids).Delete(&models.VoucherRedemption{}).Error
+	})
+	return expired, err
 }
 
 func (r *paymentRepository) CreateUserSubscription(subscription *models.UserSubscription) error {
 	return r.db.Create(subscription).Error
 }
+
+// FindPaymentsToReconcile mengambil payment pending yang sudah cukup lama (webhook kemungkinan hilang)
+// dan payment yang baru lunas untuk dicocokkan ulang dengan status di gateway. Payment yang paling lama
+// tidak dicek didahulukan agar payment lama yang tertahan tidak terus menutupi payment baru.
+func (r *paymentRepository) FindPaymentsToReconcile(pendingBefore, paidSince time.Time, limit int) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := r.db.
+		Where("(status = ? AND created_at <= ?) OR (status = ? AND paid_at >= ?)", "pending", pendingBefore, "paid", paidSince).
+		Order("checked_at ASC, created_at ASC").
+		Limit(limit).
+		Find(&payments).Error
+	return payments, err
+}
+
+func (r *paymentRepository) MarkPaymentsChecked(ids []string, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&models.Payment{}).Where("id IN ?", ids).Update("checked_at", at).Error
+}
+
+func (r *paymentRepository) HasOpenMismatch(paymentID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.PaymentMismatch{}).
+		Where("payment_id = ? AND resolved = ?", paymentID, false).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *paymentRepository) CreatePaymentMismatch(mismatch *models.PaymentMismatch) error {
+	return r.db.Create(mismatch).Error
+}
+
+func (r *paymentRepository) GetPaymentMismatches(resolved *bool, limit, offset int) ([]models.PaymentMismatch, int64, error) {
+	var mismatches []models.PaymentMismatch
+	var count int64
+
+	db := r.db.Model(&models.PaymentMismatch{})
+	if resolved != nil {
+		db = db.Where("resolved = ?", *resolved)
+	}
+
+	if err := db.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := db.Preload("Payment.User").Order("created_at DESC").
+		Limit(limit).Offset(offset).Find(&mismatches).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return mismatches, count, nil
+}
+
+func (r *paymentRepository) ResolvePaymentMismatch(id uint, note string) error {
+	result := r.db.Model(&models.PaymentMismatch{}).
+		Where("id = ? AND resolved = ?", id, false).
+		Updates(map[string]interface{}{
+			"resolved":    true,
+			"resolved_at": time.Now(),
+			"note":        note,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *paymentRepository) CountRefunds(paymentID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Refund{}).Where("payment_id = ?", paymentID).Count(&count).Error
+	return count, err
+}
+
+// FindPendingRefund mengambil refund yang sudah dicatat tapi belum selesai disimpan, nil bila tidak ada.
+func (r *paymentRepository) FindPendingRefund(paymentID string) (*models.Refund, error) {
+	var refund models.Refund
+	err := r.db.Where("payment_id = ? AND status = ?", paymentID, "pending").First(&refund).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+func (r *paymentRepository) CreateRefund(refund *models.Refund) error {
+	return r.db.Create(refund).Error
+}
+
+// DeleteRefund menghapus refund pending yang ditolak gateway.
+func (r *paymentRepository) DeleteRefund(id uint) error {
+	return r.db.Where("id = ? AND status = ?", id, "pending").Delete(&models.Refund{}).Error
+}
+
+// RecordRefund menyelesaikan refund yang sudah diproses gateway: status payment, status refund
+// dan penyesuaian langganan (jika ada) disimpan dalam satu transaksi.
+func (r *paymentRepository) RecordRefund(payment *models.Payment, refund *models.Refund, subscription *models.UserSubscription) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(payment).Error; err != nil {
+			return err
+		}
+		refund.Status = "completed"
+		if err := tx.Model(refund).Updates(map[string]interface{}{
+			"status":              refund.Status,
+			"subscription_action": refund.SubscriptionAction,
+		}).Error; err != nil {
+			return err
+		}
+		if subscription != nil {
+			return tx.Omit(clause.Associations).Save(subscription).Error
+		}
+		return nil
+	})
+}
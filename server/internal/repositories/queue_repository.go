@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"server/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type QueueRepository interface {
+	FindByOwner(userID, status string) ([]models.Queue, error)
+	FindByResponseID(userID, responseID string) (*models.Queue, error)
+	UpdateStatus(id, from, to string) (bool, error)
+}
+
+type queueRepository struct {
+	db *gorm.DB
+}
+
+func NewQueueRepository(db *gorm.DB) QueueRepository {
+	return &queueRepository{db}
+}
+
+// antrian hanya terlihat oleh pemilik form dari submission yang diantrikan
+func (r *queueRepository) ownedBy(userID string) *gorm.DB {
+	return r.db.Model(&models.Queue{}).
+		Joins("JOIN submissions ON submissions.id = queues.response_id").
+		Joins("JOIN forms ON forms.id = submissions.form_id").
+		Where("forms.user_id = ?", userID)
+}
+
+func (r *queueRepository) FindByOwner(userID, status string) ([]models.Queue, error) {
+	var queues []models.Queue
+	q := r.ownedBy(userID)
+	if status != "" {
+		q = q.Where("queues.status = ?", status)
+	}
+	err := q.Select("queues.*").Order("queues.queu_number asc").Find(&queues).Error
+	return queues, err
+}
+
+func (r *queueRepository) FindByResponseID(userID, responseID string) (*models.Queue, error) {
+	var queue models.Queue
+	err := r.ownedBy(userID).Select("queues.*").Where("queues.response_id = ?", responseID).First(&queue).Error
+	return &queue, err
+}
+
+// UpdateStatus memindahkan status hanya bila status saat ini masih from, sehingga dua operator
+// tidak bisa memproses antrian yang sama.
+func (r *queueRepository) UpdateStatus(id, from, to string) (bool, error) {
+	result := r.db.Model(&models.Queue{}).Where("id = ? AND status = ?", id, from).Update("status", to)
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"errors"
+	"server/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type QuotaRepository interface {
+	ChargeTokens(usage *models.TokenUsage) (bool, error)
+	RefundTokens(usageID uint) error
+	FindUserSubscription(userID string) (*models.UserSubscription, error)
+	FindFormOwnerID(formID string) (string, error)
+}
+
+type quotaRepository struct {
+	db *gorm.DB
+}
+
+func NewQuotaRepository(db *gorm.DB) QuotaRepository {
+	return &quotaRepository{db}
+}
+
+// ChargeTokens mengurangi saldo token dan mencatat ledger dalam satu transaksi.
+// Saldo hanya berkurang bila langganan aktif, belum kedaluwarsa dan saldonya cukup;
+// selain itu mengembalikan false tanpa mengubah data.
+func (r *quotaRepository) ChargeTokens(usage *models.TokenUsage) (bool, error) {
+	charged := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.UserSubscription{}).
+			Where("user_id = ? AND is_active = ? AND expires_at > ? AND remaining_tokens >= ?",
+				usage.UserID, true, time.Now(), usage.Tokens).
+			Update("remaining_tokens", gorm.Expr("remaining_tokens - ?", usage.Tokens))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		var sub models.UserSubscription
+		if err := tx.Select("remaining_tokens").Where("user_id = ?", usage.UserID).First(&sub).Error; err != nil {
+			return err
+		}
+		usage.BalanceAfter = sub.RemainingTokens
+
+		if err := tx.Create(usage).Error; err != nil {
+			return err
+		}
+		charged = true
+		return nil
+	})
+	return charged, err
+}
+
+// RefundTokens mengembalikan token dari sebuah charge. Unique index pada RefundOfID
+// memastikan satu charge hanya bisa di-refund sekali.
+func (r *quotaRepository) RefundTokens(usageID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var charge models.TokenUsage
+		if err := tx.First(&charge, usageID).Error; err != nil {
+			return err
+		}
+		if charge.Tokens <= 0 {
+			return errors.New("token usage is not a charge")
+		}
+
+		if err := tx.Model(&models.UserSubscription{}).
+			Where("user_id = ?", charge.UserID).
+			Update("remaining_tokens", gorm.Expr("remaining_tokens + ?", charge.Tokens)).Error; err != nil {
+			return err
+		}
+
+		var sub models.UserSubscription
+		if err := tx.Select("remaining_tokens").Where("user_id = ?", charge.UserID).First(&sub).Error; err != nil {
+			return err
+		}
+
+		refund := &models.TokenUsage{
+			UserID:       charge.UserID,
+			Action:       charge.Action,
+			Tokens:       -charge.Tokens,
+			BalanceAfter: sub.RemainingTokens,
+			ReferenceID:  charge.ReferenceID,
+			RefundOfID:   &charge.ID,
+		}
+		return tx.Create(refund).Error
+	})
+}
+
+func (r *quotaRepository) FindUserSubscription(userID string) (*models.UserSubscription, error) {
+	var sub models.UserSubscription
+	if err := r.db.Where("user_id = ?", userID).First(&sub).Error; err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (r *quotaRepository) FindFormOwnerID(formID string) (string, error) {
+	var form models.Form
+	if err := r.db.Select("user_id").Where("id = ?", formID).First(&form).Error; err != nil {
+		return "", err
+	}
+	return form.UserID.String(), nil
+}
@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"server/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RBACRepository interface {
+	GetUserAccess(userID string) (*models.User, error)
+	UpdateUserRole(userID, role string) error
+	CountUsersByRole(role string) (int64, error)
+	RoleExists(role string) (bool, error)
+	GetRolePermissions(role string) ([]string, error)
+	GetAllRolePermissions() ([]models.RolePermission, error)
+	SeedRolePermissions(permissions []models.RolePermission) error
+	BackfillEmptyRoles(role string) (int64, error)
+}
+
+type rbacRepository struct {
+	db *gorm.DB
+}
+
+func NewRBACRepository(db *gorm.DB) RBACRepository {
+	return &rbacRepository{db}
+}
+
+// GetUserAccess hanya memuat kolom yang dibutuhkan untuk otorisasi (role & status 2FA).
+func (r *rbacRepository) GetUserAccess(userID string) (*models.User, error) {
+	var user models.User
+	if err := r.db.Select("id", "role", "two_factor_enabled").First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *rbacRepository) UpdateUserRole(userID, role string) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", userID).Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *rbacRepository) CountUsersByRole(role string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Where("role = ?", role).Count(&count).Error
+	return count, err
+}
+
+func (r *rbacRepository) RoleExists(role string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.RolePermission{}).Where("role = ?", role).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *rbacRepository) GetRolePermissions(role string) ([]string, error) {
+	var permissions []string
+	err := r.db.Model(&models.RolePermission{}).Where("role = ?", role).Pluck("permission", &permissions).Error
+	return permissions, err
+}
+
+func (r *rbacRepository) GetAllRolePermissions() ([]models.RolePermission, error) {
+	var permissions []models.RolePermission
+	err := r.db.Order("role, permission").Find(&permissions).Error
+	return permissions, err
+}
+
+// SeedRolePermissions hanya menambahkan baris yang belum ada, perubahan manual di database tetap dipertahankan.
+func (r *rbacRepository) SeedRolePermissions(permissions []models.RolePermission) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&permissions).Error
+}
+
+// BackfillEmptyRoles mengisi role user lama yang dibuat sebelum kolom role dipakai.
+func (r *rbacRepository) BackfillEmptyRoles(role string) (int64, error) {
+	result := r.db.Model(&models.User{}).
+		Where("role = ? OR role IS NULL", "").
+		Update("role", role)
+	return result.RowsAffected, result.Error
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"server/internal/models"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -10,6 +11,12 @@ type SubmissionRepository interface {
 	Create(sub *models.Submission, answers []models.Answer) error
 	GetByFormID(formID string) ([]models.Submission, error)
 	GetWithAnswers(subID string) (*models.Submission, error)
+	GetByFormIDWithAnswers(formID string) ([]models.Submission, error)
+	GetRetentionSettings() ([]models.FormSetting, error)
+	AnonymizeSubmissionsBefore(formID string, cutoff time.Time) (int64, error)
+	DeleteSubmissionsBefore(formID string, cutoff time.Time) (int64, error)
+	CreateRetentionLog(entry *models.RetentionLog) error
+	GetRetentionLogs(formID string) ([]models.RetentionLog, error)
 }
 
 type submissionRepository struct {
@@ -43,3 +50,64 @@ func (r *submissionRepository) GetWithAnswers(subID string) (*models.Submission,
 	err := r.db.Preload("Answers").First(&sub, "id = ?", subID).Error
 	return &sub, err
 }
+
+func (r *submissionRepository) GetByFormIDWithAnswers(formID string) ([]models.Submission, error) {
+	var subs []models.Submission
+	err := r.db.Preload("Answers").Where("form_id = ?", formID).Order("submitted_at asc").Find(&subs).Error
+	return subs, err
+}
+
+func (r *submissionRepository) GetRetentionSettings() ([]models.FormSetting, error) {
+	var settings []models.FormSetting
+	err := r.db.Where("retention_days > 0").Find(&settings).Error
+	return settings, err
+}
+
+// AnonymizeSubmissionsBefore menghapus data identitas responden, jawaban tetap disimpan untuk analitik.
+func (r *submissionRepository) AnonymizeSubmissionsBefore(formID string, cutoff time.Time) (int64, error) {
+	res := r.db.Model(&models.Submission{}).
+		Where("form_id = ? AND submitted_at < ? AND anonymized_at IS NULL", formID, cutoff).
+		Updates(map[string]any{
+			"email":         "",
+			"ip_address":    nil,
+			"user_agent":    nil,
+			"session_token": nil,
+			"anonymized_at": time.Now(),
+		})
+	return res.RowsAffected, res.Error
+}
+
+func (r *submissionRepository) DeleteSubmissionsBefore(formID string, cutoff time.Time) (int64, error) {
+	var affected int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var ids []string
+		if err := tx.Model(&models.Submission{}).
+			Where("form_id = ? AND submitted_at < ?", formID, cutoff).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := tx.Where("submission_id IN ?", ids).Delete(&models.Answer{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("response_id IN ?", ids).Delete(&models.Queue{}).Error; err != nil {
+			return err
+		}
+		res := tx.Where("id IN ?", ids).Delete(&models.Submission{})
+		affected = res.RowsAffected
+		return res.Error
+	})
+	return affected, err
+}
+
+func (r *submissionRepository) CreateRetentionLog(entry *models.RetentionLog) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *submissionRepository) GetRetentionLogs(formID string) ([]models.RetentionLog, error) {
+	var logs []models.RetentionLog
+	err := r.db.Where("form_id = ?", formID).Order("created_at desc").Limit(100).Find(&logs).Error
+	return logs, err
+}
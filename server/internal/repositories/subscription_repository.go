@@ -3,8 +3,10 @@ package repositories
 
 import (
 	"server/internal/models"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type SubscriptionRepository interface {
@@ -13,8 +15,20 @@ type SubscriptionRepository interface {
 	GetTierByID(id uint) (*models.SubscriptionTier, error)
 	DeleteTier(id uint) error
 	ResetAllUserToken() error
+	ResetMonthlyTokens(now time.Time) (int64, error)
+	DeactivateExpiredSubscriptions(now time.Time) (int64, error)
 	FindAllUserSubscriptions() ([]models.UserSubscription, error)
 	FindUserSubscriptionByID(userID string) (*models.UserSubscription, error)
+	UpdateUserSubscription(sub *models.UserSubscription) error
+	FindDueScheduledChanges(now time.Time) ([]models.UserSubscription, error)
+
+	CreateVoucher(voucher *models.Voucher, tierIDs []uint) error
+	UpdateVoucher(voucher *models.Voucher, tierIDs []uint) error
+	DeleteVoucher(id uint) error
+	GetVoucherByID(id uint) (*models.Voucher, error)
+	GetVoucherByCode(code string) (*models.Voucher, error)
+	FindAllVouchers() ([]models.Voucher, error)
+	CountUserRedemptions(voucherID uint, userID string) (int64, error)
 }
 
 type subscriptionRepository struct {
@@ -37,10 +51,38 @@ func (r *subscriptionRepository) DeleteTier(id uint) error {
 	return r.db.Delete(&models.SubscriptionTier{}, id).Error
 }
 
+// tierTokenLimit mengambil token_limit dari tier milik subscription (kolom tersebut ada di tabel tier)
+var tierTokenLimit = gorm.Expr("(SELECT token_limit FROM subscription_tiers WHERE subscription_tiers.id = user_subscriptions.subscription_tier_id)")
+
 func (r *subscriptionRepository) ResetAllUserToken() error {
 	return r.db.Model(&models.UserSubscription{}).
 		Where("is_active = ?", true).
-		Update("remaining_tokens", gorm.Expr("token_limit")).Error
+		Updates(map[string]interface{}{
+			"remaining_tokens": tierTokenLimit,
+			"tokens_reset_at":  time.Now(),
+		}).Error
+}
+
+// ResetMonthlyTokens mengisi ulang saldo token setiap subscription aktif yang reset terakhirnya
+// (atau tanggal mulai, bila belum pernah direset) sudah lewat satu bulan.
+func (r *subscriptionRepository) ResetMonthlyTokens(now time.Time) (int64, error) {
+	result := r.db.Model(&models.UserSubscription{}).
+		Where("is_active = ? AND expires_at > ? AND COALESCE(tokens_reset_at, started_at) <= ?",
+			true, now, now.AddDate(0, -1, 0)).
+		Updates(map[string]interface{}{
+			"remaining_tokens": tierTokenLimit,
+			"tokens_reset_at":  now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// DeactivateExpiredSubscriptions menonaktifkan subscription yang sudah lewat masa berlakunya.
+// Subscription dengan downgrade terjadwal dilewati karena akan dilanjutkan oleh ApplyScheduledTierChanges.
+func (r *subscriptionRepository) DeactivateExpiredSubscriptions(now time.Time) (int64, error) {
+	result := r.db.Model(&models.UserSubscription{}).
+		Where("is_active = ? AND expires_at <= ? AND scheduled_tier_id IS NULL", true, now).
+		Update("is_active", false)
+	return result.RowsAffected, result.Error
 }
 
 func (r *subscriptionRepository) FindAllUserSubscriptions() ([]models.UserSubscription, error) {
@@ -51,7 +93,7 @@ func (r *subscriptionRepository) FindAllUserSubscriptions() ([]models.UserSubscr
 
 func (r *subscriptionRepository) FindUserSubscriptionByID(userID string) (*models.UserSubscription, error) {
 	var sub models.UserSubscription
-	err := r.db.Preload("User").Preload("SubscriptionTier").
+	err := r.db.Preload("User").Preload("SubscriptionTier").Preload("ScheduledTier").
 		Where("user_id = ?", userID).
 		First(&sub).Error
 	if err != nil {
@@ -60,6 +102,19 @@ func (r *subscriptionRepository) FindUserSubscriptionByID(userID string) (*model
 	return &sub, nil
 }
 
+func (r *subscriptionRepository) UpdateUserSubscription(sub *models.UserSubscription) error {
+	return r.db.Omit(clause.Associations).Save(sub).Error
+}
+
+// FindDueScheduledChanges mengambil subscription dengan downgrade terjadwal yang periodenya sudah berakhir.
+func (r *subscriptionRepository) FindDueScheduledChanges(now time.Time) ([]models.UserSubscription, error) {
+	var subs []models.UserSubscription
+	err := r.db.Preload("ScheduledTier").
+		Where("scheduled_tier_id IS NOT NULL AND expires_at <= ?", now).
+		Find(&subs).Error
+	return subs, err
+}
+
 func (r *subscriptionRepository) GetTierByID(id uint) (*models.SubscriptionTier, error) {
 	var tier models.SubscriptionTier
 	if err := r.db.First(&tier, id).Error; err != nil {
@@ -67,3 +122,77 @@ func (r *subscriptionRepository) GetTierByID(id uint) (*models.SubscriptionTier,
 	}
 	return &tier, nil
 }
+
+func (r *subscriptionRepository) CreateVoucher(voucher *models.Voucher, tierIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(voucher).Error; err != nil {
+			return err
+		}
+		return replaceVoucherTiers(tx, voucher, tierIDs)
+	})
+}
+
+func (r *subscriptionRepository) UpdateVoucher(voucher *models.Voucher, tierIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Select("*") agar field bernilai nol/nil (mis. IsActive=false, MaxRedemptions=nil) ikut tersimpan
+		err := tx.Model(&models.Voucher{}).
+			Where("id = ?", voucher.ID).
+			Select("code", "description", "discount_type", "discount_value", "max_discount",
+				"starts_at", "expires_at", "max_redemptions", "max_redemptions_per_user", "is_active").
+			Updates(voucher).Error
+		if err != nil {
+			return err
+		}
+		return replaceVoucherTiers(tx, voucher, tierIDs)
+	})
+}
+
+func replaceVoucherTiers(tx *gorm.DB, voucher *models.Voucher, tierIDs []uint) error {
+	var tiers []models.SubscriptionTier
+	if len(tierIDs) > 0 {
+		if err := tx.Where("id IN ?", tierIDs).Find(&tiers).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(voucher).Association("Tiers").Replace(tiers)
+}
+
+func (r *subscriptionRepository) DeleteVoucher(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		voucher := &models.Voucher{ID: id}
+		if err := tx.Model(voucher).Association("Tiers").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(voucher).Error
+	})
+}
+
+func (r *subscriptionRepository) GetVoucherByID(id uint) (*models.Voucher, error) {
+	var voucher models.Voucher
+	if err := r.db.Preload("Tiers").First(&voucher, id).Error; err != nil {
+		return nil, err
+	}
+	return &voucher, nil
+}
+
+func (r *subscriptionRepository) GetVoucherByCode(code string) (*models.Voucher, error) {
+	var voucher models.Voucher
+	if err := r.db.Preload("Tiers").Where("code = ?", code).First(&voucher).Error; err != nil {
+		return nil, err
+	}
+	return &voucher, nil
+}
+
+func (r *subscriptionRepository) FindAllVouchers() ([]models.Voucher, error) {
+	var vouchers []models.Voucher
+	err := r.db.Preload("Tiers").Order("created_at desc").Find(&vouchers).Error
+	return vouchers, err
+}
+
+func (r *subscriptionRepository) CountUserRedemptions(voucherID uint, userID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.VoucherRedemption{}).
+		Where("voucher_id = ? AND user_id = ?", voucherID, userID).
+		Count(&count).Error
+	return count, err
+}
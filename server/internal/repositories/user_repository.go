@@ -1,7 +1,11 @@
 package repositories
 
 import (
+	"errors"
+	"fmt"
 	"server/internal/models"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -10,10 +14,27 @@ type UserRepository interface {
 	GetByID(userID string) (*models.User, error)
 	Update(user *models.User) error
 	GetUserSubscription(userID string) (*models.UserSubscription, error)
-	UpdateUserTokenUsage(userID string, used int) error
+	SetSubscriptionAutoRenew(userID string, autoRenew bool, canceledAt *time.Time) error
+	GetTokenUsages(userID string, limit, offset int) ([]models.TokenUsage, int64, error)
 	GetPayments(userID string) ([]models.Payment, error)
+	GetInvoices(userID string) ([]models.Invoice, error)
+	GetInvoiceByID(userID, invoiceID string) (*models.Invoice, error)
 	GetFormsByUser(userID string) ([]models.Form, error)
 	GetFormDetail(formID string) (*models.Form, error)
+	GetExportData(userID string) (*AccountExportData, error)
+	ScheduleDeletion(userID string, at *time.Time) error
+	FindUsersDueForDeletion(now time.Time, limit int) ([]models.User, error)
+	PurgeUser(userID string) ([]string, error)
+}
+
+// AccountExportData adalah semua data milik user untuk export data pribadi.
+type AccountExportData struct {
+	User         models.User
+	Subscription *models.UserSubscription
+	Forms        []models.Form
+	Versions     []models.FormVersion
+	Submissions  []models.Submission
+	Payments     []models.Payment
 }
 
 type userRepository struct {
@@ -36,25 +57,63 @@ func (r *userRepository) Update(user *models.User) error {
 
 func (r *userRepository) GetUserSubscription(userID string) (*models.UserSubscription, error) {
 	var sub models.UserSubscription
-	err := r.db.Preload("SubscriptionTier").First(&sub, "user_id = ?", userID).Error
+	err := r.db.Preload("User").Preload("SubscriptionTier").Preload("ScheduledTier").
+		First(&sub, "user_id = ?", userID).Error
 	return &sub, err
 }
 
-func (r *userRepository) UpdateUserTokenUsage(userID string, used int) error {
-	return r.db.Model(&models.UserSubscription{}).
-		Where("user_id = ?", userID).
-		Update("remaining_tokens", gorm.Expr("remaining_tokens - ?", used)).Error
+// SetSubscriptionAutoRenew menyimpan niat perpanjangan user. Downgrade terjadwal tidak disentuh karena
+// periodenya sudah dibayar dan tetap berlaku meski perpanjangan otomatis dimatikan.
+func (r *userRepository) SetSubscriptionAutoRenew(userID string, autoRenew bool, canceledAt *time.Time) error {
+	updates := map[string]interface{}{
+		"auto_renew":  autoRenew,
+		"canceled_at": canceledAt,
+	}
+
+	result := r.db.Model(&models.UserSubscription{}).Where("user_id = ?", userID).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) GetTokenUsages(userID string, limit, offset int) ([]models.TokenUsage, int64, error) {
+	var usages []models.TokenUsage
+	var count int64
+
+	db := r.db.Model(&models.TokenUsage{}).Where("user_id = ?", userID)
+	if err := db.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := db.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&usages).Error
+	return usages, count, err
 }
 
 func (r *userRepository) GetPayments(userID string) ([]models.Payment, error) {
 	var payments []models.Payment
-	err := r.db.Preload("Tier").Preload("User").
+	err := r.db.Preload("Tier").Preload("User").Preload("Invoice").
 		Where("user_id = ?", userID).
 		Order("created_at DESC").
 		Find(&payments).Error
 	return payments, err
 }
 
+func (r *userRepository) GetInvoices(userID string) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	err := r.db.Where("user_id = ?", userID).Order("issued_at DESC").Find(&invoices).Error
+	return invoices, err
+}
+
+func (r *userRepository) GetInvoiceByID(userID, invoiceID string) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := r.db.Where("id = ? AND user_id = ?", invoiceID, userID).First(&invoice).Error
+	return &invoice, err
+}
+
 func (r *userRepository) GetFormsByUser(userID string) ([]models.Form, error) {
 	var forms []models.Form
 	err := r.db.Where("user_id = ?", userID).Find(&forms).Error
@@ -66,3 +125,176 @@ func (r *userRepository) GetFormDetail(formID string) (*models.Form, error) {
 	err := r.db.First(&form, "id = ?", formID).Error
 	return &form, err
 }
+
+func (r *userRepository) GetExportData(userID string) (*AccountExportData, error) {
+	data := &AccountExportData{}
+	if err := r.db.First(&data.User, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+
+	var sub models.UserSubscription
+	err := r.db.Preload("SubscriptionTier").First(&sub, "user_id = ?", userID).Error
+	if err == nil {
+		data.Subscription = &sub
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if err := r.db.Preload("Setting").Preload("FormSection").Preload("Questions.Options").
+		Where("user_id = ?", userID).Order("created_at").Find(&data.Forms).Error; err != nil {
+		return nil, err
+	}
+
+	formIDs := make([]string, 0, len(data.Forms))
+	for _, f := range data.Forms {
+		formIDs = append(formIDs, f.ID.String())
+	}
+	if len(formIDs) > 0 {
+		if err := r.db.Where("form_id IN ?", formIDs).Order("form_id, version").Find(&data.Versions).Error; err != nil {
+			return nil, err
+		}
+		if err := r.db.Preload("Answers").Where("form_id IN ?", formIDs).
+			Order("submitted_at").Find(&data.Submissions).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.db.Preload("Tier").Preload("Invoice").Preload("Refunds").
+		Where("user_id = ?", userID).Order("created_at").Find(&data.Payments).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (r *userRepository) ScheduleDeletion(userID string, at *time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ? AND anonymized_at IS NULL", userID).
+		Update("deletion_scheduled_at", at).Error
+}
+
+func (r *userRepository) FindUsersDueForDeletion(now time.Time, limit int) ([]models.User, error) {
+	var users []models.User
+	err := r.db.Where("deletion_scheduled_at <= ? AND anonymized_at IS NULL", now).
+		Limit(limit).Find(&users).Error
+	return users, err
+}
+
+// PurgeUser menghapus form (beserta section, pertanyaan, opsi, submission dan jawaban) dan semua data
+// akun, lalu menganonimkan baris user dan invoice. Payment tetap disimpan untuk akuntansi.
+// Mengembalikan URL aset Cloudinary yang harus dihapus setelah transaksi berhasil.
+func (r *userRepository) PurgeUser(userID string) ([]string, error) {
+	var assets []string
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+			return err
+		}
+
+		var formIDs, questionIDs, submissionIDs []string
+		if err := tx.Model(&models.Form{}).Where("user_id = ?", userID).Pluck("id", &formIDs).Error; err != nil {
+			return err
+		}
+		if len(formIDs) > 0 {
+			if err := tx.Model(&models.Question{}).Where("form_id IN ?", formIDs).Pluck("id", &questionIDs).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Submission{}).Where("form_id IN ?", formIDs).Pluck("id", &submissionIDs).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Question{}).Where("form_id IN ? AND image_url <> ''", formIDs).Pluck("image_url", &assets).Error; err != nil {
+				return err
+			}
+		}
+		if len(questionIDs) > 0 {
+			var optionImages []string
+			if err := tx.Model(&models.Option{}).Where("question_id IN ? AND image_url <> ''", questionIDs).Pluck("image_url", &optionImages).Error; err != nil {
+				return err
+			}
+			assets = append(assets, optionImages...)
+		}
+		if user.Avatar != "" {
+			assets = append(assets, user.Avatar)
+		}
+
+		if len(submissionIDs) > 0 {
+			if err := tx.Where("submission_id IN ?", submissionIDs).Delete(&models.Answer{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("response_id IN ?", submissionIDs).Delete(&models.Queue{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id IN ?", submissionIDs).Delete(&models.Submission{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(questionIDs) > 0 {
+			if err := tx.Where("question_id IN ?", questionIDs).Delete(&models.Option{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(formIDs) > 0 {
+			for _, model := range []any{&models.Question{}, &models.FormSection{}, &models.FormSetting{}, &models.FormVersion{}, &models.FormInvitee{}} {
+				if err := tx.Where("form_id IN ?", formIDs).Delete(model).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Where("id IN ?", formIDs).Delete(&models.Form{}).Error; err != nil {
+				return err
+			}
+		}
+
+		// data akun yang tidak dibutuhkan untuk akuntansi
+		for _, model := range []any{
+			&models.PasswordReset{}, &models.TwoFactorRecoveryCode{}, &models.UserIdentity{},
+			&models.APIKey{}, &models.TokenUsage{}, &models.UserSubscription{},
+		} {
+			if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.Token{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? OR email = ?", userID, user.Email).Delete(&models.SecurityEvent{}).Error; err != nil {
+			return err
+		}
+
+		// jejak audit: riwayat form ikut dihapus bersama form-nya, sisanya dipertahankan tanpa data pribadi
+		if len(formIDs) > 0 {
+			if err := tx.Where("form_id IN ?", formIDs).Delete(&models.AuditLog{}).Error; err != nil {
+				return err
+			}
+		}
+		emailPattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(user.Email) + "%"
+		if err := tx.Model(&models.AuditLog{}).
+			Where("actor_id = ? OR (entity_type = ? AND entity_id = ?) OR `before` LIKE ? OR `after` LIKE ?",
+				userID, "user", userID, emailPattern, emailPattern).
+			Updates(map[string]any{"before": "", "after": "", "ip_address": ""}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Invoice{}).Where("user_id = ?", userID).Updates(map[string]any{
+			"customer_name":  "Deleted User",
+			"customer_email": "",
+		}).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]any{
+			"email":                 fmt.Sprintf("deleted-%s@deleted.invalid", userID),
+			"password":              "",
+			"fullname":              "Deleted User",
+			"avatar":                "",
+			"two_factor_enabled":    false,
+			"two_factor_secret":     "",
+			"two_factor_enabled_at": nil,
+			"deletion_scheduled_at": nil,
+			"anonymized_at":         now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
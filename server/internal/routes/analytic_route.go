@@ -2,14 +2,16 @@ package routes
 
 import (
 	"server/internal/handlers"
+	"server/internal/services"
 
 	"server/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AnalyticsRoutes(r *gin.Engine, handler *handlers.AnalyticsHandler) {
-	analytics := r.Group("/api/v1/forms", middleware.AuthRequired(), middleware.RoleOnly("user", "admin"))
+func AnalyticsRoutes(r *gin.Engine, handler *handlers.AnalyticsHandler, quota services.QuotaService, rbac services.RBACService) {
+	analytics := r.Group("/api/v1/forms", middleware.AuthRequired(), middleware.RateLimitByMethod(middleware.PolicyRead, middleware.PolicyWrite),
+		middleware.RequirePermission(rbac, services.PermAnalyticsRead), middleware.ChargeFormOwner(quota, services.ActionAnalyticsRun))
 
 	analytics.GET("/:id/analytics", handler.GetFormAnalytics)
 	analytics.GET("/:id/analytics/summary", handler.GetFormAnalyticSummary)
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"server/internal/handlers"
+	"server/internal/middleware"
+	"server/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func APIKeyRoutes(r *gin.Engine, handler *handlers.APIKeyHandler, rbac services.RBACService) {
+	keys := r.Group("/api/v1/user/api-keys", middleware.AuthRequired(), middleware.SessionOnly(), middleware.RateLimitByMethod(middleware.PolicyRead, middleware.PolicyWrite), middleware.RequirePermission(rbac, services.PermProfileManage))
+
+	keys.POST("", handler.CreateAPIKey)
+	keys.GET("", handler.GetAPIKeys)
+	keys.DELETE("/:id", handler.RevokeAPIKey)
+}
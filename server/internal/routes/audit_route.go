@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"server/internal/handlers"
+	"server/internal/middleware"
+	"server/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AuditRoutes(r *gin.Engine, handler *handlers.AuditHandler, rbac services.RBACService) {
+	admin := r.Group("/api/v1/admin", middleware.AuthRequired(), middleware.RateLimitByMethod(middleware.PolicyRead, middleware.PolicyWrite), middleware.RequirePermission(rbac, services.PermUsersManage))
+	admin.GET("/audit-logs", handler.GetAuditLogs)
+
+	form := r.Group("/api/v1/forms", middleware.AuthRequired(), middleware.RateLimitByMethod(middleware.PolicyRead, middleware.PolicyWrite), middleware.RequirePermission(rbac, services.PermFormsManage))
+	form.GET("/:id/history", handler.GetFormHistory)
+}
@@ -1,9 +1,11 @@
 package routes
 
 import (
+	"net/http"
 	"server/internal/handlers"
 
 	"server/internal/middleware"
+	"server/internal/oidc"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,10 +13,40 @@ import (
 func AuthRoutes(r *gin.Engine, handler *handlers.AuthHandler) {
 	auth := r.Group("/api/v1/auth")
 
-	auth.POST("/send-otp", handler.SendOTP)
-	auth.POST("/verify-otp", handler.VerifyOTP)
-	auth.POST("/register", handler.Register)
-	auth.POST("/login", handler.Login)
-	protected := auth.Use(middleware.AuthRequired())
+	// endpoint kredensial publik dibatasi ketat per IP
+	public := auth.Group("", middleware.RateLimit(middleware.PolicyAuth))
+
+	public.POST("/send-otp", handler.SendOTP)
+	public.POST("/verify-otp", handler.VerifyOTP)
+	public.POST("/register", handler.Register)
+	public.POST("/login", handler.Login)
+	public.POST("/forgot-password", handler.ForgotPassword)
+	public.POST("/reset-password", handler.ResetPassword)
+	public.POST("/unlock", handler.UnlockAccount)
+	public.POST("/2fa/login", handler.TwoFactorLogin)
+	public.GET("/oidc/providers", handler.GetOIDCProviders)
+	public.GET("/oidc/:provider/login", handler.OIDCLogin)
+	public.GET("/oidc/:provider/callback", handler.OIDCCallback)
+
+	// refresh dan logout dipanggil otomatis oleh client, banyak user di balik NAT yang sama berbagi IP
+	session := auth.Group("", middleware.RateLimit(middleware.PolicySession))
+	session.POST("/refresh-token", handler.RefreshToken)
+	session.POST("/logout", handler.Logout)
+
+	protected := auth.Group("", middleware.AuthRequired(), middleware.SessionOnly(), middleware.RateLimitByMethod(middleware.PolicyRead, middleware.PolicyWrite))
 	protected.POST("/me", handler.AuthMe)
+	protected.PUT("/change-password", handler.ChangePassword)
+	protected.POST("/logout-all", handler.LogoutAll)
+	protected.GET("/sessions", handler.GetSessions)
+	protected.DELETE("/sessions/:id", handler.RevokeSession)
+	protected.POST("/2fa/enroll", handler.EnrollTwoFactor)
+	protected.POST("/2fa/verify", handler.ConfirmTwoFactor)
+	protected.POST("/2fa/disable", handler.DisableTwoFactor)
+	protected.POST("/2fa/recovery-codes", handler.RegenerateRecoveryCodes)
+}
+
+// MockOIDCRoutes memasang mock OpenID Connect issuer untuk development; OIDC_MOCK_ISSUER_URL harus
+// menunjuk ke prefix ini, mis. http://localhost:5000/api/v1/oidc-mock.
+func MockOIDCRoutes(r *gin.Engine, issuer *oidc.MockIssuer) {
+	r.Any("/api/v1/oidc-mock/*path", gin.WrapH(http.StripPrefix("/api/v1/oidc-mock", issuer)))
 }
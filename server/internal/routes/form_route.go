@@ -2,16 +2,17 @@ package routes
 
 import (
 	"server/internal/handlers"
+	"server/internal/services"
 
 	"server/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func FormRoutes(r *gin.Engine, handler *handlers.FormHandler) {
-	form := r.Group("/api/v1/forms", middleware.AuthRequired(), middleware.RoleOnly("user"))
+func FormRoutes(r *gin.Engine, handler *handlers.FormHandler, quota services.QuotaService, rbac services.RBACService) {
+	form := r.Group("/api/v1/forms", middleware.AuthRequired(), middleware.RateLimitByMethod(middleware.PolicyRead, middleware.PolicyWrite), middleware.RequirePermission(rbac, services.PermFormsManage))
 
-	form.POST("", handler.CreateNewForm)
+	form.POST("", middleware.ChargeTokens(quota, services.ActionFormCreate), handler.CreateNewForm)
 	form.GET("", handler.GetAllForms)
 	form.GET("/:id", handler.GetFormDetail)
 
@@ -21,6 +22,15 @@ func FormRoutes(r *gin.Engine, handler *handlers.FormHandler) {
 	form.POST("/:id/sections", handler.AddFormSection)
 	form.GET("/:id/sections", handler.GetFormSections)
 
+	form.POST("/:id/publish", handler.PublishForm)
+	form.GET("/:id/versions", handler.GetFormVersions)
+	form.GET("/:id/versions/:version", handler.GetFormVersion)
+	form.GET("/:id/diff", handler.DiffFormVersions)
+
+	form.POST("/:id/invitees", handler.AddInvitees)
+	form.GET("/:id/invitees", handler.GetInvitees)
+	form.DELETE("/:id/invitees/:inviteeId", handler.RemoveInvitee)
+
 	form.GET("/:id/questions", handler.GetFormQuestion)
 	form.POST("/:id/questions", handler.AddFormQuestion)
 
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"server/internal/handlers"
+	"server/internal/middleware"
+	"server/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func MetricsRoutes(r *gin.Engine, handler *handlers.MetricsHandler, rbac services.RBACService) {
+	admin := r.Group("/api/v1/metrics", middleware.AuthRequired(), middleware.RateLimitByMethod(middleware.PolicyRead, middleware.PolicyWrite), middleware.RequirePermission(rbac, services.PermMetricsRead))
+
+	admin.GET("/mrr", handler.GetMRR)
+	admin.GET("/revenue", handler.GetRevenue)
+	admin.GET("/subscriptions", handler.GetSubscriptionFlow)
+	admin.GET("/conversion", handler.GetConversion)
+	admin.GET("/tokens", handler.GetTokenConsumption)
+}
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"server/internal/handlers"
+	"server/internal/services"
+
+	"server/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PaymentRoutes(r *gin.Engine, handler *handlers.PaymentHandler, rbac services.RBACService) {
+	payment := r.Group("/api/v1/payments")
+
+	// webhook dari payment gateway (tanpa auth & api key)
+	payment.POST("/notifications", handler.HandlePaymentNotification)
+
+	// checkout & callback tiruan untuk development offline (PAYMENT_GATEWAY=fake)
+	payment.GET("/fake/:id", handler.GetFakeCheckout)
+	payment.POST("/fake/:id/:event", handler.SimulatePayment)
+
+	user := payment.Group("", middleware.AuthRequired(), middleware.RateLimitByMethod(middleware.PolicyRead, middleware.PolicyWrite), middleware.RequirePermission(rbac, services.PermPaymentsCreate))
+	user.POST("", handler.CreateNewPayment)
+
+	admin := payment.Group("", middleware.AuthRequired(), middleware.RateLimitByMethod(middleware.PolicyRead, middleware.PolicyWrite), middleware.RequirePermission(rbac, services.PermPaymentsManage))
+	admin.GET("", handler.GetAllPaymentHistory)
+	admin.GET("/:id", handler.GetPaymentDetail)
+	admin.POST("/:id/refunds", handler.RefundPayment)
+	admin.GET("/reconciliations", handler.GetPaymentMismatches)
+	admin.POST("/reconciliations", handler.RunReconciliation)
+	admin.PUT("/reconciliations/:id/resolve", handler.ResolvePaymentMismatch)
+}
@@ -2,14 +2,15 @@ package routes
 
 import (
 	"server/internal/handlers"
+	"server/internal/services"
 
 	"server/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func QueueRoutes(r *gin.Engine, handler *handlers.QueueHandler) {
-	queue := r.Group("/api/v1/queue", middleware.AuthRequired(), middleware.RoleOnly("user", "admin"))
+func QueueRoutes(r *gin.Engine, handler *handlers.QueueHandler, rbac services.RBACService) {
+	queue := r.Group("/api/v1/queue", middleware.AuthRequired(), middleware.RateLimitByMethod(middleware.PolicyRead, middleware.PolicyWrite), middleware.RequirePermission(rbac, services.PermQueueManage))
 
 	queue.GET("", handler.GetAllQueue)
 	queue.POST("/:responseId/execute", handler.ExecuteQueue)
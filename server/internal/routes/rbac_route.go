@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"server/internal/handlers"
+	"server/internal/middleware"
+	"server/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RBACRoutes(r *gin.Engine, handler *handlers.RBACHandler, rbac services.RBACService) {
+	admin := r.Group("/api/v1/admin", middleware.AuthRequired(), middleware.RateLimitByMethod(middleware.PolicyRead, middleware.PolicyWrite), middleware.RequirePermission(rbac, services.PermUsersManage))
+
+	admin.GET("/roles", handler.GetRolePermissions)
+	admin.PUT("/users/:id/promote", handler.PromoteUser)
+	admin.PUT("/users/:id/demote", handler.DemoteUser)
+}
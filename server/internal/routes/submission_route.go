@@ -2,18 +2,23 @@ package routes
 
 import (
 	"server/internal/handlers"
+	"server/internal/services"
 
 	"server/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SubmissionRoutes(r *gin.Engine, handler *handlers.SubmissionHandler) {
+func SubmissionRoutes(r *gin.Engine, handler *handlers.SubmissionHandler, quota services.QuotaService, rbac services.RBACService) {
 	form := r.Group("/api/v1/forms")
 
-	form.POST("/:id/submissions", handler.SendFormSubmission)
+	form.GET("/:id/live", middleware.RateLimit(middleware.PolicyRead), handler.GetLiveForm)
+	form.GET("/:id/submissions/challenge", middleware.RateLimit(middleware.PolicySubmission), handler.GetSubmissionChallenge)
+	form.POST("/:id/submissions", middleware.RateLimit(middleware.PolicySubmission), handler.SendFormSubmission)
 
-	admin := form.Group("", middleware.AuthRequired(), middleware.RoleOnly("user", "admin"))
+	admin := form.Group("", middleware.AuthRequired(), middleware.RateLimitByMethod(middleware.PolicyRead, middleware.PolicyWrite), middleware.RequirePermission(rbac, services.PermSubmissionsRead))
 	admin.GET("/:id/submissions", handler.GetFormSubmissions)
+	admin.GET("/:id/submissions/retention", handler.GetRetentionLogs)
+	admin.GET("/:id/submissions/export", middleware.ChargeFormOwner(quota, services.ActionExport), handler.ExportSubmissions)
 	admin.GET("/:id/submissions/:sessionid", handler.GetSubmissionsResult)
 }
@@ -2,20 +2,28 @@ package routes
 
 import (
 	"server/internal/handlers"
+	"server/internal/services"
 
 	"server/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SubscriptionRoutes(r *gin.Engine, handler *handlers.SubscriptionHandler) {
-	admin := r.Group("/api/v1/subscriptions", middleware.AuthRequired(), middleware.RoleOnly("admin"))
+func SubscriptionRoutes(r *gin.Engine, handler *handlers.SubscriptionHandler, rbac services.RBACService) {
+	admin := r.Group("/api/v1/subscriptions", middleware.AuthRequired(), middleware.RateLimitByMethod(middleware.PolicyRead, middleware.PolicyWrite), middleware.RequirePermission(rbac, services.PermSubscriptionsManage))
 
 	admin.GET("", handler.GetAllUsersWithSubscriptions)
 	admin.GET("/:id", handler.GetUserDetailSubscriptions)
 	admin.PUT("/reset", handler.ResetUserSubscription)
+	admin.PUT("/scheduled-changes", handler.ApplyScheduledTierChanges)
 
 	admin.POST("", handler.CreateSubscriptionTier)
 	admin.PUT("/:id", handler.UpdateSubscriptionTier)
 	admin.DELETE("/:id", handler.DeleteSubscriptionTier)
+
+	admin.GET("/vouchers", handler.GetAllVouchers)
+	admin.GET("/vouchers/:voucherId", handler.GetVoucherDetail)
+	admin.POST("/vouchers", handler.CreateVoucher)
+	admin.PUT("/vouchers", handler.UpdateVoucher)
+	admin.DELETE("/vouchers/:voucherId", handler.DeleteVoucher)
 }
@@ -3,19 +3,27 @@ package routes
 import (
 	"server/internal/handlers"
 	"server/internal/middleware"
+	"server/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
-func UserRoutes(r *gin.Engine, handler *handlers.UserHandler) {
-	user := r.Group("/api/v1/user", middleware.AuthRequired(), middleware.RoleOnly("user"))
+func UserRoutes(r *gin.Engine, handler *handlers.UserHandler, rbac services.RBACService) {
+	user := r.Group("/api/v1/user", middleware.AuthRequired(), middleware.RateLimitByMethod(middleware.PolicyRead, middleware.PolicyWrite), middleware.RequirePermission(rbac, services.PermProfileManage))
 
 	user.GET("/profile", handler.GetUserProfile)
 	user.PUT("/profile", handler.UpdateUserProfile)
 	user.GET("/subscriptions", handler.GetMySubscription)
-	user.PUT("/subscriptions", handler.UpdateUserSubscription)
+	user.GET("/subscriptions/usage", handler.GetMyTokenUsage)
+	user.POST("/subscriptions/cancel", handler.CancelMySubscription)
+	user.POST("/subscriptions/resume", handler.ResumeMySubscription)
 	user.GET("/payments", handler.GetMyTransactionHistory)
+	user.GET("/invoices", handler.GetMyInvoices)
+	user.GET("/invoices/:id/pdf", handler.DownloadInvoice)
 	user.POST("/forms", handler.GetMyForms)
 	user.POST("/forms/:id", handler.GetMyFormDetail)
+	user.GET("/export", middleware.SessionOnly(), handler.ExportMyData)
+	user.POST("/deletion", middleware.SessionOnly(), handler.RequestAccountDeletion)
+	user.DELETE("/deletion", middleware.SessionOnly(), handler.CancelAccountDeletion)
 
 }
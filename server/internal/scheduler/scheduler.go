@@ -0,0 +1,150 @@
+package scheduler
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"server/internal/config"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// renewLockScript memperpanjang TTL lock hanya bila lock masih dipegang instance ini.
+var renewLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func() (int64, error)
+}
+
+// Scheduler menjalankan job berkala di dalam proses server. Setiap eksekusi diawali dengan
+// mengambil lock di Redis, sehingga bila ada beberapa instance hanya satu yang menjalankan
+// job tersebut pada satu interval.
+type Scheduler struct {
+	jobs       []Job
+	instanceID string
+	stop       chan struct{}
+	wg         sync.WaitGroup
+}
+
+func New() *Scheduler {
+	return &Scheduler{
+		instanceID: uuid.NewString(),
+		stop:       make(chan struct{}),
+	}
+}
+
+func (s *Scheduler) Register(name string, interval time.Duration, run func() (int64, error)) {
+	s.jobs = append(s.jobs, Job{Name: name, Interval: interval, Run: run})
+}
+
+func (s *Scheduler) Start() {
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(job)
+	}
+	log.Printf("scheduler started with %d jobs (instance %s)", len(s.jobs), s.instanceID)
+}
+
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(job Job) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	s.execute(job)
+	for {
+		select {
+		case <-ticker.C:
+			s.execute(job)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *Scheduler) execute(job Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[scheduler] job %s panicked: %v", job.Name, r)
+		}
+	}()
+
+	acquired, err := s.acquireLock(job)
+	if err != nil {
+		log.Printf("[scheduler] job %s skipped, failed to acquire lock: %v", job.Name, err)
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go s.renewLock(job, done)
+
+	start := time.Now()
+	affected, err := job.Run()
+	if err != nil {
+		log.Printf("[scheduler] job %s failed after %v: %v", job.Name, time.Since(start), err)
+		return
+	}
+	if affected > 0 {
+		log.Printf("[scheduler] job %s done in %v, %d rows affected", job.Name, time.Since(start), affected)
+	}
+}
+
+func lockKey(job Job) string {
+	return "scheduler:lock:" + job.Name
+}
+
+func lockTTL(job Job) time.Duration {
+	return job.Interval * 9 / 10
+}
+
+// acquireLock memakai SET NX dengan TTL sedikit lebih pendek dari interval job. Lock sengaja
+// tidak dilepas setelah job selesai, agar instance lain tidak menjalankan ulang job di interval
+// yang sama, sementara instance pemegang lock tetap bisa mengambilnya lagi di tick berikutnya.
+func (s *Scheduler) acquireLock(job Job) (bool, error) {
+	return config.RedisClient.SetNX(config.Ctx, lockKey(job), s.instanceID, lockTTL(job)).Result()
+}
+
+// renewLock memperpanjang lock selama job masih berjalan, sehingga job yang lebih lama dari TTL tidak
+// diambil alih instance lain di tengah jalan. Perpanjangan berhenti ketika done ditutup atau lock
+// ternyata sudah dipegang instance lain.
+func (s *Scheduler) renewLock(job Job, done <-chan struct{}) {
+	ttl := lockTTL(job)
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			renewed, err := renewLockScript.Run(config.Ctx, config.RedisClient, []string{lockKey(job)},
+				s.instanceID, ttl.Milliseconds()).Int64()
+			if err != nil {
+				log.Printf("[scheduler] job %s failed to renew lock: %v", job.Name, err)
+				continue
+			}
+			if renewed == 0 {
+				log.Printf("[scheduler] job %s lost its lock while running", job.Name)
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
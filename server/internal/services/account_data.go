@@ -0,0 +1,198 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"server/internal/dto"
+	"server/internal/utils"
+	"time"
+)
+
+const purgeAccountsBatch = 50
+
+// ExportData menyusun seluruh data milik user (profil, langganan, form, submission pada form
+// miliknya, dan payment) menjadi arsip ZIP berisi file JSON.
+func (s *userService) ExportData(userID string) ([]byte, string, error) {
+	data, err := s.repo.GetExportData(userID)
+	if err != nil {
+		return nil, "", errors.New("user not found")
+	}
+
+	profile := map[string]any{
+		"id":        data.User.ID.String(),
+		"email":     data.User.Email,
+		"fullname":  data.User.Fullname,
+		"avatar":    data.User.Avatar,
+		"createdAt": data.User.CreatedAt,
+		"twoFactor": data.User.TwoFactorEnabled,
+	}
+
+	versionNumbers := make(map[string]int, len(data.Versions))
+	versions := make([]dto.FormVersionResponse, 0, len(data.Versions))
+	for i := range data.Versions {
+		v := &data.Versions[i]
+		versionNumbers[v.ID.String()] = v.Version
+		res := toFormVersionResponse(v, "")
+		if snapshot, err := decodeFormSnapshot(v); err == nil {
+			res.Snapshot = snapshot
+		}
+		versions = append(versions, res)
+	}
+
+	submissions := make([]dto.ExportSubmission, 0, len(data.Submissions))
+	for _, sub := range data.Submissions {
+		answers := make([]dto.ExportAnswer, 0, len(sub.Answers))
+		for _, a := range sub.Answers {
+			answers = append(answers, dto.ExportAnswer{
+				QuestionID: a.QuestionID.String(),
+				OptionID:   a.OptionID,
+				TextAnswer: a.TextAnswer,
+			})
+		}
+		exported := dto.ExportSubmission{
+			ID:          sub.ID.String(),
+			FormID:      sub.FormID.String(),
+			Email:       sub.Email,
+			Score:       sub.Score,
+			SubmittedAt: sub.SubmittedAt.Format(time.RFC3339),
+			Flagged:     sub.Flagged,
+			Answers:     answers,
+		}
+		if sub.FormVersionID != nil {
+			exported.FormVersion = versionNumbers[sub.FormVersionID.String()]
+		}
+		submissions = append(submissions, exported)
+	}
+
+	payments := make([]dto.PaymentResponse, 0, len(data.Payments))
+	for _, p := range data.Payments {
+		var invoiceID, invoiceNumber, paidAt string
+		if p.Invoice != nil {
+			invoiceID = p.Invoice.ID.String()
+			invoiceNumber = p.Invoice.Number
+		}
+		if p.PaidAt != nil {
+			paidAt = p.PaidAt.Format("2006-01-02 15:04:05")
+		}
+		payments = append(payments, dto.PaymentResponse{
+			ID:            p.ID.String(),
+			UserID:        p.UserID.String(),
+			UserEmail:     data.User.Email,
+			Fullname:      data.User.Fullname,
+			TierID:        p.TierID,
+			TierName:      p.Tier.Name,
+			Type:          p.Type,
+			Subtotal:      p.Subtotal,
+			Credit:        p.Credit,
+			Discount:      p.Discount,
+			Tax:           p.Tax,
+			Total:         p.Total,
+			PaymentMethod: p.Method,
+			Status:        p.Status,
+			PaidAt:        paidAt,
+			InvoiceID:     invoiceID,
+			InvoiceNumber: invoiceNumber,
+		})
+	}
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", profile},
+		{"subscription.json", data.Subscription},
+		{"forms.json", data.Forms},
+		{"form_versions.json", versions},
+		{"submissions.json", submissions},
+		{"payments.json", payments},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, "", err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, "", err
+	}
+
+	filename := fmt.Sprintf("account-data-%s.zip", time.Now().Format("20060102"))
+	return buf.Bytes(), filename, nil
+}
+
+// ScheduleAccountDeletion menjadwalkan penghapusan akun setelah masa tenggang; selama masa
+// tenggang user masih bisa login dan membatalkannya.
+func (s *userService) ScheduleAccountDeletion(userID string, req *dto.AccountDeletionRequest) (*dto.AccountDeletionResponse, error) {
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if user.Role == RoleAdmin {
+		return nil, errors.New("admin accounts cannot be deleted")
+	}
+	if !utils.CheckPasswordHash(req.Password, user.Password) {
+		return nil, errors.New("password is incorrect")
+	}
+
+	at := user.DeletionScheduledAt
+	if at == nil {
+		scheduled := time.Now().Add(utils.GetAccountDeletionGrace())
+		if err := s.repo.ScheduleDeletion(userID, &scheduled); err != nil {
+			return nil, err
+		}
+		at = &scheduled
+	}
+	return &dto.AccountDeletionResponse{DeletionScheduledAt: at.Format("2006-01-02 15:04:05")}, nil
+}
+
+func (s *userService) CancelAccountDeletion(userID string) error {
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	if user.DeletionScheduledAt == nil {
+		return errors.New("account deletion is not scheduled")
+	}
+	return s.repo.ScheduleDeletion(userID, nil)
+}
+
+// PurgeScheduledAccounts menghapus akun yang masa tenggangnya sudah lewat, lalu membersihkan
+// aset Cloudinary miliknya. Kegagalan hapus aset hanya dicatat agar tidak menahan proses.
+func (s *userService) PurgeScheduledAccounts() (int64, error) {
+	users, err := s.repo.FindUsersDueForDeletion(time.Now(), purgeAccountsBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	var purged int64
+	for _, u := range users {
+		assets, err := s.repo.PurgeUser(u.ID.String())
+		if err != nil {
+			log.Printf("purge account %s: %v", u.ID, err)
+			continue
+		}
+		for _, url := range assets {
+			if url == "" || utils.IsDiceBear(url) {
+				continue
+			}
+			if err := utils.DeleteFromCloudinary(url); err != nil {
+				log.Printf("purge account %s: delete asset %s: %v", u.ID, url, err)
+			}
+		}
+		InvalidateUserAccessCache(u.ID.String())
+		purged++
+	}
+	return purged, nil
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"server/internal/dto"
+	"server/internal/repositories"
+	"sort"
+)
+
+type AnalyticsService interface {
+	GetFormAnalytics(userID, formID string) (*dto.FormAnalyticsResponse, error)
+	GetFormAnalyticSummary(userID, formID string) (*dto.FormAnalyticsSummaryResponse, error)
+}
+
+type analyticsService struct {
+	repo     repositories.AnalyticsRepository
+	formRepo repositories.FormRepository
+}
+
+func NewAnalyticsService(repo repositories.AnalyticsRepository, formRepo repositories.FormRepository) AnalyticsService {
+	return &analyticsService{repo, formRepo}
+}
+
+func (s *analyticsService) GetFormAnalyticSummary(userID, formID string) (*dto.FormAnalyticsSummaryResponse, error) {
+	form, err := s.formRepo.FindByID(formID)
+	if err != nil || form.UserID.String() != userID {
+		return nil, errors.New("form not found")
+	}
+	setting, err := s.formRepo.GetFormSetting(formID)
+	if err != nil {
+		return nil, errors.New("form not found")
+	}
+
+	passingGrade := 0.0
+	if setting.PassingGrade != nil {
+		passingGrade = *setting.PassingGrade
+	}
+	stats, err := s.repo.GetSubmissionStats(formID, passingGrade)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &dto.FormAnalyticsSummaryResponse{
+		FormID:           formID,
+		TotalSubmissions: stats.Total,
+		FlaggedCount:     stats.Flagged,
+		AverageScore:     stats.AverageScore,
+	}
+	if setting.Grading && stats.Scored > 0 {
+		rate := float64(stats.Passed) * 100 / float64(stats.Scored)
+		res.PassRate = &rate
+	}
+	if stats.FirstAt != nil {
+		res.FirstSubmission = stats.FirstAt.Format("2006-01-02 15:04:05")
+	}
+	if stats.LastAt != nil {
+		res.LastSubmission = stats.LastAt.Format("2006-01-02 15:04:05")
+	}
+	return res, nil
+}
+
+// GetFormAnalytics merangkum jawaban per pertanyaan, dikelompokkan per versi form yang diisi responden.
+// Setiap kelompok dirender terhadap snapshot versinya sehingga pertanyaan dan opsi yang sudah diubah
+// atau dihapus dari draft tetap terhitung; submission sebelum publish dirender terhadap draft.
+func (s *analyticsService) GetFormAnalytics(userID, formID string) (*dto.FormAnalyticsResponse, error) {
+	form, err := s.formRepo.GetFormStructure(formID)
+	if err != nil || form.UserID.String() != userID {
+		return nil, errors.New("form not found")
+	}
+	rows, err := s.repo.GetAnswerCounts(formID)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := map[string][]repositories.AnswerCountRow{}
+	for _, row := range rows {
+		key := ""
+		if row.FormVersionID != nil {
+			key = *row.FormVersionID
+		}
+		groups[key] = append(groups[key], row)
+	}
+
+	res := &dto.FormAnalyticsResponse{FormID: form.ID.String(), Versions: []dto.FormVersionAnalytics{}}
+	for versionID, group := range groups {
+		snapshot, version := buildFormSnapshot(form), 0
+		if versionID != "" {
+			v, err := s.formRepo.GetVersionByID(versionID)
+			if err != nil {
+				return nil, err
+			}
+			if snapshot, err = decodeFormSnapshot(v); err != nil {
+				return nil, err
+			}
+			version = v.Version
+		}
+		res.Versions = append(res.Versions, dto.FormVersionAnalytics{
+			Version:   version,
+			Questions: buildFormAnalytics(snapshot, group),
+		})
+	}
+	sort.Slice(res.Versions, func(i, j int) bool { return res.Versions[i].Version < res.Versions[j].Version })
+	return res, nil
+}
+
+func buildFormAnalytics(snapshot *dto.FormSnapshot, rows []repositories.AnswerCountRow) []dto.QuestionAnalytics {
+	type counts struct {
+		total, text int64
+		options     map[uint]int64
+	}
+	byQuestion := map[string]*counts{}
+	for _, row := range rows {
+		c, ok := byQuestion[row.QuestionID]
+		if !ok {
+			c = &counts{options: map[uint]int64{}}
+			byQuestion[row.QuestionID] = c
+		}
+		c.total += row.Total
+		if row.OptionID == nil {
+			c.text += row.Total
+		} else {
+			c.options[*row.OptionID] += row.Total
+		}
+	}
+
+	questions := []dto.QuestionAnalytics{}
+	for _, q := range snapshot.Questions {
+		qa := dto.QuestionAnalytics{QuestionID: q.ID, Text: q.Text, Type: q.Type}
+		c := byQuestion[qa.QuestionID]
+		if c != nil {
+			qa.Responses, qa.TextAnswers = c.total, c.text
+		}
+		for _, o := range q.Options {
+			opt := dto.OptionAnalytics{OptionID: o.ID, Text: o.Text}
+			if c != nil {
+				opt.Count = c.options[o.ID]
+				if c.total > 0 {
+					opt.Percent = float64(opt.Count) * 100 / float64(c.total)
+				}
+			}
+			qa.Options = append(qa.Options, opt)
+		}
+		questions = append(questions, qa)
+	}
+	return questions
+}
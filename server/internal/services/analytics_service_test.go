@@ -0,0 +1,88 @@
+package services
+
+import (
+	"encoding/json"
+	"server/internal/dto"
+	"server/internal/models"
+	"server/internal/repositories"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type fakeAnalyticsRepo struct {
+	repositories.AnalyticsRepository
+
+	rows []repositories.AnswerCountRow
+}
+
+func (r *fakeAnalyticsRepo) GetAnswerCounts(string) ([]repositories.AnswerCountRow, error) {
+	return r.rows, nil
+}
+
+func TestGetFormAnalyticsGroupsByVersion(t *testing.T) {
+	owner, formID, versionID := uuid.New(), uuid.New(), uuid.New()
+	q1, removed := uuid.New(), uuid.New()
+
+	// draft saat ini: opsi Blue sudah diganti Green dan pertanyaan "removed" sudah dihapus
+	draft := &models.Form{
+		ID: formID, UserID: owner,
+		Questions: []models.Question{{
+			ID: q1, Text: "Colour (draft)", Type: "radio",
+			Options: []models.Option{{ID: 1, Text: "Red"}, {ID: 3, Text: "Green"}},
+		}},
+	}
+	snapshot, _ := json.Marshal(dto.FormSnapshot{Questions: []dto.SnapshotQuestion{
+		{QuestionResponse: dto.QuestionResponse{
+			ID: q1.String(), Text: "Colour", Type: "radio",
+			Options: []dto.Option{{ID: 1, Text: "Red"}, {ID: 2, Text: "Blue"}},
+		}},
+		{QuestionResponse: dto.QuestionResponse{ID: removed.String(), Text: "Comments", Type: "text"}},
+	}})
+
+	vid := versionID.String()
+	option := func(id uint) *uint { return &id }
+	s := &analyticsService{
+		repo: &fakeAnalyticsRepo{rows: []repositories.AnswerCountRow{
+			{FormVersionID: &vid, QuestionID: q1.String(), OptionID: option(1), Total: 2},
+			{FormVersionID: &vid, QuestionID: q1.String(), OptionID: option(2), Total: 3},
+			{FormVersionID: &vid, QuestionID: removed.String(), Total: 1},
+			{QuestionID: q1.String(), OptionID: option(3), Total: 4},
+		}},
+		formRepo: &fakeFormRepo{
+			forms:    map[string]*models.Form{formID.String(): draft},
+			versions: map[string]*models.FormVersion{vid: {ID: versionID, FormID: formID, Version: 1, Snapshot: string(snapshot)}},
+		},
+	}
+
+	if _, err := s.GetFormAnalytics(uuid.NewString(), formID.String()); err == nil {
+		t.Fatal("expected an error for another user's form")
+	}
+
+	res, err := s.GetFormAnalytics(owner.String(), formID.String())
+	if err != nil {
+		t.Fatalf("GetFormAnalytics() error = %v", err)
+	}
+	if len(res.Versions) != 2 || res.Versions[0].Version != 0 || res.Versions[1].Version != 1 {
+		t.Fatalf("versions = %+v, want unversioned and version 1", res.Versions)
+	}
+
+	unversioned := res.Versions[0].Questions
+	if len(unversioned) != 1 || unversioned[0].Text != "Colour (draft)" || unversioned[0].Responses != 4 {
+		t.Fatalf("unversioned = %+v, want the draft question with 4 responses", unversioned)
+	}
+	if green := unversioned[0].Options[1]; green.Text != "Green" || green.Count != 4 || green.Percent != 100 {
+		t.Errorf("green = %+v, want 4 answers (100%%)", green)
+	}
+
+	v1 := res.Versions[1].Questions
+	if len(v1) != 2 || v1[0].Text != "Colour" || v1[0].Responses != 5 {
+		t.Fatalf("version 1 = %+v, want the snapshot questions", v1)
+	}
+	if blue := v1[0].Options[1]; blue.Text != "Blue" || blue.Count != 3 || blue.Percent != 60 {
+		t.Errorf("blue = %+v, want 3 answers (60%%)", blue)
+	}
+	if v1[1].QuestionID != removed.String() || v1[1].TextAnswers != 1 {
+		t.Errorf("removed question = %+v, want 1 text answer", v1[1])
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"server/internal/dto"
+	"server/internal/models"
+	"server/internal/repositories"
+	"server/internal/utils"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ScopeFormsRead       = "forms:read"
+	ScopeFormsWrite      = "forms:write"
+	ScopeSubmissionsRead = "submissions:read"
+
+	apiKeyPrefix         = "fk_"
+	maxActiveAPIKeys     = 10
+	apiKeyTouchThreshold = time.Minute
+)
+
+var ErrInvalidAPIKey = errors.New("invalid or revoked api key")
+
+type APIKeyService interface {
+	CreateAPIKey(userID string, req *dto.CreateAPIKeyRequest) (*dto.APIKeyCreatedResponse, error)
+	GetAPIKeys(userID string) ([]dto.APIKeyResponse, error)
+	RevokeAPIKey(userID, keyID string) error
+	Authenticate(rawKey, ip string) (*models.APIKey, *models.User, error)
+}
+
+type apiKeyService struct {
+	repo     repositories.APIKeyRepository
+	authRepo repositories.AuthRepository
+}
+
+func NewAPIKeyService(repo repositories.APIKeyRepository, authRepo repositories.AuthRepository) APIKeyService {
+	return &apiKeyService{repo: repo, authRepo: authRepo}
+}
+
+// CreateAPIKey membuat key dengan format fk_<prefix>_<secret>; prefix dipakai untuk lookup,
+// key lengkap hanya dikembalikan sekali dan disimpan sebagai hash.
+func (s *apiKeyService) CreateAPIKey(userID string, req *dto.CreateAPIKeyRequest) (*dto.APIKeyCreatedResponse, error) {
+	now := time.Now()
+
+	count, err := s.repo.CountActiveAPIKeys(userID, now)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxActiveAPIKeys {
+		return nil, errors.New("maximum number of active api keys reached")
+	}
+
+	prefix, err := utils.GenerateRandomToken(4)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := utils.GenerateRandomToken(24)
+	if err != nil {
+		return nil, err
+	}
+	rawKey := apiKeyPrefix + prefix + "_" + secret
+
+	scopes := slices.Compact(slices.Sorted(slices.Values(req.Scopes)))
+
+	key := &models.APIKey{
+		ID:      uuid.New(),
+		UserID:  uuid.MustParse(userID),
+		Name:    strings.TrimSpace(req.Name),
+		Prefix:  prefix,
+		KeyHash: utils.HashToken(rawKey),
+		Scopes:  strings.Join(scopes, ","),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := now.AddDate(0, 0, req.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.CreateAPIKey(key); err != nil {
+		return nil, err
+	}
+
+	return &dto.APIKeyCreatedResponse{APIKeyResponse: toAPIKeyResponse(key), Key: rawKey}, nil
+}
+
+func (s *apiKeyService) GetAPIKeys(userID string) ([]dto.APIKeyResponse, error) {
+	keys, err := s.repo.GetUserAPIKeys(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dto.APIKeyResponse, 0, len(keys))
+	for i := range keys {
+		result = append(result, toAPIKeyResponse(&keys[i]))
+	}
+	return result, nil
+}
+
+func (s *apiKeyService) RevokeAPIKey(userID, keyID string) error {
+	affected, err := s.repo.RevokeAPIKey(userID, keyID, time.Now())
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("api key not found")
+	}
+	return nil
+}
+
+// Authenticate memvalidasi key dari header X-API-KEY dan mencatat pemakaian terakhir (maks. sekali per menit).
+func (s *apiKeyService) Authenticate(rawKey, ip string) (*models.APIKey, *models.User, error) {
+	rest, ok := strings.CutPrefix(rawKey, apiKeyPrefix)
+	if !ok {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	prefix, _, ok := strings.Cut(rest, "_")
+	if !ok || prefix == "" {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.GetAPIKeyByPrefix(prefix)
+	if err != nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(utils.HashToken(rawKey))) != 1 {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && now.After(*key.ExpiresAt)) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	user, err := s.authRepo.GetUserByID(key.UserID.String())
+	if err != nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchThreshold || key.LastUsedIP != ip {
+		s.repo.TouchAPIKey(key.ID.String(), ip, now)
+	}
+
+	return key, user, nil
+}
+
+func IsPersonalAPIKey(rawKey string) bool {
+	return strings.HasPrefix(rawKey, apiKeyPrefix)
+}
+
+// APIKeyScopeFor memetakan permission RBAC ke scope API key. Permission tanpa scope
+// (profil, pembayaran, admin, dll) tidak bisa diakses dengan API key.
+func APIKeyScopeFor(permission, method string) string {
+	switch permission {
+	case PermFormsManage:
+		if method == http.MethodGet {
+			return ScopeFormsRead
+		}
+		return ScopeFormsWrite
+	case PermSubmissionsRead:
+		return ScopeSubmissionsRead
+	}
+	return ""
+}
+
+func toAPIKeyResponse(key *models.APIKey) dto.APIKeyResponse {
+	res := dto.APIKeyResponse{
+		ID:         key.ID.String(),
+		Name:       key.Name,
+		Prefix:     apiKeyPrefix + key.Prefix,
+		Scopes:     strings.Split(key.Scopes, ","),
+		LastUsedIP: key.LastUsedIP,
+		CreatedAt:  key.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if key.LastUsedAt != nil {
+		res.LastUsedAt = key.LastUsedAt.Format("2006-01-02 15:04:05")
+	}
+	if key.ExpiresAt != nil {
+		res.ExpiresAt = key.ExpiresAt.Format("2006-01-02 15:04:05")
+	}
+	if key.RevokedAt != nil {
+		res.RevokedAt = key.RevokedAt.Format("2006-01-02 15:04:05")
+	}
+	return res
+}
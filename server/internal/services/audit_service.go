@@ -0,0 +1,209 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"reflect"
+	"server/internal/dto"
+	"server/internal/models"
+	"server/internal/repositories"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AuditEntityForm         = "form"
+	AuditEntityFormSetting  = "form_setting"
+	AuditEntitySection      = "section"
+	AuditEntityQuestion     = "question"
+	AuditEntityTier         = "subscription_tier"
+	AuditEntitySubscription = "subscription"
+	AuditEntityVoucher      = "voucher"
+	AuditEntityPayment      = "payment"
+	AuditEntityMismatch     = "payment_mismatch"
+	AuditEntityUser         = "user"
+)
+
+// AuditEntry adalah satu mutasi yang dicatat; Before nil untuk create dan After nil untuk delete.
+type AuditEntry struct {
+	Action     string
+	EntityType string
+	EntityID   string
+	FormID     string
+	Before     any
+	After      any
+}
+
+type AuditService interface {
+	Record(meta dto.RequestMeta, entry AuditEntry)
+	GetAuditLogs(query *dto.AuditLogQuery) (*dto.AuditLogListResponse, error)
+	GetFormHistory(userID, formID string, page, limit int) (*dto.AuditLogListResponse, error)
+}
+
+type auditService struct {
+	repo     repositories.AuditRepository
+	formRepo repositories.FormRepository
+}
+
+func NewAuditService(repo repositories.AuditRepository, formRepo repositories.FormRepository) AuditService {
+	return &auditService{repo, formRepo}
+}
+
+// Record menyimpan audit log secara best-effort: kegagalan hanya dicatat di log server agar
+// tidak menggagalkan mutasi yang sudah berhasil.
+func (s *auditService) Record(meta dto.RequestMeta, entry AuditEntry) {
+	before, after := auditDiff(entry.Before, entry.After)
+
+	row := &models.AuditLog{
+		Action:     entry.Action,
+		EntityType: entry.EntityType,
+		EntityID:   entry.EntityID,
+		Before:     encodeAuditState(before),
+		After:      encodeAuditState(after),
+		IPAddress:  meta.IPAddress,
+		RequestID:  meta.RequestID,
+	}
+	if id, err := uuid.Parse(meta.ActorID); err == nil {
+		row.ActorID = &id
+	}
+	if id, err := uuid.Parse(entry.FormID); err == nil {
+		row.FormID = &id
+	}
+
+	if err := s.repo.Create(row); err != nil {
+		log.Printf("failed to store audit log %s %s/%s: %v", entry.Action, entry.EntityType, entry.EntityID, err)
+	}
+}
+
+func (s *auditService) GetAuditLogs(query *dto.AuditLogQuery) (*dto.AuditLogListResponse, error) {
+	filter := repositories.AuditFilter{
+		ActorID:    query.ActorID,
+		Action:     query.Action,
+		EntityType: query.EntityType,
+		EntityID:   query.EntityID,
+		FormID:     query.FormID,
+		RequestID:  query.RequestID,
+	}
+	if query.From != "" {
+		from, err := time.Parse(time.RFC3339, query.From)
+		if err != nil {
+			return nil, errors.New("from must be an RFC 3339 timestamp")
+		}
+		filter.From = &from
+	}
+	if query.To != "" {
+		to, err := time.Parse(time.RFC3339, query.To)
+		if err != nil {
+			return nil, errors.New("to must be an RFC 3339 timestamp")
+		}
+		filter.To = &to
+	}
+	return s.findLogs(filter, query.Page, query.Limit)
+}
+
+// GetFormHistory mengembalikan riwayat perubahan sebuah form untuk pemiliknya.
+func (s *auditService) GetFormHistory(userID, formID string, page, limit int) (*dto.AuditLogListResponse, error) {
+	form, err := s.formRepo.FindByID(formID)
+	if err != nil || form.UserID.String() != userID {
+		return nil, errors.New("form not found")
+	}
+	return s.findLogs(repositories.AuditFilter{FormID: formID}, page, limit)
+}
+
+func (s *auditService) findLogs(filter repositories.AuditFilter, page, limit int) (*dto.AuditLogListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	logs, total, err := s.repo.Find(filter, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]dto.AuditLogResponse, 0, len(logs))
+	for _, l := range logs {
+		res := dto.AuditLogResponse{
+			ID:         l.ID,
+			Action:     l.Action,
+			EntityType: l.EntityType,
+			EntityID:   l.EntityID,
+			IPAddress:  l.IPAddress,
+			RequestID:  l.RequestID,
+			CreatedAt:  l.CreatedAt.Format("2006-01-02 15:04:05"),
+		}
+		if l.ActorID != nil {
+			res.ActorID = l.ActorID.String()
+		}
+		if l.FormID != nil {
+			res.FormID = l.FormID.String()
+		}
+		if l.Before != "" {
+			_ = json.Unmarshal([]byte(l.Before), &res.Before)
+		}
+		if l.After != "" {
+			_ = json.Unmarshal([]byte(l.After), &res.After)
+		}
+		results = append(results, res)
+	}
+
+	return &dto.AuditLogListResponse{
+		Logs:  results,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	}, nil
+}
+
+// auditDiff mengubah state sebelum/sesudah menjadi map dan, bila keduanya ada, hanya menyisakan
+// field yang berubah.
+func auditDiff(before, after any) (map[string]any, map[string]any) {
+	b, a := toAuditMap(before), toAuditMap(after)
+	if b == nil || a == nil {
+		return b, a
+	}
+
+	changedBefore, changedAfter := map[string]any{}, map[string]any{}
+	for key, val := range a {
+		if !reflect.DeepEqual(b[key], val) {
+			changedBefore[key] = b[key]
+			changedAfter[key] = val
+		}
+	}
+	for key, val := range b {
+		if _, ok := a[key]; !ok {
+			changedBefore[key] = val
+		}
+	}
+	return changedBefore, changedAfter
+}
+
+func toAuditMap(state any) map[string]any {
+	if state == nil || reflect.ValueOf(state).Kind() == reflect.Ptr && reflect.ValueOf(state).IsNil() {
+		return nil
+	}
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return map[string]any{"value": state}
+	}
+	return m
+}
+
+func encodeAuditState(state map[string]any) string {
+	if len(state) == 0 {
+		return ""
+	}
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return ""
+	}
+	return string(raw)
+}
@@ -1,43 +1,100 @@
 package services
 
 import (
+	"crypto/subtle"
 	"errors"
 	"fmt"
+	"log"
+	"net/url"
+	"os"
 	"server/internal/config"
 	"server/internal/dto"
 	"server/internal/models"
+	"server/internal/oidc"
+	"server/internal/repositories"
 	"server/internal/utils"
+	"strings"
 	"time"
 
-	"server/internal/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	otpLength         = 6
+	otpTTL            = 5 * time.Minute
+	otpMaxAttempts    = 5
+	otpLockDuration   = 15 * time.Minute
+	otpResendCooldown = time.Minute
+	verifiedEmailTTL  = 30 * time.Minute
+
+	passwordResetTTL      = 30 * time.Minute
+	passwordResetCooldown = time.Minute
+)
+
+var (
+	ErrOTPLocked   = errors.New("too many invalid attempts, please try again later")
+	ErrOTPCooldown = errors.New("please wait before requesting another OTP")
+
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected, session revoked")
 )
 
 type AuthService interface {
 	SendOTP(req *dto.SendOTPRequest) error
-	VerifyOTPCode(req *dto.VerifyOTPRequest) error
+	VerifyOTPCode(req *dto.VerifyOTPRequest) (*dto.VerifyOTPResponse, error)
 	GetUserInfo(userID string) (*dto.UserInfoResponse, error)
-	UserLogin(req *dto.LoginRequest) (*dto.AuthResponse, error)
-	RefreshUserToken(refreshToken string) (*dto.AuthResponse, error)
-	UserRegister(req *dto.RegisterRequest) (*dto.AuthResponse, error)
+	UserLogin(req *dto.LoginRequest, meta dto.RequestMeta) (*dto.AuthResponse, *dto.TwoFactorChallengeResponse, error)
+	UnlockAccount(req *dto.UnlockAccountRequest, meta dto.RequestMeta) error
+	CompleteTwoFactorLogin(req *dto.TwoFactorLoginRequest, meta dto.RequestMeta) (*dto.AuthResponse, error)
+	EnrollTwoFactor(userID string) (*dto.TwoFactorEnrollResponse, error)
+	ConfirmTwoFactor(userID string, req *dto.TwoFactorCodeRequest, meta dto.RequestMeta) (*dto.RecoveryCodesResponse, error)
+	DisableTwoFactor(userID string, req *dto.TwoFactorDisableRequest, meta dto.RequestMeta) error
+	RegenerateRecoveryCodes(userID string, req *dto.TwoFactorCodeRequest) (*dto.RecoveryCodesResponse, error)
+	MigrateTwoFactorSecrets() (int64, error)
+	RefreshUserToken(refreshToken string, meta dto.RequestMeta) (*dto.AuthResponse, error)
+	UserRegister(req *dto.RegisterRequest, meta dto.RequestMeta) (*dto.AuthResponse, error)
+	Logout(refreshToken string) error
+	LogoutAll(userID string) error
+	GetSessions(userID, currentRefreshToken string) ([]dto.SessionResponse, error)
+	RevokeSession(userID, sessionID string) error
+	PurgeExpiredTokens() (int64, error)
+	ForgotPassword(req *dto.ForgotPasswordRequest) error
+	ResetPassword(req *dto.ResetPasswordRequest, meta dto.RequestMeta) error
+	ChangePassword(userID string, req *dto.ChangePasswordRequest, meta dto.RequestMeta) error
+	GetOIDCProviders() []dto.OIDCProviderResponse
+	StartOIDCLogin(provider string) (string, error)
+	CompleteOIDCLogin(provider string, req *dto.OIDCCallbackRequest, meta dto.RequestMeta) (*dto.AuthResponse, *dto.TwoFactorChallengeResponse, error)
 }
 
 type authService struct {
-	repo repositories.AuthRepository
+	repo      repositories.AuthRepository
+	providers map[string]*oidc.Provider
+	audit     AuditService
 }
 
-func NewAuthService(repo repositories.AuthRepository) AuthService {
-	return &authService{repo}
+func NewAuthService(repo repositories.AuthRepository, providers map[string]*oidc.Provider, audit AuditService) AuthService {
+	return &authService{repo: repo, providers: providers, audit: audit}
 }
 
-func (s *authService) UserRegister(req *dto.RegisterRequest) (*dto.AuthResponse, error) {
+func (s *authService) UserRegister(req *dto.RegisterRequest, meta dto.RequestMeta) (*dto.AuthResponse, error) {
+	// token dari VerifyOTPCode membuktikan email sudah diverifikasi, dan hanya bisa dipakai sekali
+	email := normalizeEmail(req.Email)
+	verifiedKey := "otp:verified:" + email
+	savedHash, err := config.RedisClient.Get(config.Ctx, verifiedKey).Result()
+	if err != nil || subtle.ConstantTimeCompare([]byte(savedHash), []byte(utils.HashToken(req.VerificationToken))) != 1 {
+		return nil, errors.New("email is not verified")
+	}
+
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
 		return nil, err
 	}
 
 	user := &models.User{
-		Email:    req.Email,
+		ID:       uuid.New(),
+		Email:    email,
 		Password: hashedPassword,
+		Role:     RoleUser,
 		Fullname: req.Fullname,
 		Avatar:   utils.RandomUserAvatar(req.Fullname),
 	}
@@ -45,97 +102,285 @@ func (s *authService) UserRegister(req *dto.RegisterRequest) (*dto.AuthResponse,
 	if err := s.repo.CreateNewUser(user); err != nil {
 		return nil, err
 	}
-	userID := user.ID.String()
+	config.RedisClient.Del(config.Ctx, verifiedKey)
 
-	accessToken, err := utils.GenerateAccessToken(userID)
+	return s.issueTokens(user, uuid.New(), time.Now(), meta)
+}
+
+// UserLogin mengembalikan session, atau challenge 2FA bila user mengaktifkan two-factor authentication.
+// Email tidak terdaftar dan password salah menghasilkan error yang sama agar akun tidak bisa dienumerasi.
+func (s *authService) UserLogin(req *dto.LoginRequest, meta dto.RequestMeta) (*dto.AuthResponse, *dto.TwoFactorChallengeResponse, error) {
+	email := normalizeEmail(req.Email)
+	if err := s.checkLoginAllowed(email, meta); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.repo.GetUserByEmail(email)
 	if err != nil {
-		return nil, err
+		// tetap jalankan bcrypt agar waktu respon tidak membedakan email terdaftar/tidak
+		utils.CheckPasswordHash(req.Password, dummyPasswordHash())
+		return nil, nil, s.recordLoginFailure(email, nil, meta)
 	}
-	refreshToken, err := utils.GenerateRefreshToken(userID)
+
+	if !utils.CheckPasswordHash(req.Password, user.Password) {
+		return nil, nil, s.recordLoginFailure(email, user, meta)
+	}
+
+	s.clearLoginFailures(email)
+
+	// login baru dianggap berhasil setelah kode 2FA diverifikasi di CompleteTwoFactorLogin
+	if user.TwoFactorEnabled {
+		s.logSecurityEvent(models.SecurityEvent{UserID: &user.ID, Email: email, Event: SecurityEventLoginChallenged}, meta)
+		challenge, err := s.createTwoFactorChallenge(user)
+		return nil, challenge, err
+	}
+	s.logSecurityEvent(models.SecurityEvent{UserID: &user.ID, Email: email, Event: SecurityEventLoginSucceeded}, meta)
+
+	tokens, err := s.issueTokens(user, uuid.New(), time.Now(), meta)
+	return tokens, nil, err
+}
+
+// issueTokens membuat access token dan refresh token baru dalam family (sesi) yang diberikan.
+func (s *authService) issueTokens(user *models.User, familyID uuid.UUID, startedAt time.Time, meta dto.RequestMeta) (*dto.AuthResponse, error) {
+	accessToken, refreshToken, tokenModel, err := newSessionTokens(user, familyID, startedAt, meta)
 	if err != nil {
 		return nil, err
 	}
-	newTokenModel := &models.Token{
-		UserID:    user.ID,
-		Token:     refreshToken,
-		ExpiredAt: time.Now().Add(7 * 24 * time.Hour),
-	}
 
-	if err := s.repo.StoreRefreshToken(newTokenModel); err != nil {
+	if err := s.repo.StoreRefreshToken(tokenModel); err != nil {
 		return nil, err
 	}
+
 	return &dto.AuthResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(utils.AccessTokenTTL.Seconds()),
 	}, nil
 }
 
-func (s *authService) UserLogin(req *dto.LoginRequest) (*dto.AuthResponse, error) {
-	user, err := s.repo.GetUserByEmail(req.Email)
+func newSessionTokens(user *models.User, familyID uuid.UUID, startedAt time.Time, meta dto.RequestMeta) (string, string, *models.Token, error) {
+	accessToken, err := utils.GenerateAccessToken(user.ID.String(), user.Role)
 	if err != nil {
-		return nil, errors.New("invalid Email")
+		return "", "", nil, err
+	}
+	refreshToken, err := utils.GenerateRefreshToken(user.ID.String())
+	if err != nil {
+		return "", "", nil, err
 	}
 
-	if !utils.CheckPasswordHash(req.Password, user.Password) {
-		return nil, errors.New("invalid Password")
+	now := time.Now()
+	tokenModel := &models.Token{
+		ID:               uuid.New(),
+		UserID:           user.ID,
+		FamilyID:         familyID,
+		TokenHash:        utils.HashToken(refreshToken),
+		UserAgent:        truncate(meta.UserAgent, 255),
+		IPAddress:        meta.IPAddress,
+		SessionStartedAt: startedAt,
+		LastUsedAt:       now,
+		ExpiredAt:        now.Add(utils.RefreshTokenTTL),
 	}
+	return accessToken, refreshToken, tokenModel, nil
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
 
-	accessToken, err := utils.GenerateAccessToken(user.ID.String())
+func (s *authService) SendOTP(req *dto.SendOTPRequest) error {
+	email := normalizeEmail(req.Email)
+	if _, err := s.repo.GetUserByEmail(email); err == nil {
+		return errors.New("email already registered")
+	}
+
+	if locked, _ := config.RedisClient.Exists(config.Ctx, "otp:lock:"+email).Result(); locked > 0 {
+		return ErrOTPLocked
+	}
+
+	// cooldown mencegah email dibanjiri OTP, SetNX sekaligus menjadi penanda pengiriman terakhir
+	ok, err := config.RedisClient.SetNX(config.Ctx, "otp:cooldown:"+email, 1, otpResendCooldown).Result()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	refreshToken, err := utils.GenerateRefreshToken(user.ID.String())
+	if !ok {
+		return ErrOTPCooldown
+	}
+
+	otp, err := utils.GenerateOTP(otpLength)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	tokenModel := &models.Token{
-		UserID:    user.ID,
-		ExpiredAt: time.Now().Add(7 * 24 * time.Hour),
+	// penghitung percobaan sengaja tidak direset agar kirim ulang OTP tidak membuka percobaan baru
+	if err := config.RedisClient.Set(config.Ctx, "otp:"+email, otp, otpTTL).Err(); err != nil {
+		return err
 	}
-	if err := s.repo.StoreRefreshToken(tokenModel); err != nil {
+
+	htmlBody, err := utils.RenderEmailTemplate("otp.html", map[string]any{
+		"Email":            email,
+		"Code":             otp,
+		"ExpiresInMinutes": int(otpTTL.Minutes()),
+	})
+	if err != nil {
+		return err
+	}
+	subject := "Your verification code"
+	body := fmt.Sprintf("Your verification code is %s. It expires in %d minutes.", otp, int(otpTTL.Minutes()))
+
+	if err := utils.SendEmail(subject, email, body, htmlBody); err != nil {
+		config.RedisClient.Del(config.Ctx, "otp:"+email)
+		return errors.New("failed to send email")
+	}
+
+	return nil
+}
+
+// VerifyOTPCode mencocokkan OTP dan menerbitkan token verifikasi email yang wajib dikirim saat register.
+// Setelah otpMaxAttempts kali salah, email dikunci selama otpLockDuration.
+func (s *authService) VerifyOTPCode(req *dto.VerifyOTPRequest) (*dto.VerifyOTPResponse, error) {
+	email := normalizeEmail(req.Email)
+
+	if locked, _ := config.RedisClient.Exists(config.Ctx, "otp:lock:"+email).Result(); locked > 0 {
+		return nil, ErrOTPLocked
+	}
+
+	savedOTP, err := config.RedisClient.Get(config.Ctx, "otp:"+email).Result()
+	if err != nil {
+		return nil, errors.New("otp expired or invalid")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(savedOTP), []byte(req.OTP)) != 1 {
+		attempts, err := config.RedisClient.Incr(config.Ctx, "otp:attempts:"+email).Result()
+		if err != nil {
+			return nil, err
+		}
+		if attempts == 1 {
+			config.RedisClient.Expire(config.Ctx, "otp:attempts:"+email, otpLockDuration)
+		}
+		if attempts >= otpMaxAttempts {
+			config.RedisClient.Set(config.Ctx, "otp:lock:"+email, 1, otpLockDuration)
+			config.RedisClient.Del(config.Ctx, "otp:"+email, "otp:attempts:"+email)
+			return nil, ErrOTPLocked
+		}
+		return nil, fmt.Errorf("invalid OTP code, %d attempts left", otpMaxAttempts-attempts)
+	}
+
+	config.RedisClient.Del(config.Ctx, "otp:"+email, "otp:attempts:"+email)
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.RedisClient.Set(config.Ctx, "otp:verified:"+email, utils.HashToken(token), verifiedEmailTTL).Err(); err != nil {
 		return nil, err
 	}
 
-	return &dto.AuthResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+	return &dto.VerifyOTPResponse{
+		VerificationToken: token,
+		ExpiresIn:         int(verifiedEmailTTL.Seconds()),
 	}, nil
-
 }
 
-func (s *authService) SendOTP(req *dto.SendOTPRequest) error {
-	if _, err := s.repo.GetUserByEmail(req.Email); err == nil {
-		return errors.New("email already registered")
+// ForgotPassword selalu sukses dari sisi client (tidak membocorkan email yang terdaftar).
+// Link reset berisi token acak, yang disimpan hanya hash-nya.
+func (s *authService) ForgotPassword(req *dto.ForgotPasswordRequest) error {
+	email := normalizeEmail(req.Email)
+	user, err := s.repo.GetUserByEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	ok, err := config.RedisClient.SetNX(config.Ctx, "password-reset:cooldown:"+email, 1, passwordResetCooldown).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
 	}
 
-	otp := utils.GenerateOTP(6)
-	subject := "one time password (OTP) XXXXXX"
-	body := fmt.Sprintf("your OTP code is %s", otp)
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return err
+	}
 
-	if err := utils.SendEmail(subject, req.Email, otp, body); err != nil {
-		return errors.New("failed to send email")
+	reset := &models.PasswordReset{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	}
+	if err := s.repo.CreatePasswordReset(reset); err != nil {
+		return err
+	}
+
+	resetURL := fmt.Sprintf("%s/reset-password?token=%s", strings.TrimRight(os.Getenv("CLIENT_URL"), "/"), url.QueryEscape(token))
+	htmlBody, err := utils.RenderEmailTemplate("reset_password.html", map[string]any{
+		"Fullname":         user.Fullname,
+		"ResetURL":         resetURL,
+		"ExpiresInMinutes": int(passwordResetTTL.Minutes()),
+	})
+	if err != nil {
+		return err
+	}
+	body := fmt.Sprintf("Open this link to reset your password: %s. It expires in %d minutes.", resetURL, int(passwordResetTTL.Minutes()))
+
+	if err := utils.SendEmail("Reset your password", user.Email, body, htmlBody); err != nil {
+		log.Printf("failed to send password reset email to %s: %v", user.Email, err)
 	}
+	return nil
+}
 
-	err := config.RedisClient.Set(config.Ctx, "otp:"+req.Email, otp, 5*time.Minute).Err()
-	return err
+func (s *authService) ResetPassword(req *dto.ResetPasswordRequest, meta dto.RequestMeta) error {
+	reset, err := s.repo.GetPasswordResetByHash(utils.HashToken(req.Token))
+	if err != nil || reset.UsedAt != nil || reset.ExpiresAt.Before(time.Now()) {
+		return errors.New("reset token is invalid or expired")
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
 
+	if err := s.repo.ResetPassword(reset.ID.String(), reset.UserID.String(), hashedPassword); err != nil {
+		return err
+	}
+	s.logSecurityEvent(models.SecurityEvent{UserID: &reset.UserID, Event: SecurityEventPasswordReset}, meta)
+	return nil
 }
 
-func (s *authService) VerifyOTPCode(req *dto.VerifyOTPRequest) error {
-	savedOTP, err := config.RedisClient.Get(config.Ctx, "otp:"+req.Email).Result()
+func (s *authService) ChangePassword(userID string, req *dto.ChangePasswordRequest, meta dto.RequestMeta) error {
+	user, err := s.repo.GetUserByID(userID)
 	if err != nil {
-		return errors.New("otp expired or invalid")
+		return errors.New("user not found")
 	}
 
-	if savedOTP != req.OTP {
-		return errors.New("invalid OTP code")
+	if !utils.CheckPasswordHash(req.CurrentPassword, user.Password) {
+		return errors.New("current password is incorrect")
+	}
+	if req.CurrentPassword == req.NewPassword {
+		return errors.New("new password must be different from the current password")
 	}
 
-	config.RedisClient.Del(config.Ctx, "otp:"+req.Email)
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdatePassword(userID, hashedPassword); err != nil {
+		return err
+	}
+	s.logSecurityEvent(models.SecurityEvent{UserID: &user.ID, Email: user.Email, Event: SecurityEventPasswordChanged}, meta)
 	return nil
 }
 
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 func (s *authService) GetUserInfo(userID string) (*dto.UserInfoResponse, error) {
 	user, err := s.repo.GetUserByID(userID)
 	if err != nil {
@@ -150,18 +395,23 @@ func (s *authService) GetUserInfo(userID string) (*dto.UserInfoResponse, error)
 	}, nil
 }
 
-func (s *authService) RefreshUserToken(refreshToken string) (*dto.AuthResponse, error) {
-
-	_, err := utils.DecodeRefreshToken(refreshToken)
-	if err != nil {
+// RefreshUserToken merotasi refresh token. Token yang sudah pernah dirotasi/dicabut lalu dipakai lagi
+// menandakan token bocor, sehingga seluruh family (sesi) dicabut.
+func (s *authService) RefreshUserToken(refreshToken string, meta dto.RequestMeta) (*dto.AuthResponse, error) {
+	if _, err := utils.DecodeRefreshToken(refreshToken); err != nil {
 		return nil, errors.New("unauthorized ! invalid credential")
 	}
 
-	token, err := s.repo.FindRefreshToken(refreshToken)
+	token, err := s.repo.FindRefreshTokenByHash(utils.HashToken(refreshToken))
 	if err != nil {
 		return nil, errors.New("unauthorized ! invalid refresh token")
 	}
 
+	if token.DeletedAt.Valid {
+		s.revokeReusedFamily(token, meta)
+		return nil, ErrRefreshTokenReused
+	}
+
 	if token.ExpiredAt.Before(time.Now()) {
 		return nil, errors.New("refresh token expired")
 	}
@@ -171,30 +421,90 @@ func (s *authService) RefreshUserToken(refreshToken string) (*dto.AuthResponse,
 		return nil, errors.New("user not found")
 	}
 
-	newAccessToken, err := utils.GenerateAccessToken(user.ID.String())
+	accessToken, newRefreshToken, newToken, err := newSessionTokens(user, token.FamilyID, token.SessionStartedAt, meta)
 	if err != nil {
 		return nil, err
 	}
-	newRefreshToken, err := utils.GenerateRefreshToken(user.ID.String())
-	if err != nil {
+
+	if err := s.repo.RotateRefreshToken(token.ID.String(), newToken); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// token yang sama sudah dirotasi oleh request lain
+			s.revokeReusedFamily(token, meta)
+			return nil, ErrRefreshTokenReused
+		}
 		return nil, err
 	}
 
-	if err := s.repo.DeleteRefreshToken(refreshToken); err != nil {
-		return nil, err
+	return &dto.AuthResponse{
+		AccessToken:  accessToken,
+		RefreshToken: newRefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(utils.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+func (s *authService) revokeReusedFamily(token *models.Token, meta dto.RequestMeta) {
+	s.logSecurityEvent(models.SecurityEvent{
+		UserID: &token.UserID,
+		Event:  SecurityEventRefreshReused,
+		Detail: "session " + token.FamilyID.String() + " revoked",
+	}, meta)
+	if err := s.repo.RevokeTokenFamily(token.FamilyID.String()); err != nil {
+		log.Printf("failed to revoke session %s: %v", token.FamilyID, err)
 	}
+}
 
-	newToken := &models.Token{
-		UserID:    user.ID,
-		Token:     newRefreshToken,
-		ExpiredAt: time.Now().Add(7 * 24 * time.Hour),
+// Logout mencabut sesi milik refresh token yang sedang dipakai.
+func (s *authService) Logout(refreshToken string) error {
+	token, err := s.repo.FindRefreshTokenByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		return nil
 	}
-	if err := s.repo.StoreRefreshToken(newToken); err != nil {
+	return s.repo.RevokeTokenFamily(token.FamilyID.String())
+}
+
+func (s *authService) LogoutAll(userID string) error {
+	return s.repo.RevokeAllUserTokens(userID)
+}
+
+// GetSessions menampilkan sesi aktif user, sesi dari refresh token saat ini ditandai current.
+func (s *authService) GetSessions(userID, currentRefreshToken string) ([]dto.SessionResponse, error) {
+	tokens, err := s.repo.GetActiveSessions(userID)
+	if err != nil {
 		return nil, err
 	}
 
-	return &dto.AuthResponse{
-		AccessToken:  newAccessToken,
-		RefreshToken: newRefreshToken,
-	}, nil
+	currentHash := ""
+	if currentRefreshToken != "" {
+		currentHash = utils.HashToken(currentRefreshToken)
+	}
+
+	sessions := make([]dto.SessionResponse, 0, len(tokens))
+	for _, t := range tokens {
+		sessions = append(sessions, dto.SessionResponse{
+			ID:         t.FamilyID.String(),
+			UserAgent:  t.UserAgent,
+			IPAddress:  t.IPAddress,
+			StartedAt:  t.SessionStartedAt.Format("2006-01-02 15:04:05"),
+			LastUsedAt: t.LastUsedAt.Format("2006-01-02 15:04:05"),
+			ExpiresAt:  t.ExpiredAt.Format("2006-01-02 15:04:05"),
+			Current:    t.TokenHash == currentHash,
+		})
+	}
+	return sessions, nil
+}
+
+func (s *authService) RevokeSession(userID, sessionID string) error {
+	revoked, err := s.repo.RevokeUserSession(userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return errors.New("session not found")
+	}
+	return nil
+}
+
+func (s *authService) PurgeExpiredTokens() (int64, error) {
+	return s.repo.DeleteExpiredTokens(time.Now())
 }
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"server/internal/dto"
+	"server/internal/models"
+	"server/internal/utils"
+	"strings"
+	"time"
+)
+
+var (
+	ErrFormNotAccepting    = errors.New("form is not accepting submissions")
+	ErrInviteeLimitReached = errors.New("invitee limit reached for this account")
+)
+
+const (
+	defaultClosingSoonWindow = 24 * time.Hour
+	scheduleTimeLayout       = "2006-01-02 15:04 MST"
+	maxInviteesPerOwner      = 2000 // total invitee di semua form milik satu user
+)
+
+// ApplyFormSchedules membuka form yang StartAt-nya sudah lewat dan menutup form yang EndAt-nya
+// sudah lewat, lalu mengirim email ke pemilik form untuk setiap perubahan.
+func (s *formService) ApplyFormSchedules() (int64, error) {
+	now := time.Now()
+	var changed int64
+
+	toOpen, err := s.repo.FindFormsDueToOpen(now)
+	if err != nil {
+		return 0, err
+	}
+	for i := range toOpen {
+		form := &toOpen[i]
+		if err := s.repo.MarkFormOpened(form.ID.String(), now); err != nil {
+			log.Printf("schedule: failed to open form %s: %v", form.ID, err)
+			continue
+		}
+		s.recordScheduleChange(form, "form.opened", true)
+		s.notifyOwner(form, true, now)
+		changed++
+	}
+
+	toClose, err := s.repo.FindFormsDueToClose(now)
+	if err != nil {
+		return changed, err
+	}
+	for i := range toClose {
+		form := &toClose[i]
+		if err := s.repo.MarkFormClosed(form.ID.String(), now); err != nil {
+			log.Printf("schedule: failed to close form %s: %v", form.ID, err)
+			continue
+		}
+		s.recordScheduleChange(form, "form.closed", false)
+		s.notifyOwner(form, false, now)
+		changed++
+	}
+
+	return changed, nil
+}
+
+// SendClosingReminders mengirim pengingat ke invitee yang belum mengisi form saat form masuk
+// jendela ReminderHoursBefore sebelum ditutup. Setiap invitee hanya diingatkan sekali per jadwal.
+// Form dengan AnonymousResponses dilewati: email responden tidak disimpan sehingga invitee yang
+// sudah mengisi tidak bisa dibedakan dari yang belum.
+func (s *formService) SendClosingReminders() (int64, error) {
+	now := time.Now()
+	forms, err := s.repo.FindFormsDueForReminder(now)
+	if err != nil {
+		return 0, err
+	}
+
+	var sent int64
+	for i := range forms {
+		form := &forms[i]
+		invitees, err := s.repo.GetPendingInvitees(form.ID.String())
+		if err != nil {
+			log.Printf("reminder: failed to load invitees for form %s: %v", form.ID, err)
+			continue
+		}
+
+		closesAt := form.Setting.EndAt.Format(scheduleTimeLayout)
+		formURL := fmt.Sprintf("%s/forms/%s", strings.TrimRight(os.Getenv("CLIENT_URL"), "/"), form.ID)
+		htmlBody, err := utils.RenderEmailTemplate("form_reminder.html", map[string]any{
+			"FormTitle": form.Title,
+			"ClosesAt":  closesAt,
+			"FormURL":   formURL,
+		})
+		if err != nil {
+			log.Printf("reminder: failed to render email for form %s: %v", form.ID, err)
+			continue
+		}
+		body := fmt.Sprintf("%s closes at %s and we have not received your response yet: %s", form.Title, closesAt, formURL)
+
+		for _, invitee := range invitees {
+			if err := utils.SendEmail(form.Title+" closes soon", invitee.Email, body, htmlBody); err != nil {
+				log.Printf("reminder: failed to send to %s: %v", invitee.Email, err)
+				continue
+			}
+			if err := s.repo.MarkInviteeReminded(invitee.ID, now); err != nil {
+				log.Printf("reminder: failed to mark invitee %d: %v", invitee.ID, err)
+			}
+			sent++
+		}
+
+		if err := s.repo.MarkFormReminded(form.ID.String(), now); err != nil {
+			log.Printf("reminder: failed to mark form %s: %v", form.ID, err)
+		}
+	}
+	return sent, nil
+}
+
+func (s *formService) notifyOwner(form *models.Form, opened bool, at time.Time) {
+	owner, err := s.repo.GetFormOwner(form.ID.String())
+	if err != nil {
+		log.Printf("schedule: owner of form %s not found: %v", form.ID, err)
+		return
+	}
+
+	status := "closed"
+	if opened {
+		status = "open"
+	}
+	closesAt := ""
+	if opened && form.Setting.EndAt != nil {
+		closesAt = form.Setting.EndAt.Format(scheduleTimeLayout)
+	}
+
+	htmlBody, err := utils.RenderEmailTemplate("form_status.html", map[string]any{
+		"Fullname":  owner.Fullname,
+		"FormTitle": form.Title,
+		"Status":    status,
+		"Opened":    opened,
+		"At":        at.Format(scheduleTimeLayout),
+		"ClosesAt":  closesAt,
+	})
+	if err != nil {
+		log.Printf("schedule: failed to render email for form %s: %v", form.ID, err)
+		return
+	}
+	body := fmt.Sprintf("Your form %s is now %s.", form.Title, status)
+
+	if err := utils.SendEmail(fmt.Sprintf("Your form %q is now %s", form.Title, status), owner.Email, body, htmlBody); err != nil {
+		log.Printf("schedule: failed to email owner of form %s: %v", form.ID, err)
+	}
+}
+
+func (s *formService) recordScheduleChange(form *models.Form, action string, active bool) {
+	s.audit.Record(dto.RequestMeta{}, AuditEntry{
+		Action: action, EntityType: AuditEntityForm, EntityID: form.ID.String(), FormID: form.ID.String(),
+		Before: map[string]any{"isActive": form.IsActive}, After: map[string]any{"isActive": active},
+	})
+}
+
+// scheduleActivation menyalin penanda jadwal dari setting lama dan mengosongkannya bila StartAt/EndAt
+// berubah, sehingga job memproses jadwal baru. Mengembalikan status aktif yang harus langsung
+// diterapkan (nil = biarkan job yang menentukan).
+func scheduleActivation(before, setting *models.FormSetting, now time.Time) (active *bool, endChanged bool) {
+	setting.OpenedAt, setting.ClosedAt, setting.RemindedAt = before.OpenedAt, before.ClosedAt, before.RemindedAt
+
+	if !sameTime(before.StartAt, setting.StartAt) {
+		setting.OpenedAt = nil
+	}
+	endChanged = !sameTime(before.EndAt, setting.EndAt)
+	if endChanged {
+		setting.ClosedAt, setting.RemindedAt = nil, nil
+	}
+
+	switch {
+	case setting.StartAt != nil && setting.StartAt.After(now):
+		// belum waktunya dibuka
+		inactive := false
+		return &inactive, endChanged
+	case endChanged && before.ClosedAt != nil && (setting.EndAt == nil || setting.EndAt.After(now)):
+		// jadwal tutup diperpanjang setelah form ditutup otomatis
+		reopened := true
+		return &reopened, endChanged
+	}
+	return nil, endChanged
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+// acceptingSubmissions tidak menunggu job: jadwal yang sudah lewat tetap ditolak meski IsActive
+// belum sempat diperbarui.
+func acceptingSubmissions(form *models.Form, setting *models.FormSetting, now time.Time) bool {
+	if !form.IsActive {
+		return false
+	}
+	if setting.StartAt != nil && setting.StartAt.After(now) {
+		return false
+	}
+	return setting.EndAt == nil || setting.EndAt.After(now)
+}
+
+// closingSoon menentukan banner "segera ditutup" pada form publik.
+func closingSoon(setting *models.FormSetting, now time.Time) bool {
+	if setting.EndAt == nil || !setting.EndAt.After(now) {
+		return false
+	}
+	window := defaultClosingSoonWindow
+	if setting.ReminderHoursBefore > 0 {
+		window = time.Duration(setting.ReminderHoursBefore) * time.Hour
+	}
+	return setting.EndAt.Sub(now) <= window
+}
+
+// AddInvitees menambahkan invitee ke form milik user. Total invitee per owner dibatasi karena setiap
+// invitee menerima email pengingat dari server.
+func (s *formService) AddInvitees(userID, formID string, req *dto.AddInviteesRequest) (int64, error) {
+	form, err := s.repo.FindByID(formID)
+	if err != nil || form.UserID.String() != userID {
+		return 0, errors.New("form not found")
+	}
+	total, err := s.repo.CountInviteesByOwner(userID)
+	if err != nil {
+		return 0, err
+	}
+	// email duplikat ikut dihitung sehingga batas tidak pernah terlampaui
+	if total+int64(len(req.Emails)) > maxInviteesPerOwner {
+		return 0, ErrInviteeLimitReached
+	}
+	return s.repo.AddInvitees(formID, req.Emails)
+}
+
+func (s *formService) GetInvitees(userID, formID string) ([]dto.InviteeResponse, error) {
+	form, err := s.repo.FindByID(formID)
+	if err != nil || form.UserID.String() != userID {
+		return nil, errors.New("form not found")
+	}
+	invitees, err := s.repo.GetInvitees(formID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.InviteeResponse, 0, len(invitees))
+	for _, inv := range invitees {
+		res := dto.InviteeResponse{
+			ID:        inv.ID,
+			Email:     inv.Email,
+			CreatedAt: inv.CreatedAt.Format("2006-01-02 15:04:05"),
+		}
+		if inv.RemindedAt != nil {
+			res.RemindedAt = inv.RemindedAt.Format("2006-01-02 15:04:05")
+		}
+		result = append(result, res)
+	}
+	return result, nil
+}
+
+func (s *formService) RemoveInvitee(userID, formID string, id uint) error {
+	form, err := s.repo.FindByID(formID)
+	if err != nil || form.UserID.String() != userID {
+		return errors.New("form not found")
+	}
+	if err := s.repo.DeleteInvitee(formID, id); err != nil {
+		return errors.New("invitee not found")
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"server/internal/dto"
+	"server/internal/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var scheduleNow = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func nowPlus(d time.Duration) *time.Time {
+	t := scheduleNow.Add(d)
+	return &t
+}
+
+func TestClosingSoon(t *testing.T) {
+	tests := []struct {
+		name    string
+		setting models.FormSetting
+		want    bool
+	}{
+		{"no end date", models.FormSetting{}, false},
+		{"already closed", models.FormSetting{EndAt: nowPlus(-time.Hour)}, false},
+		{"closes exactly now", models.FormSetting{EndAt: nowPlus(0)}, false},
+		{"inside default window", models.FormSetting{EndAt: nowPlus(23 * time.Hour)}, true},
+		{"edge of default window", models.FormSetting{EndAt: nowPlus(24 * time.Hour)}, true},
+		{"outside default window", models.FormSetting{EndAt: nowPlus(25 * time.Hour)}, false},
+		{"custom window from reminder hours", models.FormSetting{EndAt: nowPlus(47 * time.Hour), ReminderHoursBefore: 48}, true},
+		{"outside custom window", models.FormSetting{EndAt: nowPlus(3 * time.Hour), ReminderHoursBefore: 2}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := closingSoon(&tt.setting, scheduleNow); got != tt.want {
+				t.Errorf("closingSoon = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAcceptingSubmissions(t *testing.T) {
+	tests := []struct {
+		name    string
+		active  bool
+		setting models.FormSetting
+		want    bool
+	}{
+		{"inactive form", false, models.FormSetting{}, false},
+		{"no schedule", true, models.FormSetting{}, true},
+		{"not open yet", true, models.FormSetting{StartAt: nowPlus(time.Hour)}, false},
+		{"opened", true, models.FormSetting{StartAt: nowPlus(-time.Hour)}, true},
+		{"end passed before the job ran", true, models.FormSetting{EndAt: nowPlus(-time.Minute)}, false},
+		{"inside schedule", true, models.FormSetting{StartAt: nowPlus(-time.Hour), EndAt: nowPlus(time.Hour)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := &models.Form{IsActive: tt.active}
+			if got := acceptingSubmissions(form, &tt.setting, scheduleNow); got != tt.want {
+				t.Errorf("acceptingSubmissions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleActivation(t *testing.T) {
+	openedAt, closedAt, remindedAt := nowPlus(-48*time.Hour), nowPlus(-time.Hour), nowPlus(-2*time.Hour)
+	base := models.FormSetting{
+		StartAt: nowPlus(-48 * time.Hour), EndAt: nowPlus(-time.Hour),
+		OpenedAt: openedAt, ClosedAt: closedAt, RemindedAt: remindedAt,
+	}
+
+	tests := []struct {
+		name           string
+		startAt        *time.Time
+		endAt          *time.Time
+		wantActive     *bool
+		wantEndChanged bool
+		wantOpened     bool
+		wantClosed     bool
+	}{
+		{
+			name: "unchanged schedule keeps markers", startAt: base.StartAt, endAt: base.EndAt,
+			wantOpened: true, wantClosed: true,
+		},
+		{
+			name: "start moved to the future deactivates", startAt: nowPlus(time.Hour), endAt: base.EndAt,
+			wantActive: boolPtr(false), wantClosed: true,
+		},
+		{
+			name: "end extended after auto close reopens", startAt: base.StartAt, endAt: nowPlus(time.Hour),
+			wantActive: boolPtr(true), wantEndChanged: true, wantOpened: true,
+		},
+		{
+			name: "end removed after auto close reopens", startAt: base.StartAt, endAt: nil,
+			wantActive: boolPtr(true), wantEndChanged: true, wantOpened: true,
+		},
+		{
+			name: "end moved but still in the past", startAt: base.StartAt, endAt: nowPlus(-30 * time.Minute),
+			wantEndChanged: true, wantOpened: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := base
+			setting := &models.FormSetting{StartAt: tt.startAt, EndAt: tt.endAt}
+
+			active, endChanged := scheduleActivation(&before, setting, scheduleNow)
+			if (active == nil) != (tt.wantActive == nil) || (active != nil && *active != *tt.wantActive) {
+				t.Errorf("active = %v, want %v", fmtBoolPtr(active), fmtBoolPtr(tt.wantActive))
+			}
+			if endChanged != tt.wantEndChanged {
+				t.Errorf("endChanged = %v, want %v", endChanged, tt.wantEndChanged)
+			}
+			if (setting.OpenedAt != nil) != tt.wantOpened {
+				t.Errorf("openedAt kept = %v, want %v", setting.OpenedAt != nil, tt.wantOpened)
+			}
+			if (setting.ClosedAt != nil) != tt.wantClosed || (setting.RemindedAt != nil) != tt.wantClosed {
+				t.Errorf("closed/reminded kept = %v/%v, want %v", setting.ClosedAt != nil, setting.RemindedAt != nil, tt.wantClosed)
+			}
+		})
+	}
+}
+
+func TestUpdateFormSettingsResetsInviteeReminders(t *testing.T) {
+	owner, formID := uuid.New(), uuid.New()
+	end, honeypot := nowPlus(time.Hour).Format(time.RFC3339), true
+
+	tests := []struct {
+		name       string
+		req        dto.UpdateFormSettingRequest
+		wantResets int
+	}{
+		{"end changed", dto.UpdateFormSettingRequest{EndAt: &end}, 1},
+		{"end cleared", dto.UpdateFormSettingRequest{Clear: []string{"endAt"}}, 1},
+		{"end not sent", dto.UpdateFormSettingRequest{HoneypotEnabled: &honeypot}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeFormRepo{
+				forms:   map[string]*models.Form{formID.String(): {ID: formID, UserID: owner}},
+				setting: &models.FormSetting{EndAt: nowPlus(2 * time.Hour)},
+			}
+			s := &formService{repo: repo, audit: nopAudit{}}
+
+			if err := s.UpdateFormSettings(owner.String(), formID.String(), &tt.req, dto.RequestMeta{}); err != nil {
+				t.Fatalf("UpdateFormSettings() error = %v", err)
+			}
+			if repo.resets != tt.wantResets {
+				t.Errorf("invitee reminders reset %d times, want %d", repo.resets, tt.wantResets)
+			}
+		})
+	}
+}
+
+func boolPtr(v bool) *bool { return &v }
+
+func fmtBoolPtr(v *bool) string {
+	if v == nil {
+		return "nil"
+	}
+	if *v {
+		return "true"
+	}
+	return "false"
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"server/internal/dto"
 	"server/internal/models"
 	"server/internal/repositories"
@@ -10,31 +11,48 @@ import (
 )
 
 type FormService interface {
-	CreateForm(userID string, req *dto.CreateFormRequest) error
+	CreateForm(userID string, req *dto.CreateFormRequest, meta dto.RequestMeta) error
 	GetAllForms(userID string) ([]dto.FormResponse, error)
 	GetFormDetail(formID string) (*dto.FormDetailResponse, error)
-	AddFormSection(req *dto.AddSectionRequest) (*dto.SectionResponse, error)
-	UpdateFormSettings(formID string, req *dto.UpdateFormSettingRequest) error
+	AddFormSection(userID string, req *dto.AddSectionRequest, meta dto.RequestMeta) (*dto.SectionResponse, error)
+	UpdateFormSettings(userID, formID string, req *dto.UpdateFormSettingRequest, meta dto.RequestMeta) error
 	GetFormSettings(formID string) (*dto.FormSettingResponse, error)
 	GetFormQuestion(formID string) ([]dto.QuestionResponse, error)
-	DeleteFormSections(sectionID string) error
-	UpdateFormSections(req *dto.UpdateSectionRequest) error
+	DeleteFormSections(userID, sectionID string, meta dto.RequestMeta) error
+	UpdateFormSections(userID string, req *dto.UpdateSectionRequest, meta dto.RequestMeta) error
 	GetFormSections(formID string) ([]dto.SectionResponse, error)
-	DeleteQuestion(id string) error
+	DeleteQuestion(userID, id string, meta dto.RequestMeta) error
 
-	AddFormQuestion(req *dto.AddQuestionRequest) error
-	UpdateQuestion(req *dto.UpdateQuestionRequest) error
+	AddFormQuestion(userID string, req *dto.AddQuestionRequest, meta dto.RequestMeta) error
+	UpdateQuestion(userID string, req *dto.UpdateQuestionRequest, meta dto.RequestMeta) error
+
+	PublishForm(userID, formID string, meta dto.RequestMeta) (*dto.FormVersionResponse, error)
+	GetFormVersions(userID, formID string) ([]dto.FormVersionResponse, error)
+	GetFormVersion(userID, formID string, version int) (*dto.FormVersionResponse, error)
+	DiffFormVersions(userID, formID string, from, to int) (*dto.FormVersionDiffResponse, error)
+
+	ApplyFormSchedules() (int64, error)
+	SendClosingReminders() (int64, error)
+	AddInvitees(userID, formID string, req *dto.AddInviteesRequest) (int64, error)
+	GetInvitees(userID, formID string) ([]dto.InviteeResponse, error)
+	RemoveInvitee(userID, formID string, id uint) error
 }
 
+var (
+	ErrSectionNotFound  = errors.New("section not found")
+	ErrQuestionNotFound = errors.New("question not found")
+)
+
 type formService struct {
-	repo repositories.FormRepository
+	repo  repositories.FormRepository
+	audit AuditService
 }
 
-func NewFormService(repo repositories.FormRepository) FormService {
-	return &formService{repo}
+func NewFormService(repo repositories.FormRepository, audit AuditService) FormService {
+	return &formService{repo, audit}
 }
 
-func (s *formService) CreateForm(userID string, req *dto.CreateFormRequest) error {
+func (s *formService) CreateForm(userID string, req *dto.CreateFormRequest, meta dto.RequestMeta) error {
 	form := &models.Form{
 		ID:          uuid.New(),
 		UserID:      uuid.MustParse(userID),
@@ -44,7 +62,14 @@ func (s *formService) CreateForm(userID string, req *dto.CreateFormRequest) erro
 		IsActive:    true,
 		Duration:    req.Duration,
 	}
-	return s.repo.Create(form)
+	if err := s.repo.Create(form); err != nil {
+		return err
+	}
+	s.audit.Record(meta, AuditEntry{
+		Action: "form.created", EntityType: AuditEntityForm, EntityID: form.ID.String(),
+		FormID: form.ID.String(), After: form,
+	})
+	return nil
 }
 
 func (s *formService) GetAllForms(userID string) ([]dto.FormResponse, error) {
@@ -108,26 +133,169 @@ func (s *formService) GetFormSettings(formID string) (*dto.FormSettingResponse,
 		MaxSubmissions:     setting.MaxSubmissions,
 		StartAt:            start,
 		EndAt:              end,
+
+		HoneypotEnabled:       setting.HoneypotEnabled,
+		MinFillSeconds:        setting.MinFillSeconds,
+		RequireRenderToken:    setting.RequireRenderToken,
+		ProofOfWorkDifficulty: setting.ProofOfWorkDifficulty,
+
+		RetentionDays:      setting.RetentionDays,
+		RetentionAction:    setting.RetentionAction,
+		AnonymousResponses: setting.AnonymousResponses,
+
+		ReminderHoursBefore: setting.ReminderHoursBefore,
+		OpenedAt:            formatTimePointer(setting.OpenedAt),
+		ClosedAt:            formatTimePointer(setting.ClosedAt),
 	}, nil
 }
 
-func (s *formService) UpdateFormSettings(formID string, req *dto.UpdateFormSettingRequest) error {
-	startAt, endAt := parseTimePointer(req.StartAt), parseTimePointer(req.EndAt)
+func (s *formService) UpdateFormSettings(userID, formID string, req *dto.UpdateFormSettingRequest, meta dto.RequestMeta) error {
+	if err := s.requireFormOwner(userID, formID); err != nil {
+		return err
+	}
+	before, err := s.repo.GetFormSetting(formID)
+	if err != nil {
+		return errors.New("form setting not found")
+	}
+
+	setting, columns := applySettingUpdates(before, req)
+	active, endChanged := scheduleActivation(before, setting, time.Now())
+	if !sameTime(before.OpenedAt, setting.OpenedAt) {
+		columns["opened_at"] = setting.OpenedAt
+	}
+	if endChanged {
+		columns["closed_at"], columns["reminded_at"] = setting.ClosedAt, setting.RemindedAt
+	}
+	if err := s.repo.UpdateFormSetting(formID, columns); err != nil {
+		return err
+	}
+	if endChanged {
+		if err := s.repo.ResetInviteeReminders(formID); err != nil {
+			return err
+		}
+	}
+	if active != nil {
+		if err := s.repo.SetFormActive(formID, *active); err != nil {
+			return err
+		}
+	}
 
-	setting := &models.FormSetting{
-		FormID:             uuid.MustParse(formID),
-		ShowResult:         req.ShowResult,
-		MultipleSubmission: req.MultipleSubmission,
-		PassingGrade:       req.PassingGrade,
-		Grading:            req.Grading,
-		MaxSubmissions:     req.MaxSubmissions,
-		StartAt:            startAt,
-		EndAt:              endAt,
+	after, err := s.repo.GetFormSetting(formID)
+	if err != nil {
+		after = setting
 	}
-	return s.repo.UpdateFormSetting(setting)
+	s.audit.Record(meta, AuditEntry{
+		Action: "form.settings_updated", EntityType: AuditEntityFormSetting, EntityID: formID,
+		FormID: formID, Before: before, After: after,
+	})
+	return nil
 }
 
-func (s *formService) AddFormSection(req *dto.AddSectionRequest) (*dto.SectionResponse, error) {
+// applySettingUpdates menggabungkan field yang dikirim ke salinan setting lama dan mengembalikan
+// kolom yang perlu ditulis, sehingga field yang tidak dikirim tidak ikut di-reset. Field yang ada
+// di req.Clear dikosongkan (null), nilai yang dikirim bersamaan untuk field itu diabaikan.
+func applySettingUpdates(before *models.FormSetting, req *dto.UpdateFormSettingRequest) (*models.FormSetting, map[string]any) {
+	setting := *before
+	columns := map[string]any{}
+
+	cleared := make(map[string]bool, len(req.Clear))
+	for _, field := range req.Clear {
+		cleared[field] = true
+	}
+	switch {
+	case cleared["passingGrade"]:
+		setting.PassingGrade = nil
+		columns["passing_grade"] = nil
+	case req.PassingGrade != nil:
+		setting.PassingGrade = req.PassingGrade
+		columns["passing_grade"] = setting.PassingGrade
+	}
+	switch {
+	case cleared["maxSubmissions"]:
+		setting.MaxSubmissions = nil
+		columns["max_submissions"] = nil
+	case req.MaxSubmissions != nil:
+		setting.MaxSubmissions = req.MaxSubmissions
+		columns["max_submissions"] = setting.MaxSubmissions
+	}
+	switch {
+	case cleared["startAt"]:
+		setting.StartAt = nil
+		columns["start_at"] = nil
+	case req.StartAt != nil:
+		setting.StartAt = parseTimePointer(req.StartAt)
+		columns["start_at"] = setting.StartAt
+	}
+	switch {
+	case cleared["endAt"]:
+		setting.EndAt = nil
+		columns["end_at"] = nil
+	case req.EndAt != nil:
+		setting.EndAt = parseTimePointer(req.EndAt)
+		columns["end_at"] = setting.EndAt
+	}
+
+	if req.ShowResult != nil {
+		setting.ShowResult = *req.ShowResult
+		columns["show_result"] = setting.ShowResult
+	}
+	if req.MultipleSubmission != nil {
+		setting.MultipleSubmission = *req.MultipleSubmission
+		columns["multiple_submission"] = setting.MultipleSubmission
+	}
+	if req.Grading != nil {
+		setting.Grading = *req.Grading
+		columns["grading"] = setting.Grading
+	}
+	if req.HoneypotEnabled != nil {
+		setting.HoneypotEnabled = *req.HoneypotEnabled
+		columns["honeypot_enabled"] = setting.HoneypotEnabled
+	}
+	if req.MinFillSeconds != nil {
+		setting.MinFillSeconds = *req.MinFillSeconds
+		columns["min_fill_seconds"] = setting.MinFillSeconds
+	}
+	if req.RequireRenderToken != nil {
+		setting.RequireRenderToken = *req.RequireRenderToken
+		columns["require_render_token"] = setting.RequireRenderToken
+	}
+	if req.ProofOfWorkDifficulty != nil {
+		setting.ProofOfWorkDifficulty = *req.ProofOfWorkDifficulty
+		columns["proof_of_work_difficulty"] = setting.ProofOfWorkDifficulty
+	}
+	if req.RetentionDays != nil {
+		setting.RetentionDays = *req.RetentionDays
+		columns["retention_days"] = setting.RetentionDays
+	}
+	if req.RetentionAction != nil {
+		setting.RetentionAction = *req.RetentionAction
+		columns["retention_action"] = setting.RetentionAction
+	}
+	if req.AnonymousResponses != nil {
+		setting.AnonymousResponses = *req.AnonymousResponses
+		columns["anonymous_responses"] = setting.AnonymousResponses
+	}
+	if req.ReminderHoursBefore != nil {
+		setting.ReminderHoursBefore = *req.ReminderHoursBefore
+		columns["reminder_hours_before"] = setting.ReminderHoursBefore
+	}
+	return &setting, columns
+}
+
+// requireFormOwner memastikan form ada dan dimiliki userID. Form milik user lain diperlakukan
+// sama dengan form yang tidak ada agar keberadaannya tidak bocor.
+func (s *formService) requireFormOwner(userID, formID string) error {
+	form, err := s.repo.FindByID(formID)
+	if err != nil || form.UserID.String() != userID {
+		return ErrFormNotFound
+	}
+	return nil
+}
+
+func (s *formService) AddFormSection(userID string, req *dto.AddSectionRequest, meta dto.RequestMeta) (*dto.SectionResponse, error) {
+	if err := s.requireFormOwner(userID, req.FormID); err != nil {
+		return nil, err
+	}
 	section := &models.FormSection{
 		ID:          uuid.New(),
 		FormID:      uuid.MustParse(req.FormID),
@@ -138,6 +306,10 @@ func (s *formService) AddFormSection(req *dto.AddSectionRequest) (*dto.SectionRe
 	if err := s.repo.AddSection(section); err != nil {
 		return nil, err
 	}
+	s.audit.Record(meta, AuditEntry{
+		Action: "section.created", EntityType: AuditEntitySection, EntityID: section.ID.String(),
+		FormID: section.FormID.String(), After: section,
+	})
 	return &dto.SectionResponse{
 		ID:          section.ID.String(),
 		FormID:      section.FormID.String(),
@@ -158,6 +330,14 @@ func parseTimePointer(input *string) *time.Time {
 	return &t
 }
 
+func formatTimePointer(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.Format(time.RFC3339)
+	return &s
+}
+
 func (s *formService) GetFormSections(formID string) ([]dto.SectionResponse, error) {
 	sections, err := s.repo.GetSectionsByFormID(formID)
 	if err != nil {
@@ -176,18 +356,52 @@ func (s *formService) GetFormSections(formID string) ([]dto.SectionResponse, err
 	return result, nil
 }
 
-func (s *formService) UpdateFormSections(req *dto.UpdateSectionRequest) error {
+func (s *formService) UpdateFormSections(userID string, req *dto.UpdateSectionRequest, meta dto.RequestMeta) error {
+	before, err := s.repo.GetSectionByID(req.ID)
+	if err != nil {
+		return ErrSectionNotFound
+	}
+	if err := s.requireFormOwner(userID, before.FormID.String()); err != nil {
+		return ErrSectionNotFound
+	}
+
 	section := &models.FormSection{
 		ID:          uuid.MustParse(req.ID),
 		Title:       req.Title,
 		Description: req.Description,
 		Order:       req.Order,
 	}
-	return s.repo.UpdateSection(section)
+	if err := s.repo.UpdateSection(section); err != nil {
+		return err
+	}
+
+	after, err := s.repo.GetSectionByID(req.ID)
+	if err != nil {
+		after = section
+	}
+	s.audit.Record(meta, AuditEntry{
+		Action: "section.updated", EntityType: AuditEntitySection, EntityID: req.ID,
+		FormID: before.FormID.String(), Before: before, After: after,
+	})
+	return nil
 }
 
-func (s *formService) DeleteFormSections(sectionID string) error {
-	return s.repo.DeleteSection(sectionID)
+func (s *formService) DeleteFormSections(userID, sectionID string, meta dto.RequestMeta) error {
+	before, err := s.repo.GetSectionByID(sectionID)
+	if err != nil {
+		return ErrSectionNotFound
+	}
+	if err := s.requireFormOwner(userID, before.FormID.String()); err != nil {
+		return ErrSectionNotFound
+	}
+	if err := s.repo.DeleteSection(sectionID); err != nil {
+		return err
+	}
+	s.audit.Record(meta, AuditEntry{
+		Action: "section.deleted", EntityType: AuditEntitySection, EntityID: sectionID,
+		FormID: before.FormID.String(), Before: before,
+	})
+	return nil
 }
 
 func (s *formService) GetFormQuestion(formID string) ([]dto.QuestionResponse, error) {
@@ -222,7 +436,14 @@ func (s *formService) GetFormQuestion(formID string) ([]dto.QuestionResponse, er
 	return result, nil
 }
 
-func (s *formService) AddFormQuestion(req *dto.AddQuestionRequest) error {
+func (s *formService) AddFormQuestion(userID string, req *dto.AddQuestionRequest, meta dto.RequestMeta) error {
+	if err := s.requireFormOwner(userID, req.FormID); err != nil {
+		return err
+	}
+	section, err := s.repo.GetSectionByID(req.SectionID)
+	if err != nil || section.FormID.String() != req.FormID {
+		return ErrSectionNotFound
+	}
 
 	sectionID := uuid.MustParse(req.SectionID)
 	q := &models.Question{
@@ -236,10 +457,25 @@ func (s *formService) AddFormQuestion(req *dto.AddQuestionRequest) error {
 		Score:      req.Score,
 		ImageURL:   req.ImageURL,
 	}
-	return s.repo.AddQuestion(q)
+	if err := s.repo.AddQuestion(q); err != nil {
+		return err
+	}
+	s.audit.Record(meta, AuditEntry{
+		Action: "question.created", EntityType: AuditEntityQuestion, EntityID: q.ID.String(),
+		FormID: req.FormID, After: q,
+	})
+	return nil
 }
 
-func (s *formService) UpdateQuestion(req *dto.UpdateQuestionRequest) error {
+func (s *formService) UpdateQuestion(userID string, req *dto.UpdateQuestionRequest, meta dto.RequestMeta) error {
+	before, err := s.repo.GetQuestionByID(req.ID)
+	if err != nil {
+		return ErrQuestionNotFound
+	}
+	if err := s.requireFormOwner(userID, before.FormID.String()); err != nil {
+		return ErrQuestionNotFound
+	}
+
 	q := &models.Question{
 		ID:         uuid.MustParse(req.ID),
 		Text:       req.Text,
@@ -249,9 +485,35 @@ func (s *formService) UpdateQuestion(req *dto.UpdateQuestionRequest) error {
 		Score:      req.Score,
 		ImageURL:   req.ImageURL,
 	}
-	return s.repo.UpdateQuestion(q)
+	if err := s.repo.UpdateQuestion(q); err != nil {
+		return err
+	}
+
+	after, err := s.repo.GetQuestionByID(req.ID)
+	if err != nil {
+		after = q
+	}
+	s.audit.Record(meta, AuditEntry{
+		Action: "question.updated", EntityType: AuditEntityQuestion, EntityID: req.ID,
+		FormID: before.FormID.String(), Before: before, After: after,
+	})
+	return nil
 }
 
-func (s *formService) DeleteQuestion(id string) error {
-	return s.repo.DeleteQuestion(id)
+func (s *formService) DeleteQuestion(userID, id string, meta dto.RequestMeta) error {
+	before, err := s.repo.GetQuestionByID(id)
+	if err != nil {
+		return ErrQuestionNotFound
+	}
+	if err := s.requireFormOwner(userID, before.FormID.String()); err != nil {
+		return ErrQuestionNotFound
+	}
+	if err := s.repo.DeleteQuestion(id); err != nil {
+		return err
+	}
+	s.audit.Record(meta, AuditEntry{
+		Action: "question.deleted", EntityType: AuditEntityQuestion, EntityID: id,
+		FormID: before.FormID.String(), Before: before,
+	})
+	return nil
 }
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"errors"
+	"maps"
+	"server/internal/dto"
+	"server/internal/models"
+	"server/internal/repositories"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestApplySettingUpdates(t *testing.T) {
+	grade, maxSubs := 70.0, 50
+	end := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	before := &models.FormSetting{
+		ShowResult: true, HoneypotEnabled: true, RequireRenderToken: true,
+		MinFillSeconds: 5, RetentionDays: 30, RetentionAction: RetentionDelete,
+		ReminderHoursBefore: 24, PassingGrade: &grade, MaxSubmissions: &maxSubs, EndAt: &end,
+	}
+	no, zero, anonymize := false, 0, RetentionAnonymize
+	newGrade, newEnd := 80.0, "2026-07-01T00:00:00Z"
+
+	tests := []struct {
+		name        string
+		req         dto.UpdateFormSettingRequest
+		wantColumns []string
+		check       func(t *testing.T, s *models.FormSetting)
+	}{
+		{
+			name:        "omitted fields keep their stored value",
+			req:         dto.UpdateFormSettingRequest{},
+			wantColumns: []string{},
+			check: func(t *testing.T, s *models.FormSetting) {
+				if !s.HoneypotEnabled || s.ReminderHoursBefore != 24 || s.RetentionAction != RetentionDelete {
+					t.Errorf("omitted fields were reset: %+v", s)
+				}
+				if s.PassingGrade == nil || s.MaxSubmissions == nil || s.EndAt == nil {
+					t.Errorf("limits or schedule were reset: grade=%v max=%v end=%v", s.PassingGrade, s.MaxSubmissions, s.EndAt)
+				}
+			},
+		},
+		{
+			name:        "false and zero are written when sent",
+			req:         dto.UpdateFormSettingRequest{HoneypotEnabled: &no, ReminderHoursBefore: &zero},
+			wantColumns: []string{"honeypot_enabled", "reminder_hours_before"},
+			check: func(t *testing.T, s *models.FormSetting) {
+				if s.HoneypotEnabled || s.ReminderHoursBefore != 0 {
+					t.Errorf("sent values not applied: honeypot=%v reminder=%d", s.HoneypotEnabled, s.ReminderHoursBefore)
+				}
+				if !s.ShowResult || s.MinFillSeconds != 5 || s.EndAt == nil || !s.EndAt.Equal(end) {
+					t.Errorf("other fields changed: %+v", s)
+				}
+			},
+		},
+		{
+			name:        "limits and schedule are written when sent",
+			req:         dto.UpdateFormSettingRequest{PassingGrade: &newGrade, EndAt: &newEnd},
+			wantColumns: []string{"end_at", "passing_grade"},
+			check: func(t *testing.T, s *models.FormSetting) {
+				if s.PassingGrade == nil || *s.PassingGrade != newGrade {
+					t.Errorf("passing grade = %v, want %v", s.PassingGrade, newGrade)
+				}
+				if s.EndAt == nil || s.EndAt.Format(time.RFC3339) != newEnd {
+					t.Errorf("end at = %v, want %s", s.EndAt, newEnd)
+				}
+				if s.MaxSubmissions == nil || *s.MaxSubmissions != maxSubs {
+					t.Errorf("max submissions = %v, want %d", s.MaxSubmissions, maxSubs)
+				}
+			},
+		},
+		{
+			name:        "cleared fields are written as null",
+			req:         dto.UpdateFormSettingRequest{Clear: []string{"maxSubmissions", "endAt"}, EndAt: &newEnd, RetentionAction: &anonymize},
+			wantColumns: []string{"end_at", "max_submissions", "retention_action"},
+			check: func(t *testing.T, s *models.FormSetting) {
+				if s.MaxSubmissions != nil || s.EndAt != nil {
+					t.Errorf("fields not cleared: max=%v end=%v", s.MaxSubmissions, s.EndAt)
+				}
+				if s.PassingGrade == nil || *s.PassingGrade != grade {
+					t.Errorf("passing grade = %v, want %v", s.PassingGrade, grade)
+				}
+				if s.RetentionAction != RetentionAnonymize {
+					t.Errorf("retention action = %q, want %q", s.RetentionAction, RetentionAnonymize)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setting, columns := applySettingUpdates(before, &tt.req)
+			if got := slices.Sorted(maps.Keys(columns)); !slices.Equal(got, tt.wantColumns) {
+				t.Errorf("columns = %v, want %v", got, tt.wantColumns)
+			}
+			tt.check(t, setting)
+			if !before.HoneypotEnabled || before.PassingGrade == nil || before.EndAt == nil {
+				t.Fatal("stored setting was mutated")
+			}
+		})
+	}
+}
+
+// fakeFormRepo hanya mengimplementasikan method yang dipakai pengecekan pemilik form.
+type fakeFormRepo struct {
+	repositories.FormRepository
+
+	forms     map[string]*models.Form
+	sections  map[string]*models.FormSection
+	questions map[string]*models.Question
+	versions  map[string]*models.FormVersion
+	setting   *models.FormSetting
+	writes    []string
+	resets    int
+}
+
+func (r *fakeFormRepo) FindByID(id string) (*models.Form, error) {
+	if f, ok := r.forms[id]; ok {
+		return f, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeFormRepo) GetFormStructure(id string) (*models.Form, error) {
+	return r.FindByID(id)
+}
+
+func (r *fakeFormRepo) GetVersionByID(id string) (*models.FormVersion, error) {
+	if v, ok := r.versions[id]; ok {
+		return v, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeFormRepo) GetSectionByID(id string) (*models.FormSection, error) {
+	if s, ok := r.sections[id]; ok {
+		return s, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeFormRepo) GetQuestionByID(id string) (*models.Question, error) {
+	if q, ok := r.questions[id]; ok {
+		return q, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeFormRepo) GetFormSetting(formID string) (*models.FormSetting, error) {
+	if r.setting != nil {
+		setting := *r.setting
+		return &setting, nil
+	}
+	return &models.FormSetting{}, nil
+}
+
+func (r *fakeFormRepo) UpdateFormSetting(string, map[string]any) error {
+	r.writes = append(r.writes, "setting")
+	return nil
+}
+
+func (r *fakeFormRepo) ResetInviteeReminders(string) error {
+	r.resets++
+	return nil
+}
+
+func (r *fakeFormRepo) SetFormActive(string, bool) error {
+	return nil
+}
+
+func (r *fakeFormRepo) AddSection(*models.FormSection) error {
+	r.writes = append(r.writes, "section")
+	return nil
+}
+
+func (r *fakeFormRepo) UpdateSection(*models.FormSection) error {
+	r.writes = append(r.writes, "section")
+	return nil
+}
+
+func (r *fakeFormRepo) DeleteSection(string) error {
+	r.writes = append(r.writes, "section")
+	return nil
+}
+
+func (r *fakeFormRepo) AddQuestion(*models.Question) error {
+	r.writes = append(r.writes, "question")
+	return nil
+}
+
+func (r *fakeFormRepo) UpdateQuestion(*models.Question) error {
+	r.writes = append(r.writes, "question")
+	return nil
+}
+
+func (r *fakeFormRepo) DeleteQuestion(string) error {
+	r.writes = append(r.writes, "question")
+	return nil
+}
+
+type nopAudit struct{ AuditService }
+
+func (nopAudit) Record(dto.RequestMeta, AuditEntry) {}
+
+func TestFormOwnerChecks(t *testing.T) {
+	owner, other := uuid.New(), uuid.New()
+	formID, otherFormID := uuid.New(), uuid.New()
+	sectionID, otherSectionID, questionID := uuid.New(), uuid.New(), uuid.New()
+
+	newService := func() (*formService, *fakeFormRepo) {
+		repo := &fakeFormRepo{
+			forms: map[string]*models.Form{
+				formID.String():      {ID: formID, UserID: owner},
+				otherFormID.String(): {ID: otherFormID, UserID: other},
+			},
+			sections: map[string]*models.FormSection{
+				sectionID.String():      {ID: sectionID, FormID: formID},
+				otherSectionID.String(): {ID: otherSectionID, FormID: otherFormID},
+			},
+			questions: map[string]*models.Question{
+				questionID.String(): {ID: questionID, FormID: formID},
+			},
+		}
+		return &formService{repo: repo, audit: nopAudit{}}, repo
+	}
+	meta := dto.RequestMeta{}
+
+	tests := []struct {
+		name    string
+		userID  uuid.UUID
+		call    func(s *formService, userID string) error
+		wantErr error
+	}{
+		{
+			name: "settings", userID: other, wantErr: ErrFormNotFound,
+			call: func(s *formService, userID string) error {
+				return s.UpdateFormSettings(userID, formID.String(), &dto.UpdateFormSettingRequest{}, meta)
+			},
+		},
+		{
+			name: "add section", userID: other, wantErr: ErrFormNotFound,
+			call: func(s *formService, userID string) error {
+				_, err := s.AddFormSection(userID, &dto.AddSectionRequest{FormID: formID.String(), Title: "x"}, meta)
+				return err
+			},
+		},
+		{
+			name: "update section", userID: other, wantErr: ErrSectionNotFound,
+			call: func(s *formService, userID string) error {
+				return s.UpdateFormSections(userID, &dto.UpdateSectionRequest{ID: sectionID.String(), Title: "x"}, meta)
+			},
+		},
+		{
+			name: "delete section", userID: other, wantErr: ErrSectionNotFound,
+			call: func(s *formService, userID string) error {
+				return s.DeleteFormSections(userID, sectionID.String(), meta)
+			},
+		},
+		{
+			name: "add question", userID: other, wantErr: ErrFormNotFound,
+			call: func(s *formService, userID string) error {
+				return s.AddFormQuestion(userID, &dto.AddQuestionRequest{FormID: formID.String(), SectionID: sectionID.String()}, meta)
+			},
+		},
+		{
+			name: "add question to a section of another form", userID: owner, wantErr: ErrSectionNotFound,
+			call: func(s *formService, userID string) error {
+				return s.AddFormQuestion(userID, &dto.AddQuestionRequest{FormID: formID.String(), SectionID: otherSectionID.String()}, meta)
+			},
+		},
+		{
+			name: "update question", userID: other, wantErr: ErrQuestionNotFound,
+			call: func(s *formService, userID string) error {
+				return s.UpdateQuestion(userID, &dto.UpdateQuestionRequest{ID: questionID.String()}, meta)
+			},
+		},
+		{
+			name: "delete question", userID: other, wantErr: ErrQuestionNotFound,
+			call: func(s *formService, userID string) error {
+				return s.DeleteQuestion(userID, questionID.String(), meta)
+			},
+		},
+		{
+			name: "owner can delete a question", userID: owner,
+			call: func(s *formService, userID string) error {
+				return s.DeleteQuestion(userID, questionID.String(), meta)
+			},
+		},
+		{
+			name: "owner can add a question", userID: owner,
+			call: func(s *formService, userID string) error {
+				return s.AddFormQuestion(userID, &dto.AddQuestionRequest{FormID: formID.String(), SectionID: sectionID.String()}, meta)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo := newService()
+			err := tt.call(s, tt.userID.String())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && len(repo.writes) > 0 {
+				t.Errorf("rejected call still wrote %v", repo.writes)
+			}
+			if tt.wantErr == nil && len(repo.writes) == 0 {
+				t.Error("allowed call did not write")
+			}
+		})
+	}
+}
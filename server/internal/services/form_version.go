@@ -0,0 +1,249 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"server/internal/dto"
+	"server/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrNoChangesToPublish = errors.New("form has no changes since the latest published version")
+
+// PublishForm membekukan draft form saat ini menjadi versi baru dan menjadikannya versi live.
+func (s *formService) PublishForm(userID, formID string, meta dto.RequestMeta) (*dto.FormVersionResponse, error) {
+	form, err := s.repo.GetFormStructure(formID)
+	if err != nil || form.UserID.String() != userID {
+		return nil, errors.New("form not found")
+	}
+	if len(form.Questions) == 0 {
+		return nil, errors.New("form must have at least one question before publishing")
+	}
+
+	snapshot := buildFormSnapshot(form)
+	if form.PublishedVersionID != nil {
+		if live, err := s.repo.GetVersionByID(form.PublishedVersionID.String()); err == nil {
+			if current, err := decodeFormSnapshot(live); err == nil && reflect.DeepEqual(current, snapshot) {
+				return nil, ErrNoChangesToPublish
+			}
+		}
+	}
+
+	raw, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	version := &models.FormVersion{
+		ID:          uuid.New(),
+		FormID:      form.ID,
+		Snapshot:    string(raw),
+		PublishedAt: time.Now(),
+	}
+	if id, err := uuid.Parse(meta.ActorID); err == nil {
+		version.PublishedBy = &id
+	}
+	if err := s.repo.PublishVersion(version); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(meta, AuditEntry{
+		Action: "form.published", EntityType: AuditEntityForm, EntityID: formID, FormID: formID,
+		After: map[string]any{"version": version.Version, "versionId": version.ID.String()},
+	})
+
+	res := toFormVersionResponse(version, version.ID.String())
+	return &res, nil
+}
+
+func (s *formService) GetFormVersions(userID, formID string) ([]dto.FormVersionResponse, error) {
+	form, err := s.repo.FindByID(formID)
+	if err != nil || form.UserID.String() != userID {
+		return nil, errors.New("form not found")
+	}
+	versions, err := s.repo.GetVersions(formID)
+	if err != nil {
+		return nil, err
+	}
+
+	live := ""
+	if form.PublishedVersionID != nil {
+		live = form.PublishedVersionID.String()
+	}
+	result := make([]dto.FormVersionResponse, 0, len(versions))
+	for i := range versions {
+		result = append(result, toFormVersionResponse(&versions[i], live))
+	}
+	return result, nil
+}
+
+func (s *formService) GetFormVersion(userID, formID string, version int) (*dto.FormVersionResponse, error) {
+	form, err := s.repo.FindByID(formID)
+	if err != nil || form.UserID.String() != userID {
+		return nil, errors.New("form not found")
+	}
+	v, err := s.repo.GetVersion(formID, version)
+	if err != nil {
+		return nil, errors.New("form version not found")
+	}
+	snapshot, err := decodeFormSnapshot(v)
+	if err != nil {
+		return nil, err
+	}
+
+	live := ""
+	if form.PublishedVersionID != nil {
+		live = form.PublishedVersionID.String()
+	}
+	res := toFormVersionResponse(v, live)
+	res.Snapshot = snapshot
+	return &res, nil
+}
+
+// DiffFormVersions membandingkan dua versi; section dan pertanyaan dicocokkan berdasarkan ID.
+func (s *formService) DiffFormVersions(userID, formID string, from, to int) (*dto.FormVersionDiffResponse, error) {
+	form, err := s.repo.FindByID(formID)
+	if err != nil || form.UserID.String() != userID {
+		return nil, errors.New("form not found")
+	}
+	fromVersion, err := s.repo.GetVersion(formID, from)
+	if err != nil {
+		return nil, fmt.Errorf("form version %d not found", from)
+	}
+	toVersion, err := s.repo.GetVersion(formID, to)
+	if err != nil {
+		return nil, fmt.Errorf("form version %d not found", to)
+	}
+	before, err := decodeFormSnapshot(fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	after, err := decodeFormSnapshot(toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []dto.VersionChange{}
+	formBefore := map[string]any{"title": before.Title, "description": before.Description, "type": before.Type, "duration": before.Duration}
+	formAfter := map[string]any{"title": after.Title, "description": after.Description, "type": after.Type, "duration": after.Duration}
+	if b, a := auditDiff(formBefore, formAfter); len(a) > 0 {
+		changes = append(changes, dto.VersionChange{Kind: "form", Change: "changed", Before: b, After: a})
+	}
+
+	sectionsBefore, sectionsAfter := map[string]any{}, map[string]any{}
+	var sectionOrder []string
+	for _, sec := range before.Sections {
+		sectionsBefore[sec.ID] = sec
+		sectionOrder = append(sectionOrder, sec.ID)
+	}
+	for _, sec := range after.Sections {
+		if _, ok := sectionsBefore[sec.ID]; !ok {
+			sectionOrder = append(sectionOrder, sec.ID)
+		}
+		sectionsAfter[sec.ID] = sec
+	}
+	changes = append(changes, diffVersionItems("section", sectionOrder, sectionsBefore, sectionsAfter)...)
+
+	questionsBefore, questionsAfter := map[string]any{}, map[string]any{}
+	var questionOrder []string
+	for _, q := range before.Questions {
+		questionsBefore[q.ID] = q
+		questionOrder = append(questionOrder, q.ID)
+	}
+	for _, q := range after.Questions {
+		if _, ok := questionsBefore[q.ID]; !ok {
+			questionOrder = append(questionOrder, q.ID)
+		}
+		questionsAfter[q.ID] = q
+	}
+	changes = append(changes, diffVersionItems("question", questionOrder, questionsBefore, questionsAfter)...)
+
+	return &dto.FormVersionDiffResponse{From: from, To: to, Changes: changes}, nil
+}
+
+func diffVersionItems(kind string, order []string, before, after map[string]any) []dto.VersionChange {
+	var changes []dto.VersionChange
+	for _, id := range order {
+		b, inBefore := before[id]
+		a, inAfter := after[id]
+		switch {
+		case !inBefore:
+			changes = append(changes, dto.VersionChange{Kind: kind, ID: id, Change: "added", After: toAuditMap(a)})
+		case !inAfter:
+			changes = append(changes, dto.VersionChange{Kind: kind, ID: id, Change: "removed", Before: toAuditMap(b)})
+		default:
+			if db, da := auditDiff(b, a); len(db) > 0 || len(da) > 0 {
+				changes = append(changes, dto.VersionChange{Kind: kind, ID: id, Change: "changed", Before: db, After: da})
+			}
+		}
+	}
+	return changes
+}
+
+func buildFormSnapshot(form *models.Form) *dto.FormSnapshot {
+	snapshot := &dto.FormSnapshot{
+		Title:       form.Title,
+		Description: form.Description,
+		Type:        form.Type,
+		Duration:    form.Duration,
+		Sections:    []dto.SectionResponse{},
+		Questions:   []dto.SnapshotQuestion{},
+	}
+	for _, sec := range form.FormSection {
+		snapshot.Sections = append(snapshot.Sections, dto.SectionResponse{
+			ID:          sec.ID.String(),
+			FormID:      sec.FormID.String(),
+			Title:       sec.Title,
+			Description: sec.Description,
+			Order:       sec.Order,
+		})
+	}
+	for _, q := range form.Questions {
+		opts := []dto.Option{}
+		for _, o := range q.Options {
+			opts = append(opts, dto.Option{ID: o.ID, Text: o.Text, ImageURL: o.ImageURL, IsCorrect: o.IsCorrect})
+		}
+		sq := dto.SnapshotQuestion{
+			QuestionResponse: dto.QuestionResponse{
+				ID:         q.ID.String(),
+				Text:       q.Text,
+				Type:       q.Type,
+				IsRequired: q.IsRequired,
+				Order:      q.Order,
+				Score:      q.Score,
+				ImageURL:   q.ImageURL,
+				Options:    opts,
+			},
+		}
+		if q.SectionID != nil {
+			sq.SectionID = q.SectionID.String()
+		}
+		snapshot.Questions = append(snapshot.Questions, sq)
+	}
+	return snapshot
+}
+
+func decodeFormSnapshot(v *models.FormVersion) (*dto.FormSnapshot, error) {
+	var snapshot dto.FormSnapshot
+	if err := json.Unmarshal([]byte(v.Snapshot), &snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot for form version %d: %w", v.Version, err)
+	}
+	return &snapshot, nil
+}
+
+func toFormVersionResponse(v *models.FormVersion, liveID string) dto.FormVersionResponse {
+	res := dto.FormVersionResponse{
+		ID:          v.ID.String(),
+		FormID:      v.FormID.String(),
+		Version:     v.Version,
+		Live:        v.ID.String() == liveID,
+		PublishedAt: v.PublishedAt.Format("2006-01-02 15:04:05"),
+	}
+	if v.PublishedBy != nil {
+		res.PublishedBy = v.PublishedBy.String()
+	}
+	return res
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"reflect"
+	"server/internal/dto"
+	"testing"
+)
+
+func TestDiffVersionItems(t *testing.T) {
+	q := func(id, text string, required bool) dto.SnapshotQuestion {
+		return dto.SnapshotQuestion{QuestionResponse: dto.QuestionResponse{ID: id, Text: text, IsRequired: required}}
+	}
+
+	tests := []struct {
+		name   string
+		order  []string
+		before map[string]any
+		after  map[string]any
+		want   []dto.VersionChange
+	}{
+		{
+			name:   "identical versions",
+			order:  []string{"q1"},
+			before: map[string]any{"q1": q("q1", "Name", true)},
+			after:  map[string]any{"q1": q("q1", "Name", true)},
+			want:   nil,
+		},
+		{
+			name:   "added question",
+			order:  []string{"q1"},
+			before: map[string]any{},
+			after:  map[string]any{"q1": q("q1", "Name", true)},
+			want: []dto.VersionChange{{
+				Kind: "question", ID: "q1", Change: "added", After: toAuditMap(q("q1", "Name", true)),
+			}},
+		},
+		{
+			name:   "removed question",
+			order:  []string{"q1"},
+			before: map[string]any{"q1": q("q1", "Name", true)},
+			after:  map[string]any{},
+			want: []dto.VersionChange{{
+				Kind: "question", ID: "q1", Change: "removed", Before: toAuditMap(q("q1", "Name", true)),
+			}},
+		},
+		{
+			name:   "changed question only reports changed fields",
+			order:  []string{"q1"},
+			before: map[string]any{"q1": q("q1", "Name", true)},
+			after:  map[string]any{"q1": q("q1", "Full name", true)},
+			want: []dto.VersionChange{{
+				Kind: "question", ID: "q1", Change: "changed",
+				Before: map[string]any{"text": "Name"}, After: map[string]any{"text": "Full name"},
+			}},
+		},
+		{
+			name:   "changes follow the given order",
+			order:  []string{"q2", "q1"},
+			before: map[string]any{"q1": q("q1", "Name", true)},
+			after:  map[string]any{"q2": q("q2", "Email", false)},
+			want: []dto.VersionChange{
+				{Kind: "question", ID: "q2", Change: "added", After: toAuditMap(q("q2", "Email", false))},
+				{Kind: "question", ID: "q1", Change: "removed", Before: toAuditMap(q("q1", "Name", true))},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffVersionItems("question", tt.order, tt.before, tt.after)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffVersionItems =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
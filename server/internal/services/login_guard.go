@@ -0,0 +1,225 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"os"
+	"server/internal/config"
+	"server/internal/dto"
+	"server/internal/models"
+	"server/internal/utils"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	loginFailureWindow    = 15 * time.Minute
+	loginBackoffAfter     = 3 // gagal ke-3 dan seterusnya harus menunggu 1s, 2s, 4s, ...
+	loginMaxBackoff       = 5 * time.Minute
+	loginAccountLockAfter = 10
+	loginIPLockAfter      = 50
+	loginLockDuration     = 30 * time.Minute
+	unlockTokenTTL        = 30 * time.Minute
+
+	SecurityEventLoginSucceeded  = "login_succeeded"
+	SecurityEventLoginChallenged = "login_2fa_required"
+	SecurityEventLoginFailed     = "login_failed"
+	SecurityEventLoginThrottled  = "login_throttled"
+	SecurityEventAccountLocked   = "account_locked"
+	SecurityEventAccountUnlocked = "account_unlocked"
+	SecurityEventIPBlocked       = "ip_blocked"
+	SecurityEventRefreshReused   = "refresh_token_reused"
+	SecurityEventPasswordChanged = "password_changed"
+	SecurityEventPasswordReset   = "password_reset"
+	SecurityEventTwoFactorOn     = "two_factor_enabled"
+	SecurityEventTwoFactorOff    = "two_factor_disabled"
+	SecurityEventTwoFactorLocked = "two_factor_locked"
+)
+
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+// LoginThrottledError dikembalikan selama backoff atau lockout; RetryAfter dipakai untuk header Retry-After.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return "too many failed login attempts, login is temporarily locked"
+	}
+	return "too many failed login attempts, please try again later"
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = utils.HashPassword("dummy-password-for-timing")
+	})
+	return dummyHash
+}
+
+func loginKey(kind, id string) string {
+	return "login:" + kind + ":" + id
+}
+
+// checkLoginAllowed menolak login selama IP/akun terkunci atau masih dalam masa backoff.
+func (s *authService) checkLoginAllowed(email string, meta dto.RequestMeta) error {
+	for _, key := range []string{loginKey("lock:ip", meta.IPAddress), loginKey("lock:acct", email)} {
+		if ttl, err := config.RedisClient.TTL(config.Ctx, key).Result(); err == nil && ttl > 0 {
+			return &LoginThrottledError{RetryAfter: ttl, Locked: true}
+		}
+	}
+
+	if ttl, err := config.RedisClient.TTL(config.Ctx, loginKey("backoff", email)).Result(); err == nil && ttl > 0 {
+		// dicatat sekali per masa backoff agar brute force tidak ikut membanjiri tabel event
+		if first, _ := config.RedisClient.SetNX(config.Ctx, loginKey("throttle-logged", email), 1, ttl).Result(); first {
+			s.logSecurityEvent(models.SecurityEvent{Email: email, Event: SecurityEventLoginThrottled}, meta)
+		}
+		return &LoginThrottledError{RetryAfter: ttl}
+	}
+	return nil
+}
+
+// recordLoginFailure menaikkan counter per akun dan per IP, memasang backoff eksponensial,
+// dan mengunci akun/IP setelah batas tercapai. Selalu mengembalikan error yang seragam.
+func (s *authService) recordLoginFailure(email string, user *models.User, meta dto.RequestMeta) error {
+	event := models.SecurityEvent{Email: email, Event: SecurityEventLoginFailed}
+	if user != nil {
+		event.UserID = &user.ID
+	}
+	s.logSecurityEvent(event, meta)
+
+	accountFailures := incrWithWindow(loginKey("fail:acct", email))
+	ipFailures := incrWithWindow(loginKey("fail:ip", meta.IPAddress))
+
+	if ipFailures >= loginIPLockAfter {
+		config.RedisClient.Set(config.Ctx, loginKey("lock:ip", meta.IPAddress), 1, loginLockDuration)
+		config.RedisClient.Del(config.Ctx, loginKey("fail:ip", meta.IPAddress))
+		s.logSecurityEvent(models.SecurityEvent{Event: SecurityEventIPBlocked, Detail: fmt.Sprintf("%d failed logins", ipFailures)}, meta)
+	}
+
+	if accountFailures >= loginAccountLockAfter {
+		config.RedisClient.Set(config.Ctx, loginKey("lock:acct", email), 1, loginLockDuration)
+		config.RedisClient.Del(config.Ctx, loginKey("fail:acct", email), loginKey("backoff", email))
+
+		event.Event = SecurityEventAccountLocked
+		event.Detail = fmt.Sprintf("%d failed logins", accountFailures)
+		s.logSecurityEvent(event, meta)
+
+		if user != nil {
+			s.sendUnlockEmail(user, accountFailures)
+		}
+		return &LoginThrottledError{RetryAfter: loginLockDuration, Locked: true}
+	}
+
+	if delay := loginBackoffDelay(accountFailures); delay > 0 {
+		config.RedisClient.Set(config.Ctx, loginKey("backoff", email), 1, delay)
+	}
+
+	return ErrInvalidCredentials
+}
+
+// loginBackoffDelay mengembalikan lama backoff setelah sejumlah gagal login, 0 bila belum perlu menunggu.
+func loginBackoffDelay(failures int64) time.Duration {
+	if failures < loginBackoffAfter {
+		return 0
+	}
+	delay := time.Duration(math.Pow(2, float64(failures-loginBackoffAfter))) * time.Second
+	return min(delay, loginMaxBackoff)
+}
+
+func (s *authService) clearLoginFailures(email string) {
+	config.RedisClient.Del(config.Ctx, loginKey("fail:acct", email), loginKey("backoff", email))
+}
+
+func incrWithWindow(key string) int64 {
+	count, err := config.RedisClient.Incr(config.Ctx, key).Result()
+	if err != nil {
+		return 0
+	}
+	if count == 1 {
+		config.RedisClient.Expire(config.Ctx, key, loginFailureWindow)
+	}
+	return count
+}
+
+func (s *authService) sendUnlockEmail(user *models.User, attempts int64) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		log.Printf("failed to create unlock token for %s: %v", user.Email, err)
+		return
+	}
+	email := normalizeEmail(user.Email)
+	if err := config.RedisClient.Set(config.Ctx, loginKey("unlock", utils.HashToken(token)), email, unlockTokenTTL).Err(); err != nil {
+		log.Printf("failed to store unlock token for %s: %v", user.Email, err)
+		return
+	}
+
+	unlockURL := fmt.Sprintf("%s/unlock-account?token=%s", strings.TrimRight(os.Getenv("CLIENT_URL"), "/"), url.QueryEscape(token))
+	htmlBody, err := utils.RenderEmailTemplate("unlock_account.html", map[string]any{
+		"Fullname":         user.Fullname,
+		"Attempts":         attempts,
+		"LockMinutes":      int(loginLockDuration.Minutes()),
+		"UnlockURL":        unlockURL,
+		"ExpiresInMinutes": int(unlockTokenTTL.Minutes()),
+	})
+	if err != nil {
+		log.Printf("failed to render unlock email: %v", err)
+		return
+	}
+	body := fmt.Sprintf("Your account was locked after %d failed sign-in attempts. Open this link to unlock it: %s", attempts, unlockURL)
+
+	if err := utils.SendEmail("Your account has been locked", user.Email, body, htmlBody); err != nil {
+		log.Printf("failed to send unlock email to %s: %v", user.Email, err)
+	}
+}
+
+// UnlockAccount membuka lockout akun memakai token sekali pakai dari email.
+func (s *authService) UnlockAccount(req *dto.UnlockAccountRequest, meta dto.RequestMeta) error {
+	key := loginKey("unlock", utils.HashToken(req.Token))
+	email, err := config.RedisClient.Get(config.Ctx, key).Result()
+	if err != nil {
+		return errors.New("unlock token is invalid or expired")
+	}
+
+	config.RedisClient.Del(config.Ctx, key, loginKey("lock:acct", email), loginKey("fail:acct", email), loginKey("backoff", email))
+
+	event := models.SecurityEvent{Email: email, Event: SecurityEventAccountUnlocked}
+	if user, err := s.repo.GetUserByEmail(email); err == nil {
+		event.UserID = &user.ID
+	}
+	s.logSecurityEvent(event, meta)
+	return nil
+}
+
+// logSecurityEvent mencatat event ke tabel security_events dan log server; kegagalan simpan tidak menggagalkan request.
+func (s *authService) logSecurityEvent(event models.SecurityEvent, meta dto.RequestMeta) {
+	event.IPAddress = meta.IPAddress
+	event.UserAgent = truncate(meta.UserAgent, 255)
+
+	log.Printf("security event %s email=%q ip=%s %s", event.Event, event.Email, event.IPAddress, event.Detail)
+	if err := s.repo.CreateSecurityEvent(&event); err != nil {
+		log.Printf("failed to store security event %s: %v", event.Event, err)
+	}
+
+	// event autentikasi juga masuk audit log. Actor hanya diisi bila request terautentikasi, dan email
+	// disimpan sebagai hash agar percobaan login dengan alamat siapa pun tidak menumpuk PII di audit log.
+	state := map[string]any{"emailHash": utils.HashToken(normalizeEmail(event.Email))}
+	if event.Detail != "" {
+		state["detail"] = event.Detail
+	}
+	entry := AuditEntry{Action: "auth." + event.Event, EntityType: AuditEntityUser, After: state}
+	if event.UserID != nil {
+		entry.EntityID = event.UserID.String()
+	}
+	s.audit.Record(meta, entry)
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoginBackoffDelay(t *testing.T) {
+	tests := []struct {
+		failures int64
+		want     time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{9, 64 * time.Second},
+		{12, 5 * time.Minute}, // dibatasi loginMaxBackoff
+	}
+
+	for _, tt := range tests {
+		if got := loginBackoffDelay(tt.failures); got != tt.want {
+			t.Errorf("loginBackoffDelay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLoginThrottledError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *LoginThrottledError
+		want string
+	}{
+		{"backoff", &LoginThrottledError{RetryAfter: time.Second}, "please try again later"},
+		{"locked", &LoginThrottledError{RetryAfter: loginLockDuration, Locked: true}, "temporarily locked"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); !strings.HasSuffix(got, tt.want) {
+			t.Errorf("%s: Error() = %q, want suffix %q", tt.name, got, tt.want)
+		}
+	}
+}
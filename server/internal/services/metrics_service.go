@@ -0,0 +1,281 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"server/internal/config"
+	"server/internal/dto"
+	"server/internal/repositories"
+	"sort"
+	"time"
+)
+
+const (
+	metricsCachePrefix = "metrics:"
+	metricsCacheTTL    = 10 * time.Minute
+)
+
+var ErrInvalidMetricsQuery = errors.New("invalid groupBy, expected tier or method")
+
+var periodFormats = map[string]string{
+	"day":   "%Y-%m-%d",
+	"week":  "%x-W%v",
+	"month": "%Y-%m",
+}
+
+type MetricsService interface {
+	GetMRR() (*dto.MRRResponse, error)
+	GetRevenue(q dto.MetricsQuery) (*dto.RevenueResponse, error)
+	GetSubscriptionFlow(q dto.MetricsQuery) (*dto.SubscriptionFlowResponse, error)
+	GetConversion(q dto.MetricsQuery) (*dto.ConversionResponse, error)
+	GetTokenConsumption(q dto.MetricsQuery) (*dto.TokenConsumptionResponse, error)
+}
+
+type metricsService struct {
+	repo repositories.MetricsRepository
+}
+
+func NewMetricsService(repo repositories.MetricsRepository) MetricsService {
+	return &metricsService{repo}
+}
+
+func (s *metricsService) GetMRR() (*dto.MRRResponse, error) {
+	var res dto.MRRResponse
+	err := cachedMetric("mrr", &res, func() error {
+		rows, err := s.repo.GetMRRByTier(time.Now())
+		if err != nil {
+			return err
+		}
+
+		res.ByTier = make([]dto.TierMRRResponse, 0, len(rows))
+		for _, row := range rows {
+			res.MRR += row.MRR
+			res.ActiveSubscriptions += row.Subscriptions
+			res.ByTier = append(res.ByTier, dto.TierMRRResponse{
+				TierName:      row.TierName,
+				Subscriptions: row.Subscriptions,
+				MRR:           row.MRR,
+			})
+		}
+		return nil
+	})
+	return &res, err
+}
+
+func (s *metricsService) GetRevenue(q dto.MetricsQuery) (*dto.RevenueResponse, error) {
+	from, to, interval, err := parseMetricsQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	groupBy := q.GroupBy
+	if groupBy == "" {
+		groupBy = "tier"
+	}
+	if groupBy != "tier" && groupBy != "method" {
+		return nil, ErrInvalidMetricsQuery
+	}
+
+	res := dto.RevenueResponse{GroupBy: groupBy, Interval: interval}
+	key := fmt.Sprintf("revenue:%s:%s:%s:%s", groupBy, interval, from.Format("20060102"), to.Format("20060102"))
+	err = cachedMetric(key, &res, func() error {
+		rows, err := s.repo.GetRevenue(from, to, periodFormats[interval], groupBy)
+		if err != nil {
+			return err
+		}
+
+		res.Points = make([]dto.RevenuePointResponse, 0, len(rows))
+		for _, row := range rows {
+			res.Total += row.Revenue
+			res.Points = append(res.Points, dto.RevenuePointResponse{
+				Period:   row.Period,
+				Key:      row.GroupKey,
+				Revenue:  row.Revenue,
+				Payments: row.Payments,
+			})
+		}
+		return nil
+	})
+	return &res, err
+}
+
+func (s *metricsService) GetSubscriptionFlow(q dto.MetricsQuery) (*dto.SubscriptionFlowResponse, error) {
+	from, to, interval, err := parseMetricsQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	res := dto.SubscriptionFlowResponse{Interval: interval}
+	key := fmt.Sprintf("subscriptions:%s:%s:%s", interval, from.Format("20060102"), to.Format("20060102"))
+	err = cachedMetric(key, &res, func() error {
+		format := periodFormats[interval]
+		newRows, err := s.repo.CountNewSubscriptions(from, to, format)
+		if err != nil {
+			return err
+		}
+		churnedRows, err := s.repo.CountChurnedSubscriptions(from, to, format)
+		if err != nil {
+			return err
+		}
+		canceledRows, err := s.repo.CountCanceledSubscriptions(from, to, format)
+		if err != nil {
+			return err
+		}
+
+		points := make(map[string]*dto.SubscriptionFlowPointResponse)
+		point := func(period string) *dto.SubscriptionFlowPointResponse {
+			if points[period] == nil {
+				points[period] = &dto.SubscriptionFlowPointResponse{Period: period}
+			}
+			return points[period]
+		}
+		for _, row := range newRows {
+			point(row.Period).New = row.Total
+		}
+		for _, row := range churnedRows {
+			point(row.Period).Churned = row.Total
+		}
+		for _, row := range canceledRows {
+			point(row.Period).Canceled = row.Total
+		}
+
+		res.Points = make([]dto.SubscriptionFlowPointResponse, 0, len(points))
+		for _, p := range points {
+			res.Points = append(res.Points, *p)
+		}
+		sort.Slice(res.Points, func(i, j int) bool { return res.Points[i].Period < res.Points[j].Period })
+		return nil
+	})
+	return &res, err
+}
+
+func (s *metricsService) GetConversion(q dto.MetricsQuery) (*dto.ConversionResponse, error) {
+	from, to, _, err := parseMetricsQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	res := dto.ConversionResponse{From: from.Format("2006-01-02"), To: to.Format("2006-01-02")}
+	key := fmt.Sprintf("conversion:%s:%s", from.Format("20060102"), to.Format("20060102"))
+	err = cachedMetric(key, &res, func() error {
+		registered, paying, err := s.repo.CountRegisteredUsers(from, to)
+		if err != nil {
+			return err
+		}
+		res.RegisteredUsers = registered
+		res.PayingUsers = paying
+		if registered > 0 {
+			res.ConversionRate = float64(paying) / float64(registered)
+		}
+		return nil
+	})
+	return &res, err
+}
+
+func (s *metricsService) GetTokenConsumption(q dto.MetricsQuery) (*dto.TokenConsumptionResponse, error) {
+	from, to, interval, err := parseMetricsQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	res := dto.TokenConsumptionResponse{Interval: interval}
+	key := fmt.Sprintf("tokens:%s:%s:%s", interval, from.Format("20060102"), to.Format("20060102"))
+	err = cachedMetric(key, &res, func() error {
+		rows, err := s.repo.GetTokenConsumptionByTier(from, to, periodFormats[interval])
+		if err != nil {
+			return err
+		}
+
+		res.Points = make([]dto.TokenConsumptionPointResponse, 0, len(rows))
+		for _, row := range rows {
+			res.Points = append(res.Points, dto.TokenConsumptionPointResponse{
+				Period:   row.Period,
+				TierName: row.TierName,
+				Tokens:   row.Tokens,
+			})
+		}
+		return nil
+	})
+	return &res, err
+}
+
+// parseMetricsQuery mengembalikan rentang waktu [from, to] (default 12 bulan terakhir) dan interval.
+func parseMetricsQuery(q dto.MetricsQuery) (time.Time, time.Time, string, error) {
+	interval := q.Interval
+	if interval == "" {
+		interval = "month"
+	}
+	if _, ok := periodFormats[interval]; !ok {
+		return time.Time{}, time.Time{}, "", errors.New("invalid interval")
+	}
+
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if q.To != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", q.To, now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, "", errors.New("invalid to date, expected YYYY-MM-DD")
+		}
+		to = parsed
+	}
+	// tanggal akhir bersifat inklusif
+	to = to.Add(24*time.Hour - time.Nanosecond)
+
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, -11, 0)
+	if q.From != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", q.From, now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, "", errors.New("invalid from date, expected YYYY-MM-DD")
+		}
+		from = parsed
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, "", errors.New("from date must be before to date")
+	}
+	return from, to, interval, nil
+}
+
+// cachedMetric membaca hasil dari Redis, atau menghitung lewat compute lalu menyimpannya.
+// Redis yang tidak tersedia tidak menggagalkan request, metrik dihitung langsung dari database.
+func cachedMetric(key string, out interface{}, compute func() error) error {
+	key = metricsCachePrefix + key
+
+	if cached, err := config.RedisClient.Get(config.Ctx, key).Bytes(); err == nil {
+		if json.Unmarshal(cached, out) == nil {
+			return nil
+		}
+	}
+
+	if err := compute(); err != nil {
+		return err
+	}
+
+	if data, err := json.Marshal(out); err == nil {
+		if err := config.RedisClient.Set(config.Ctx, key, data, metricsCacheTTL).Err(); err != nil {
+			log.Printf("metrics: failed to cache %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// invalidateMetricsCache menghapus seluruh cache metrik, dipanggil setiap ada event payment
+// (lunas, gagal, refund) agar angka pendapatan dan langganan langsung mutakhir.
+func invalidateMetricsCache() {
+	iter := config.RedisClient.Scan(config.Ctx, 0, metricsCachePrefix+"*", 100).Iterator()
+	var keys []string
+	for iter.Next(config.Ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		log.Printf("metrics: failed to scan cache keys: %v", err)
+		return
+	}
+	if len(keys) == 0 {
+		return
+	}
+	if err := config.RedisClient.Del(config.Ctx, keys...).Err(); err != nil {
+		log.Printf("metrics: failed to invalidate cache: %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"server/internal/config"
+	"server/internal/dto"
+	"server/internal/models"
+	"server/internal/oidc"
+	"server/internal/utils"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const oidcStateTTL = 10 * time.Minute
+
+var ErrOIDCEmailNotVerified = errors.New("login provider did not return a verified email")
+
+// oidcLoginState disimpan di Redis selama redirect ke provider; dihapus saat callback (sekali pakai).
+type oidcLoginState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"codeVerifier"`
+}
+
+func (s *authService) GetOIDCProviders() []dto.OIDCProviderResponse {
+	result := make([]dto.OIDCProviderResponse, 0, len(s.providers))
+	for name := range s.providers {
+		result = append(result, dto.OIDCProviderResponse{
+			Name:     name,
+			LoginURL: "/api/v1/auth/oidc/" + name + "/login",
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// StartOIDCLogin membuat state, nonce dan PKCE verifier lalu mengembalikan URL authorization provider.
+func (s *authService) StartOIDCLogin(provider string) (string, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return "", oidc.ErrUnknownProvider
+	}
+
+	state, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	verifier, err := utils.GenerateRandomToken(48)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(oidcLoginState{Provider: provider, Nonce: nonce, CodeVerifier: verifier})
+	if err != nil {
+		return "", err
+	}
+	if err := config.RedisClient.Set(config.Ctx, "oidc:state:"+utils.HashToken(state), data, oidcStateTTL).Err(); err != nil {
+		return "", err
+	}
+
+	return p.AuthCodeURL(config.Ctx, state, nonce, oidc.CodeChallenge(verifier))
+}
+
+// CompleteOIDCLogin memverifikasi callback provider lalu login ke user yang terhubung. Identity baru
+// di-link ke user dengan email yang sama hanya jika provider menyatakan email tersebut terverifikasi.
+func (s *authService) CompleteOIDCLogin(provider string, req *dto.OIDCCallbackRequest, meta dto.RequestMeta) (*dto.AuthResponse, *dto.TwoFactorChallengeResponse, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return nil, nil, oidc.ErrUnknownProvider
+	}
+
+	key := "oidc:state:" + utils.HashToken(req.State)
+	data, err := config.RedisClient.Get(config.Ctx, key).Bytes()
+	if err != nil {
+		return nil, nil, errors.New("login session expired or invalid state")
+	}
+	config.RedisClient.Del(config.Ctx, key)
+
+	var state oidcLoginState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, nil, err
+	}
+	if state.Provider != provider {
+		return nil, nil, errors.New("login session expired or invalid state")
+	}
+
+	claims, err := p.Exchange(config.Ctx, req.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.resolveOIDCUser(provider, claims)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if user.TwoFactorEnabled {
+		challenge, err := s.createTwoFactorChallenge(user)
+		return nil, challenge, err
+	}
+
+	tokens, err := s.issueTokens(user, uuid.New(), time.Now(), meta)
+	return tokens, nil, err
+}
+
+func (s *authService) resolveOIDCUser(provider string, claims *oidc.Claims) (*models.User, error) {
+	now := time.Now()
+
+	identity, err := s.repo.GetIdentity(provider, claims.Subject)
+	if err == nil {
+		s.repo.TouchIdentity(identity.ID.String(), now)
+		return s.repo.GetUserByID(identity.UserID.String())
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	email := normalizeEmail(claims.Email)
+	if email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	identity = &models.UserIdentity{
+		ID:          uuid.New(),
+		Provider:    provider,
+		Subject:     claims.Subject,
+		Email:       email,
+		LastLoginAt: &now,
+	}
+
+	user, err := s.repo.GetUserByEmail(email)
+	if err == nil {
+		identity.UserID = user.ID
+		if err := s.repo.CreateIdentity(identity); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// user baru: password acak yang tidak diketahui siapa pun, bisa diganti lewat forgot password
+	randomPassword, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := utils.HashPassword(randomPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	fullname := strings.TrimSpace(claims.Name)
+	if fullname == "" {
+		fullname = strings.Split(email, "@")[0]
+	}
+	avatar := claims.Picture
+	if avatar == "" {
+		avatar = utils.RandomUserAvatar(fullname)
+	}
+
+	user = &models.User{
+		ID:       uuid.New(),
+		Email:    email,
+		Password: hashedPassword,
+		Role:     RoleUser,
+		Fullname: fullname,
+		Avatar:   avatar,
+	}
+	identity.UserID = user.ID
+
+	if err := s.repo.CreateUserWithIdentity(user, identity); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -2,62 +2,155 @@ package services
 
 import (
 	"errors"
-	"server/internal/config"
+	"fmt"
+	"html"
+	"log"
 	"server/internal/dto"
+	"server/internal/gateway"
 	"server/internal/models"
 	"server/internal/repositories"
 	"server/internal/utils"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/midtrans/midtrans-go"
-	"github.com/midtrans/midtrans-go/snap"
 )
 
+var ErrSimulationUnavailable = errors.New("payment simulation is only available with the fake gateway")
+
+// VoucherError dikembalikan ketika voucher tidak bisa dipakai untuk checkout (tidak valid, kedaluwarsa,
+// bukan untuk tier tersebut, atau kuotanya habis).
+type VoucherError struct {
+	Reason string
+}
+
+func (e *VoucherError) Error() string {
+	return e.Reason
+}
+
 type PaymentService interface {
-	CreatePayment(userID string, req dto.CreatePaymentRequest) (*dto.CreatePaymentResponse, error)
-	HandlePaymentNotification(req dto.MidtransNotificationRequest) error
+	CreatePayment(userID string, req dto.CreatePaymentRequest, meta dto.RequestMeta) (*dto.CreatePaymentResponse, error)
+	HandlePaymentNotification(payload []byte, meta dto.RequestMeta) error
+	SimulatePayment(orderID, event string, meta dto.RequestMeta) error
+	GetGatewayStatus(orderID string) (*gateway.Notification, error)
 	GetAllUserPayments(query string, page, limit int) (*dto.PaymentListResponse, error)
 	GetPaymentByID(id string) (*dto.PaymentDetailResponse, error)
+	ExpirePendingPayments() (int64, error)
+	ReconcilePayments() (int64, error)
+	GetPaymentMismatches(resolved *bool, page, limit int) (*dto.PaymentMismatchListResponse, error)
+	ResolvePaymentMismatch(id uint, note string, meta dto.RequestMeta) error
+	RefundPayment(adminID, paymentID string, req dto.RefundPaymentRequest, meta dto.RequestMeta) (*dto.RefundResponse, error)
 }
 
 type paymentService struct {
 	repo     repositories.PaymentRepository
 	tierRepo repositories.SubscriptionRepository
 	authRepo repositories.AuthRepository
+	gateway  gateway.PaymentGateway
+	audit    AuditService
 }
 
 func NewPaymentService(
 	repo repositories.PaymentRepository,
 	tierRepo repositories.SubscriptionRepository,
 	authRepo repositories.AuthRepository,
+	gw gateway.PaymentGateway,
+	audit AuditService,
 ) PaymentService {
-	return &paymentService{repo, tierRepo, authRepo}
+	return &paymentService{repo, tierRepo, authRepo, gw, audit}
 }
 
-func (s *paymentService) CreatePayment(userID string, req dto.CreatePaymentRequest) (*dto.CreatePaymentResponse, error) {
+func (s *paymentService) CreatePayment(userID string, req dto.CreatePaymentRequest, meta dto.RequestMeta) (*dto.CreatePaymentResponse, error) {
 	tier, err := s.tierRepo.GetTierByID(req.TierID)
 	if err != nil {
 		return nil, errors.New("subscription tier not found")
 	}
 
-	tax := tier.Price * utils.GetTaxRate()
-	total := tier.Price + tax
+	var voucher *models.Voucher
+	if req.VoucherCode != nil && strings.TrimSpace(*req.VoucherCode) != "" {
+		voucher, err = s.validateVoucher(*req.VoucherCode, userID, tier.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	current, err := s.tierRepo.FindUserSubscriptionByID(userID)
+	if err != nil {
+		current = nil
+	}
+	changeType, credit := quotePlanChange(current, tier, time.Now())
+
+	// kredit prorata dikurangkan lebih dulu, lalu diskon voucher, dan pajak dihitung setelah keduanya
+	discount := 0.0
+	if voucher != nil {
+		discount = calculateDiscount(voucher, tier.Price-credit)
+	}
+	taxable := tier.Price - credit - discount
+	tax := taxable * utils.GetTaxRate()
+	total := taxable + tax
 	paymentID := uuid.New()
 
 	payment := &models.Payment{
 		ID:       paymentID,
 		UserID:   uuid.MustParse(userID),
 		TierID:   tier.ID,
+		Type:     changeType,
 		Subtotal: tier.Price,
+		Credit:   credit,
+		Discount: discount,
 		Tax:      tax,
 		Total:    total,
 		Method:   req.Method,
 		Status:   "pending",
 	}
 
-	if err := s.repo.CreatePayment(payment); err != nil {
-		return nil, err
+	if voucher != nil {
+		payment.VoucherID = &voucher.ID
+		redemption := &models.VoucherRedemption{
+			VoucherID: voucher.ID,
+			UserID:    payment.UserID,
+			Amount:    discount,
+		}
+		if err := s.repo.CreatePaymentWithVoucher(payment, redemption); err != nil {
+			if errors.Is(err, repositories.ErrVoucherLimitReached) || errors.Is(err, repositories.ErrVoucherUserLimitReached) {
+				return nil, &VoucherError{err.Error()}
+			}
+			return nil, err
+		}
+	} else {
+		if err := s.repo.CreatePayment(payment); err != nil {
+			return nil, err
+		}
+	}
+
+	res := &dto.CreatePaymentResponse{
+		PaymentID: paymentID.String(),
+		Type:      payment.Type,
+		Subtotal:  payment.Subtotal,
+		Credit:    payment.Credit,
+		Discount:  payment.Discount,
+		Tax:       payment.Tax,
+		Total:     payment.Total,
+	}
+
+	// kredit dan diskon menutup seluruh harga: Midtrans menolak gross amount 0, jadi payment langsung
+	// dilunasi tanpa checkout di gateway
+	if int64(total) <= 0 {
+		s.audit.Record(meta, AuditEntry{
+			Action: "payment.created", EntityType: AuditEntityPayment, EntityID: paymentID.String(),
+			After: auditPaymentState(payment),
+		})
+		before := auditPaymentState(payment)
+		if err := s.markPaid(payment, time.Now()); err != nil {
+			s.abandonPayment(payment)
+			return nil, err
+		}
+		invalidateMetricsCache()
+		s.recordPaymentStatus(meta, payment, before)
+		res.Type = payment.Type
+		res.Status = payment.Status
+		return res, nil
 	}
 
 	user, err := s.authRepo.GetUserByID(userID)
@@ -65,71 +158,706 @@ func (s *paymentService) CreatePayment(userID string, req dto.CreatePaymentReque
 		return nil, err
 	}
 
-	snapReq := &snap.Request{
-		TransactionDetails: midtrans.TransactionDetails{
-			OrderID:  paymentID.String(),
-			GrossAmt: int64(total),
-		},
-		CustomerDetail: &midtrans.CustomerDetails{
-			Email: user.Email,
-		},
+	checkout, err := s.gateway.CreateCheckout(&gateway.CheckoutRequest{
+		OrderID:       paymentID.String(),
+		Amount:        int64(total),
+		CustomerName:  user.Fullname,
+		CustomerEmail: user.Email,
+	})
+	if err != nil {
+		s.abandonPayment(payment)
+		return nil, fmt.Errorf("failed to create checkout: %w", err)
 	}
 
-	snapResp, err := config.SnapClient.CreateTransaction(snapReq)
+	s.audit.Record(meta, AuditEntry{
+		Action: "payment.created", EntityType: AuditEntityPayment, EntityID: paymentID.String(),
+		After: auditPaymentState(payment),
+	})
 
-	return &dto.CreatePaymentResponse{
-		PaymentID: paymentID.String(),
-		SnapToken: snapResp.Token,
-		SnapURL:   snapResp.RedirectURL,
-	}, nil
+	res.Status = payment.Status
+	res.SnapToken = checkout.Token
+	res.SnapURL = checkout.RedirectURL
+	return res, nil
+}
+
+// abandonPayment menggagalkan payment yang tidak bisa diselesaikan saat checkout dan mengembalikan voucher-nya.
+func (s *paymentService) abandonPayment(payment *models.Payment) {
+	payment.Status = "failed"
+	payment.PaidAt = nil
+	if err := s.repo.FailPayment(payment); err != nil {
+		log.Printf("failed to mark payment %s as failed: %v", payment.ID, err)
+	}
+}
+
+// validateVoucher memeriksa status, masa berlaku, tier dan batas pemakaian voucher.
+// Batas pemakaian dicek ulang secara atomik saat payment disimpan.
+func (s *paymentService) validateVoucher(code, userID string, tierID uint) (*models.Voucher, error) {
+	voucher, err := s.tierRepo.GetVoucherByCode(normalizeVoucherCode(code))
+	if err != nil || !voucher.IsActive {
+		return nil, &VoucherError{"invalid voucher code"}
+	}
+
+	now := time.Now()
+	if voucher.StartsAt != nil && now.Before(*voucher.StartsAt) {
+		return nil, &VoucherError{"voucher is not yet valid"}
+	}
+	if voucher.ExpiresAt != nil && now.After(*voucher.ExpiresAt) {
+		return nil, &VoucherError{"voucher has expired"}
+	}
+
+	if len(voucher.Tiers) > 0 {
+		allowed := false
+		for _, t := range voucher.Tiers {
+			if t.ID == tierID {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil, &VoucherError{"voucher is not valid for this subscription tier"}
+		}
+	}
+
+	if voucher.MaxRedemptions != nil && voucher.RedeemedCount >= *voucher.MaxRedemptions {
+		return nil, &VoucherError{"voucher redemption limit reached"}
+	}
+
+	if voucher.MaxRedemptionsPerUser != nil {
+		used, err := s.tierRepo.CountUserRedemptions(voucher.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if used >= int64(*voucher.MaxRedemptionsPerUser) {
+			return nil, &VoucherError{"voucher already used the maximum number of times"}
+		}
+	}
+
+	return voucher, nil
+}
+
+func calculateDiscount(voucher *models.Voucher, price float64) float64 {
+	var discount float64
+	switch voucher.DiscountType {
+	case "percentage":
+		discount = price * voucher.DiscountValue / 100
+		if voucher.MaxDiscount != nil && discount > *voucher.MaxDiscount {
+			discount = *voucher.MaxDiscount
+		}
+	case "fixed":
+		discount = voucher.DiscountValue
+	}
+
+	if discount > price {
+		discount = price
+	}
+	return discount
+}
+
+// HandlePaymentNotification memverifikasi payload webhook lewat gateway sebelum status payment diubah.
+func (s *paymentService) HandlePaymentNotification(payload []byte, meta dto.RequestMeta) error {
+	notif, err := s.gateway.ParseNotification(payload)
+	if err != nil {
+		return err
+	}
+	return s.applyNotification(notif, meta)
+}
+
+// SimulatePayment memicu callback tiruan (settle, expire, deny, cancel) dari fake gateway
+// dan memprosesnya lewat jalur webhook yang sama.
+func (s *paymentService) SimulatePayment(orderID, event string, meta dto.RequestMeta) error {
+	simulator, ok := s.gateway.(gateway.Simulator)
+	if !ok {
+		return ErrSimulationUnavailable
+	}
+
+	payload, err := simulator.Simulate(orderID, event)
+	if err != nil {
+		return err
+	}
+	return s.HandlePaymentNotification(payload, meta)
+}
+
+func (s *paymentService) GetGatewayStatus(orderID string) (*gateway.Notification, error) {
+	if _, ok := s.gateway.(gateway.Simulator); !ok {
+		return nil, ErrSimulationUnavailable
+	}
+	return s.gateway.QueryStatus(orderID)
 }
 
-func (s *paymentService) HandlePaymentNotification(req dto.MidtransNotificationRequest) error {
+func (s *paymentService) applyNotification(req *gateway.Notification, meta dto.RequestMeta) error {
 	payment, err := s.repo.GetPaymentByOrderID(req.OrderID)
 	if err != nil {
 		return err
 	}
 
-	if payment.Status == "success" {
-		// avoid duplicate processing
+	if req.GrossAmount != "" {
+		amount, err := strconv.ParseFloat(req.GrossAmount, 64)
+		if err != nil || int64(amount) != int64(payment.Total) {
+			return errors.New("gross amount does not match payment total")
+		}
+	}
+
+	if payment.Status != "pending" {
+		// paid, refunded dan failed bersifat final: notifikasi ganda atau terlambat diabaikan, termasuk
+		// pelunasan untuk payment gagal yang voucher-nya sudah dikembalikan
 		return nil
 	}
 
+	before := auditPaymentState(payment)
 	payment.Method = req.PaymentType
 
-	switch req.TransactionStatus {
+	switch gatewayOutcome(req) {
+	case "paid":
+		err = s.markPaid(payment, time.Now())
+	case "pending":
+		return nil
+	default:
+		payment.Status = "failed"
+		err = s.repo.FailPayment(payment)
+	}
+	if errors.Is(err, repositories.ErrPaymentNotPending) {
+		// sudah diproses oleh webhook, rekonsiliasi atau job expire yang berjalan bersamaan
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	invalidateMetricsCache()
+	s.recordPaymentStatus(meta, payment, before)
+	return nil
+}
+
+func (s *paymentService) recordPaymentStatus(meta dto.RequestMeta, payment *models.Payment, before map[string]any) {
+	if before["status"] == payment.Status {
+		return
+	}
+	s.audit.Record(meta, AuditEntry{
+		Action: "payment.status_changed", EntityType: AuditEntityPayment, EntityID: payment.ID.String(),
+		Before: before, After: auditPaymentState(payment),
+	})
+}
+
+// auditPaymentState adalah ringkasan payment untuk audit log, tanpa relasi user/tier.
+func auditPaymentState(p *models.Payment) map[string]any {
+	state := map[string]any{
+		"status":   p.Status,
+		"type":     p.Type,
+		"tierId":   p.TierID,
+		"total":    p.Total,
+		"refunded": p.Refunded,
+		"method":   p.Method,
+	}
+	if p.PaidAt != nil {
+		state["paidAt"] = p.PaidAt.Format(time.RFC3339)
+	}
+	return state
+}
+
+// gatewayOutcome memetakan status transaksi gateway ke status payment lokal. Dipakai oleh webhook
+// maupun rekonsiliasi agar transisinya selalu sama. Capture dengan fraud challenge/deny tetap pending.
+func gatewayOutcome(n *gateway.Notification) string {
+	switch n.TransactionStatus {
 	case "settlement", "capture":
-		if req.FraudStatus == "accept" || req.FraudStatus == "" {
-			payment.Status = "success"
-			now := time.Now()
-			payment.PaidAt = &now
-
-			// Buat user subscription setelah pembayaran sukses
-			tier, err := s.tierRepo.GetTierByID(payment.TierID)
-			if err != nil {
-				return err
-			}
+		if n.FraudStatus == "accept" || n.FraudStatus == "" {
+			return "paid"
+		}
+		return "pending"
+	case "pending":
+		return "pending"
+	default:
+		return "failed"
+	}
+}
+
+// markPaid melunasi payment: paket diterapkan ke langganan user, lalu payment, langganan dan invoice-nya
+// disimpan dalam satu transaksi.
+func (s *paymentService) markPaid(payment *models.Payment, now time.Time) error {
+	payment.Status = "paid"
+	payment.PaidAt = &now
+
+	subscription, err := s.applySubscriptionChange(payment, now)
+	if err != nil {
+		return err
+	}
+	return s.settlePayment(payment, subscription, now)
+}
+
+// applySubscriptionChange menghitung langganan user setelah paket dibayar: membuat langganan baru,
+// memperpanjang (renewal), mengganti tier saat itu juga (upgrade), atau menjadwalkan downgrade.
+// Hasilnya disimpan oleh SettlePayment bersama payment-nya.
+func (s *paymentService) applySubscriptionChange(payment *models.Payment, now time.Time) (*models.UserSubscription, error) {
+	tier, err := s.tierRepo.GetTierByID(payment.TierID)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.tierRepo.FindUserSubscriptionByID(payment.UserID.String())
+	if err != nil {
+		// user belum pernah berlangganan
+		payment.Type = "new"
+		return &models.UserSubscription{
+			UserID:             payment.UserID,
+			SubscriptionTierID: tier.ID,
+			StartedAt:          now,
+			ExpiresAt:          now.AddDate(0, 0, tier.Duration), // durasi dalam hari
+			IsActive:           true,
+			AutoRenew:          true,
+			RemainingTokens:    tier.TokenLimit,
+			TokensResetAt:      &now,
+		}, nil
+	}
+
+	// status langganan bisa berubah antara checkout dan pelunasan, jadi jenis perubahan dihitung ulang
+	changeType, _ := quotePlanChange(current, tier, now)
+	payment.Type = changeType
+
+	switch changeType {
+	case "renewal":
+		current.ExpiresAt = current.ExpiresAt.AddDate(0, 0, tier.Duration)
+		current.RemainingTokens = nextTokenBalance(current.RemainingTokens, tier.TokenLimit)
+		current.ScheduledTierID = nil
+	case "upgrade":
+		current.SubscriptionTierID = tier.ID
+		current.StartedAt = now
+		current.ExpiresAt = now.AddDate(0, 0, tier.Duration)
+		current.RemainingTokens = nextTokenBalance(current.RemainingTokens, tier.TokenLimit)
+		current.TokensResetAt = &now
+		current.ScheduledTierID = nil
+	case "downgrade":
+		current.ScheduledTierID = &tier.ID
+	default:
+		// langganan lama sudah tidak aktif, mulai periode baru
+		current.SubscriptionTierID = tier.ID
+		current.StartedAt = now
+		current.ExpiresAt = now.AddDate(0, 0, tier.Duration)
+		current.IsActive = true
+		current.RemainingTokens = tier.TokenLimit
+		current.TokensResetAt = &now
+		current.ScheduledTierID = nil
+	}
+
+	// membayar paket lagi berarti user ingin melanjutkan langganan
+	current.AutoRenew = true
+	current.CanceledAt = nil
+
+	return current, nil
+}
+
+// settlePayment menyimpan payment yang lunas beserta langganan dan invoice-nya, lalu mengirim struk ke email user.
+func (s *paymentService) settlePayment(payment *models.Payment, subscription *models.UserSubscription, paidAt time.Time) error {
+	user, err := s.authRepo.GetUserByID(payment.UserID.String())
+	if err != nil {
+		return err
+	}
+	tier, err := s.tierRepo.GetTierByID(payment.TierID)
+	if err != nil {
+		return err
+	}
+
+	taxRate := 0.0
+	if taxable := payment.Subtotal - payment.Credit - payment.Discount; taxable > 0 {
+		taxRate = payment.Tax / taxable
+	}
+
+	invoice := &models.Invoice{
+		ID:            uuid.New(),
+		PaymentID:     payment.ID,
+		UserID:        payment.UserID,
+		CustomerName:  user.Fullname,
+		CustomerEmail: user.Email,
+		TierName:      tier.Name,
+		Method:        payment.Method,
+		Subtotal:      payment.Subtotal,
+		Credit:        payment.Credit,
+		Discount:      payment.Discount,
+		TaxRate:       taxRate,
+		Tax:           payment.Tax,
+		Total:         payment.Total,
+		IssuedAt:      paidAt,
+	}
+	if err := s.repo.SettlePayment(payment, subscription, invoice); err != nil {
+		return err
+	}
+
+	go s.sendReceipt(invoice)
+	return nil
+}
+
+func (s *paymentService) sendReceipt(invoice *models.Invoice) {
+	pdf, err := utils.GenerateInvoicePDF(invoice)
+	if err != nil {
+		log.Printf("failed to generate invoice %s: %v", invoice.Number, err)
+		return
+	}
+
+	subject := fmt.Sprintf("Payment receipt %s", invoice.Number)
+	body := fmt.Sprintf("Hi %s, thank you for your payment of %s for the %s subscription. Your invoice %s is attached.",
+		invoice.CustomerName, utils.FormatRupiah(invoice.Total), invoice.TierName, invoice.Number)
+	htmlBody := fmt.Sprintf("<p>Hi %s,</p><p>Thank you for your payment of <b>%s</b> for the <b>%s</b> subscription.</p><p>Your invoice <b>%s</b> is attached.</p>",
+		html.EscapeString(invoice.CustomerName), utils.FormatRupiah(invoice.Total), html.EscapeString(invoice.TierName), invoice.Number)
+
+	if err := utils.SendEmailWithAttachment(subject, invoice.CustomerEmail, body, htmlBody, invoice.Number+".pdf", pdf); err != nil {
+		log.Printf("failed to send receipt %s: %v", invoice.Number, err)
+	}
+}
+
+// ExpirePendingPayments menggagalkan payment pending yang lebih dari 24 jam. Status di gateway dicek lebih
+// dulu: payment yang ternyata sudah lunas diselesaikan seperti webhook, yang masih pending di gateway
+// dibiarkan, dan hanya yang gagal/kedaluwarsa atau tidak pernah dibuat di gateway yang digagalkan.
+func (s *paymentService) ExpirePendingPayments() (int64, error) {
+	payments, err := s.repo.FindStalePendingPayments(time.Now().Add(-24*time.Hour), 200)
+	if err != nil {
+		return 0, err
+	}
+
+	var expired int64
+	var unknown []string
+	defer s.markChecked(payments)
+	for i := range payments {
+		payment := &payments[i]
+
+		status, err := s.gateway.QueryStatus(payment.ID.String())
+		if errors.Is(err, gateway.ErrTransactionNotFound) {
+			// checkout tidak pernah dibuka, tidak ada yang bisa lunas di gateway
+			unknown = append(unknown, payment.ID.String())
+			continue
+		}
+		if err != nil {
+			log.Printf("expire: failed to query payment %s: %v", payment.ID, err)
+			continue
+		}
+
+		outcome := gatewayOutcome(status)
+		if outcome == "pending" {
+			continue
+		}
+		if err := s.applyNotification(status, dto.RequestMeta{}); err != nil {
+			log.Printf("expire: failed to apply gateway status for payment %s: %v", payment.ID, err)
+			continue
+		}
+		if outcome == "failed" {
+			expired++
+		}
+	}
+
+	count, err := s.repo.ExpirePendingPayments(unknown)
+	expired += count
+	if expired > 0 {
+		s.audit.Record(dto.RequestMeta{}, AuditEntry{
+			Action: "payment.pending_expired", EntityType: AuditEntityPayment, EntityID: "*",
+			After: map[string]any{"count": expired},
+		})
+	}
+	return expired, err
+}
+
+// ReconcilePayments mencocokkan payment pending yang tertahan dan payment yang baru lunas dengan status
+// di gateway. Payment pending mengikuti status gateway lewat transisi yang sama seperti webhook,
+// sedangkan payment lunas yang tidak lunas di gateway hanya dilaporkan untuk ditangani admin.
+func (s *paymentService) ReconcilePayments() (int64, error) {
+	now := time.Now()
+	payments, err := s.repo.FindPaymentsToReconcile(now.Add(-utils.GetReconcileDelay()), now.Add(-24*time.Hour), 200)
+	if err != nil {
+		return 0, err
+	}
+
+	var mismatches int64
+	defer s.markChecked(payments)
+	for i := range payments {
+		payment := &payments[i]
 
-			subscription := &models.UserSubscription{
-				UserID:             payment.UserID,
-				SubscriptionTierID: tier.ID,
-				StartedAt:          now,
-				ExpiresAt:          now.AddDate(0, 0, tier.Duration), // durasi dalam hari
-				IsActive:           true,
-				RemainingTokens:    tier.TokenLimit,
+		status, err := s.gateway.QueryStatus(payment.ID.String())
+		if errors.Is(err, gateway.ErrTransactionNotFound) {
+			// pending tanpa transaksi berarti user belum membuka checkout, biarkan job expire yang menangani
+			if payment.Status == "paid" {
+				status = &gateway.Notification{OrderID: payment.ID.String(), TransactionStatus: "not_found"}
+			} else {
+				continue
 			}
+		} else if err != nil {
+			log.Printf("reconcile: failed to query payment %s: %v", payment.ID, err)
+			continue
+		}
+
+		mismatch := s.reconcilePayment(payment, status)
+		if mismatch == nil {
+			continue
+		}
+
+		open, err := s.repo.HasOpenMismatch(payment.ID.String())
+		if err != nil {
+			return mismatches, err
+		}
+		if open && !mismatch.Resolved {
+			continue
+		}
+		if err := s.repo.CreatePaymentMismatch(mismatch); err != nil {
+			return mismatches, err
+		}
+		mismatches++
+	}
+
+	return mismatches, nil
+}
+
+// markChecked mencatat waktu pengecekan agar batch berikutnya mendahulukan payment lain.
+func (s *paymentService) markChecked(payments []models.Payment) {
+	ids := make([]string, 0, len(payments))
+	for _, p := range payments {
+		ids = append(ids, p.ID.String())
+	}
+	if err := s.repo.MarkPaymentsChecked(ids, time.Now()); err != nil {
+		log.Printf("failed to mark payments as checked: %v", err)
+	}
+}
+
+func (s *paymentService) reconcilePayment(payment *models.Payment, status *gateway.Notification) *models.PaymentMismatch {
+	outcome := gatewayOutcome(status)
+	if status.TransactionStatus == "not_found" {
+		outcome = "failed"
+	}
+	if outcome == payment.Status || outcome == "pending" {
+		return nil
+	}
+
+	mismatch := &models.PaymentMismatch{
+		PaymentID:     payment.ID,
+		LocalStatus:   payment.Status,
+		GatewayStatus: status.TransactionStatus,
+	}
+
+	if payment.Status == "paid" {
+		mismatch.Kind = "paid_locally"
+		mismatch.Detail = "payment is paid locally but " + status.TransactionStatus + " at the gateway"
+		return mismatch
+	}
+
+	mismatch.Kind = "failed_at_gateway"
+	if outcome == "paid" {
+		mismatch.Kind = "paid_at_gateway"
+	}
+
+	// proses sistem, dicatat tanpa actor
+	if err := s.applyNotification(status, dto.RequestMeta{}); err != nil {
+		mismatch.Detail = "failed to apply gateway status: " + err.Error()
+		return mismatch
+	}
+
+	now := time.Now()
+	mismatch.Detail = "local status updated from gateway"
+	mismatch.Resolved = true
+	mismatch.ResolvedAt = &now
+	return mismatch
+}
+
+func (s *paymentService) GetPaymentMismatches(resolved *bool, page, limit int) (*dto.PaymentMismatchListResponse, error) {
+	offset := (page - 1) * limit
+
+	mismatches, total, err := s.repo.GetPaymentMismatches(resolved, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]dto.PaymentMismatchResponse, 0, len(mismatches))
+	for _, m := range mismatches {
+		resolvedAt := ""
+		if m.ResolvedAt != nil {
+			resolvedAt = m.ResolvedAt.Format("2006-01-02 15:04:05")
+		}
+		results = append(results, dto.PaymentMismatchResponse{
+			ID:            m.ID,
+			PaymentID:     m.PaymentID.String(),
+			UserEmail:     m.Payment.User.Email,
+			Kind:          m.Kind,
+			LocalStatus:   m.LocalStatus,
+			GatewayStatus: m.GatewayStatus,
+			Detail:        m.Detail,
+			Resolved:      m.Resolved,
+			ResolvedAt:    resolvedAt,
+			Note:          m.Note,
+			CreatedAt:     m.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	return &dto.PaymentMismatchListResponse{
+		Mismatches: results,
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+	}, nil
+}
+
+func (s *paymentService) ResolvePaymentMismatch(id uint, note string, meta dto.RequestMeta) error {
+	if err := s.repo.ResolvePaymentMismatch(id, note); err != nil {
+		return err
+	}
+	s.audit.Record(meta, AuditEntry{
+		Action: "payment.mismatch_resolved", EntityType: AuditEntityMismatch, EntityID: fmt.Sprint(id),
+		Before: map[string]any{"resolved": false}, After: map[string]any{"resolved": true, "note": note},
+	})
+	return nil
+}
+
+// RefundPayment mengirim refund penuh/sebagian ke gateway, lalu mencabut atau memperpendek langganan
+// yang dibeli dengan payment tersebut dan mencatatnya di ledger refund.
+func (s *paymentService) RefundPayment(adminID, paymentID string, req dto.RefundPaymentRequest, meta dto.RequestMeta) (*dto.RefundResponse, error) {
+	payment, err := s.repo.GetPaymentByID(paymentID)
+	if err != nil {
+		return nil, errors.New("payment not found")
+	}
+	if payment.Status != "paid" && payment.Status != "partially_refunded" {
+		return nil, errors.New("only paid payments can be refunded")
+	}
+
+	refundable := payment.Total - payment.Refunded
+	amount := refundable
+	if req.Amount != nil {
+		amount = *req.Amount
+	}
+	if amount <= 0 || amount > refundable {
+		return nil, fmt.Errorf("refund amount must be between 0 and %.2f", refundable)
+	}
+
+	// refund dicatat lebih dulu sebagai pending agar refund yang sudah diterima gateway tidak hilang bila
+	// penyimpanan berikutnya gagal. Refund pending dari percobaan sebelumnya dilanjutkan dengan refund key
+	// yang sama, sehingga gateway tidak memproses refund ganda.
+	refund, err := s.repo.FindPendingRefund(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	resumed := refund != nil
+	if resumed {
+		if req.Amount != nil && *req.Amount != refund.Amount {
+			return nil, fmt.Errorf("refund %s of %.2f is still pending, retry with the same amount", refund.RefundKey, refund.Amount)
+		}
+		amount = refund.Amount
+		if amount > refundable {
+			return nil, fmt.Errorf("pending refund %s exceeds the refundable amount", refund.RefundKey)
+		}
+	} else {
+		refundType := "partial"
+		if amount == refundable {
+			refundType = "full"
+		}
+
+		// refund key deterministik per urutan refund, sehingga refund bersamaan untuk payment yang sama
+		// ditolak oleh unique index
+		count, err := s.repo.CountRefunds(paymentID)
+		if err != nil {
+			return nil, err
+		}
+		refund = &models.Refund{
+			PaymentID:          payment.ID,
+			UserID:             payment.UserID,
+			AdminID:            uuid.MustParse(adminID),
+			RefundKey:          fmt.Sprintf("refund-%s-%d", payment.ID, count+1),
+			Type:               refundType,
+			Amount:             amount,
+			Reason:             req.Reason,
+			SubscriptionAction: "none",
+			Status:             "pending",
+		}
+		if err := s.repo.CreateRefund(refund); err != nil {
+			return nil, fmt.Errorf("failed to record refund: %w", err)
+		}
+	}
 
-			if err := s.repo.CreateUserSubscription(subscription); err != nil {
-				return err
+	if err := s.gateway.Refund(&gateway.RefundRequest{
+		OrderID:   payment.ID.String(),
+		RefundKey: refund.RefundKey,
+		Amount:    int64(amount),
+		Reason:    refund.Reason,
+	}); err != nil {
+		if !resumed {
+			if err := s.repo.DeleteRefund(refund.ID); err != nil {
+				log.Printf("failed to remove rejected refund %s: %v", refund.RefundKey, err)
 			}
 		}
-	case "pending":
-		payment.Status = "pending"
-	default:
-		payment.Status = "failed"
+		return nil, fmt.Errorf("gateway refund failed: %w", err)
+	}
+
+	full := refund.Type == "full"
+	before := auditPaymentState(payment)
+	payment.Refunded += amount
+	payment.Status = "partially_refunded"
+	if full {
+		payment.Status = "refunded"
+	}
+
+	subscription, action := s.adjustSubscriptionForRefund(payment, amount, full)
+	refund.SubscriptionAction = action
+	if err := s.repo.RecordRefund(payment, refund, subscription); err != nil {
+		log.Printf("refund %s accepted by gateway but failed to save, it stays pending until retried: %v", refund.RefundKey, err)
+		return nil, err
+	}
+	invalidateMetricsCache()
+
+	after := auditPaymentState(payment)
+	after["refundKey"] = refund.RefundKey
+	after["subscriptionAction"] = action
+	s.audit.Record(meta, AuditEntry{
+		Action: "payment.refunded", EntityType: AuditEntityPayment, EntityID: payment.ID.String(),
+		Before: before, After: after,
+	})
+
+	res := toRefundResponse(refund)
+	return &res, nil
+}
+
+// adjustSubscriptionForRefund menentukan perubahan langganan akibat refund. Refund penuh mencabut
+// akses (atau membatalkan downgrade terjadwal), refund sebagian memotong masa aktif secara proporsional.
+func (s *paymentService) adjustSubscriptionForRefund(payment *models.Payment, amount float64, full bool) (*models.UserSubscription, string) {
+	sub, err := s.tierRepo.FindUserSubscriptionByID(payment.UserID.String())
+	if err != nil {
+		return nil, "none"
+	}
+	now := time.Now()
+
+	if payment.Type == "downgrade" {
+		if full && sub.ScheduledTierID != nil && *sub.ScheduledTierID == payment.TierID {
+			sub.ScheduledTierID = nil
+			return sub, "schedule_canceled"
+		}
+		return nil, "none"
+	}
+
+	// payment lama yang tiernya sudah diganti tidak lagi menentukan langganan berjalan
+	if sub.SubscriptionTierID != payment.TierID || !sub.IsActive {
+		return nil, "none"
+	}
+
+	if !full {
+		tier, err := s.tierRepo.GetTierByID(payment.TierID)
+		if err != nil || payment.Total <= 0 {
+			return nil, "none"
+		}
+		cut := time.Duration(float64(tier.Duration) * 24 * float64(time.Hour) * amount / payment.Total)
+		sub.ExpiresAt = sub.ExpiresAt.Add(-cut)
+		if sub.ExpiresAt.After(now) {
+			return sub, "shortened"
+		}
 	}
 
-	return s.repo.UpdatePayment(payment)
+	sub.IsActive = false
+	sub.ExpiresAt = now
+	sub.RemainingTokens = 0
+	sub.ScheduledTierID = nil
+	sub.AutoRenew = false
+	return sub, "revoked"
+}
+
+func toRefundResponse(r *models.Refund) dto.RefundResponse {
+	return dto.RefundResponse{
+		ID:                 r.ID,
+		PaymentID:          r.PaymentID.String(),
+		Type:               r.Type,
+		Amount:             r.Amount,
+		Reason:             r.Reason,
+		SubscriptionAction: r.SubscriptionAction,
+		Status:             r.Status,
+		CreatedAt:          r.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
 }
 
 func (s *paymentService) GetPaymentByID(id string) (*dto.PaymentDetailResponse, error) {
@@ -137,16 +865,30 @@ func (s *paymentService) GetPaymentByID(id string) (*dto.PaymentDetailResponse,
 	if err != nil {
 		return nil, err
 	}
+	voucherCode := ""
+	if p.Voucher != nil {
+		voucherCode = p.Voucher.Code
+	}
+	refunds := make([]dto.RefundResponse, 0, len(p.Refunds))
+	for i := range p.Refunds {
+		refunds = append(refunds, toRefundResponse(&p.Refunds[i]))
+	}
 	return &dto.PaymentDetailResponse{
-		ID:       p.ID.String(),
-		UserID:   p.UserID.String(),
-		TierID:   p.TierID,
-		Subtotal: p.Subtotal,
-		Tax:      p.Tax,
-		Total:    p.Total,
-		Method:   p.Method,
-		Status:   p.Status,
-		PaidAt:   p.PaidAt.Format("2006-01-02 15:04:05"),
+		ID:          p.ID.String(),
+		UserID:      p.UserID.String(),
+		TierID:      p.TierID,
+		Type:        p.Type,
+		Subtotal:    p.Subtotal,
+		Credit:      p.Credit,
+		Discount:    p.Discount,
+		VoucherCode: voucherCode,
+		Tax:         p.Tax,
+		Total:       p.Total,
+		Refunded:    p.Refunded,
+		Method:      p.Method,
+		Status:      p.Status,
+		PaidAt:      formatPaidAt(p.PaidAt),
+		Refunds:     refunds,
 	}, nil
 }
 
@@ -167,12 +909,15 @@ func (s *paymentService) GetAllUserPayments(query string, page, limit int) (*dto
 			Fullname:      p.User.Fullname,
 			TierID:        p.TierID,
 			TierName:      p.Tier.Name,
+			Type:          p.Type,
 			Subtotal:      p.Subtotal,
+			Credit:        p.Credit,
+			Discount:      p.Discount,
 			Tax:           p.Tax,
 			Total:         p.Total,
 			PaymentMethod: p.Method,
 			Status:        p.Status,
-			PaidAt:        p.PaidAt.Format("2006-01-02 15:04:05"),
+			PaidAt:        formatPaidAt(p.PaidAt),
 		})
 	}
 
@@ -183,3 +928,11 @@ func (s *paymentService) GetAllUserPayments(query string, page, limit int) (*dto
 		Limit:    limit,
 	}, nil
 }
+
+// formatPaidAt mengembalikan string kosong untuk payment yang belum dibayar.
+func formatPaidAt(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"errors"
+	"server/internal/models"
+	"server/internal/repositories"
+	"strings"
+	"testing"
+	"time"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+func intPtr(v int) *int { return &v }
+
+func TestCalculateDiscount(t *testing.T) {
+	tests := []struct {
+		name    string
+		voucher models.Voucher
+		price   float64
+		want    float64
+	}{
+		{"percentage", models.Voucher{DiscountType: "percentage", DiscountValue: 20}, 100000, 20000},
+		{"percentage capped by max discount", models.Voucher{DiscountType: "percentage", DiscountValue: 50, MaxDiscount: floatPtr(30000)}, 100000, 30000},
+		{"percentage under max discount", models.Voucher{DiscountType: "percentage", DiscountValue: 10, MaxDiscount: floatPtr(30000)}, 100000, 10000},
+		{"fixed", models.Voucher{DiscountType: "fixed", DiscountValue: 25000}, 100000, 25000},
+		{"fixed larger than price", models.Voucher{DiscountType: "fixed", DiscountValue: 150000}, 100000, 100000},
+		{"full percentage", models.Voucher{DiscountType: "percentage", DiscountValue: 100}, 100000, 100000},
+		{"price already covered by credit", models.Voucher{DiscountType: "fixed", DiscountValue: 25000}, 0, 0},
+		{"unknown type", models.Voucher{DiscountType: "bogus", DiscountValue: 25000}, 100000, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calculateDiscount(&tt.voucher, tt.price); got != tt.want {
+				t.Errorf("calculateDiscount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeVoucherRepo hanya mengimplementasikan method yang dipakai validateVoucher.
+type fakeVoucherRepo struct {
+	repositories.SubscriptionRepository
+
+	vouchers map[string]*models.Voucher
+	used     int64
+}
+
+func (r *fakeVoucherRepo) GetVoucherByCode(code string) (*models.Voucher, error) {
+	v, ok := r.vouchers[code]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	return v, nil
+}
+
+func (r *fakeVoucherRepo) CountUserRedemptions(uint, string) (int64, error) {
+	return r.used, nil
+}
+
+func TestValidateVoucher(t *testing.T) {
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		voucher models.Voucher
+		code    string
+		tierID  uint
+		used    int64
+		wantErr string
+	}{
+		{name: "valid", voucher: models.Voucher{IsActive: true}, code: "promo", tierID: 1},
+		{name: "unknown code", voucher: models.Voucher{IsActive: true}, code: "NOPE", tierID: 1, wantErr: "invalid voucher code"},
+		{name: "inactive", voucher: models.Voucher{}, code: "PROMO", tierID: 1, wantErr: "invalid voucher code"},
+		{name: "not yet valid", voucher: models.Voucher{IsActive: true, StartsAt: &future}, code: "PROMO", tierID: 1, wantErr: "not yet valid"},
+		{name: "expired", voucher: models.Voucher{IsActive: true, ExpiresAt: &past}, code: "PROMO", tierID: 1, wantErr: "expired"},
+		{name: "within validity window", voucher: models.Voucher{IsActive: true, StartsAt: &past, ExpiresAt: &future}, code: "PROMO", tierID: 1},
+		{
+			name:    "restricted to other tiers",
+			voucher: models.Voucher{IsActive: true, Tiers: []models.SubscriptionTier{{ID: 2}, {ID: 3}}},
+			code:    "PROMO", tierID: 1, wantErr: "not valid for this subscription tier",
+		},
+		{
+			name:    "restricted to this tier",
+			voucher: models.Voucher{IsActive: true, Tiers: []models.SubscriptionTier{{ID: 1}}},
+			code:    "PROMO", tierID: 1,
+		},
+		{name: "global limit reached", voucher: models.Voucher{IsActive: true, MaxRedemptions: intPtr(5), RedeemedCount: 5}, code: "PROMO", tierID: 1, wantErr: "limit reached"},
+		{name: "per user limit reached", voucher: models.Voucher{IsActive: true, MaxRedemptionsPerUser: intPtr(1)}, code: "PROMO", tierID: 1, used: 1, wantErr: "maximum number of times"},
+		{name: "per user limit not reached", voucher: models.Voucher{IsActive: true, MaxRedemptionsPerUser: intPtr(2)}, code: "PROMO", tierID: 1, used: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeVoucherRepo{vouchers: map[string]*models.Voucher{"PROMO": &tt.voucher}, used: tt.used}
+			s := &paymentService{tierRepo: repo}
+
+			got, err := s.validateVoucher(tt.code, "user-1", tt.tierID)
+			if tt.wantErr == "" {
+				if err != nil || got != &tt.voucher {
+					t.Fatalf("validateVoucher() = %v, %v, want the voucher", got, err)
+				}
+				return
+			}
+
+			var voucherErr *VoucherError
+			if !errors.As(err, &voucherErr) {
+				t.Fatalf("err = %v, want a VoucherError", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %q, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
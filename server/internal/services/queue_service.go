@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"server/internal/dto"
+	"server/internal/models"
+	"server/internal/repositories"
+)
+
+const (
+	QueueWaiting  = "waiting"
+	QueueProgress = "progress"
+	QueueDone     = "done"
+)
+
+type QueueService interface {
+	GetAllQueue(userID, status string) ([]dto.QueueResponse, error)
+	ExecuteQueue(userID, responseID string) (*dto.QueueResponse, error)
+	CompleteQueue(userID, responseID string) (*dto.QueueResponse, error)
+}
+
+type queueService struct {
+	repo repositories.QueueRepository
+}
+
+func NewQueueService(repo repositories.QueueRepository) QueueService {
+	return &queueService{repo}
+}
+
+func (s *queueService) GetAllQueue(userID, status string) ([]dto.QueueResponse, error) {
+	queues, err := s.repo.FindByOwner(userID, status)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.QueueResponse, 0, len(queues))
+	for i := range queues {
+		result = append(result, toQueueResponse(&queues[i]))
+	}
+	return result, nil
+}
+
+// ExecuteQueue memanggil antrian berikutnya (waiting -> progress).
+func (s *queueService) ExecuteQueue(userID, responseID string) (*dto.QueueResponse, error) {
+	return s.transition(userID, responseID, QueueWaiting, QueueProgress)
+}
+
+// CompleteQueue menyelesaikan antrian yang sedang diproses (progress -> done).
+func (s *queueService) CompleteQueue(userID, responseID string) (*dto.QueueResponse, error) {
+	return s.transition(userID, responseID, QueueProgress, QueueDone)
+}
+
+func (s *queueService) transition(userID, responseID, from, to string) (*dto.QueueResponse, error) {
+	queue, err := s.repo.FindByResponseID(userID, responseID)
+	if err != nil {
+		return nil, errors.New("queue not found")
+	}
+	updated, err := s.repo.UpdateStatus(queue.ID.String(), from, to)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, errors.New("queue is not " + from)
+	}
+	queue.Status = to
+	res := toQueueResponse(queue)
+	return &res, nil
+}
+
+func toQueueResponse(q *models.Queue) dto.QueueResponse {
+	return dto.QueueResponse{
+		ID:          q.ID.String(),
+		ResponseID:  q.ResponseID.String(),
+		QueueNumber: q.QueuNumber,
+		Status:      q.Status,
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"server/internal/models"
+	"server/internal/repositories"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// aksi yang dikenai biaya token
+const (
+	ActionFormCreate        = "form.create"
+	ActionSubmissionReceive = "submission.receive"
+	ActionExport            = "export"
+	ActionAnalyticsRun      = "analytics.run"
+)
+
+var tokenCosts = map[string]int{
+	ActionFormCreate:        5,
+	ActionSubmissionReceive: 1,
+	ActionExport:            3,
+	ActionAnalyticsRun:      2,
+}
+
+var (
+	ErrQuotaExceeded        = errors.New("token quota exhausted, please upgrade or renew your subscription")
+	ErrSubscriptionInactive = errors.New("no active subscription, please subscribe to continue")
+	ErrFormNotFound         = errors.New("form not found")
+)
+
+type QuotaService interface {
+	Charge(userID, action, referenceID string) (uint, error)
+	ChargeFormOwner(callerID, formID, action string) (uint, error)
+	Refund(usageID uint) error
+}
+
+type quotaService struct {
+	repo repositories.QuotaRepository
+}
+
+func NewQuotaService(repo repositories.QuotaRepository) QuotaService {
+	return &quotaService{repo}
+}
+
+// Charge memotong token untuk aksi yang dilakukan user dan mengembalikan ID baris ledger.
+// Aksi tanpa biaya mengembalikan ID 0.
+func (s *quotaService) Charge(userID, action, referenceID string) (uint, error) {
+	cost, ok := tokenCosts[action]
+	if !ok {
+		return 0, errors.New("unknown metered action: " + action)
+	}
+	if cost == 0 {
+		return 0, nil
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	usage := &models.TokenUsage{
+		UserID:      uid,
+		Action:      action,
+		Tokens:      cost,
+		ReferenceID: referenceID,
+	}
+	charged, err := s.repo.ChargeTokens(usage)
+	if err != nil {
+		return 0, err
+	}
+	if !charged {
+		sub, err := s.repo.FindUserSubscription(userID)
+		if err != nil || !sub.IsActive || !sub.ExpiresAt.After(time.Now()) {
+			return 0, ErrSubscriptionInactive
+		}
+		return 0, ErrQuotaExceeded
+	}
+	return usage.ID, nil
+}
+
+// ChargeFormOwner membebankan aksi pada sebuah form ke pemilik form. Pemanggil yang bukan pemilik
+// ditolak sebelum token dipotong agar tidak bisa menguras saldo atau melihat status kuota orang lain.
+func (s *quotaService) ChargeFormOwner(callerID, formID, action string) (uint, error) {
+	ownerID, err := s.repo.FindFormOwnerID(formID)
+	if err != nil || ownerID != callerID {
+		return 0, ErrFormNotFound
+	}
+	return s.Charge(ownerID, action, formID)
+}
+
+func (s *quotaService) Refund(usageID uint) error {
+	if usageID == 0 {
+		return nil
+	}
+	return s.repo.RefundTokens(usageID)
+}
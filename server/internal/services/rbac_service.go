@@ -0,0 +1,269 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"server/internal/config"
+	"server/internal/models"
+	"server/internal/repositories"
+	"server/internal/utils"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+
+	PermProfileManage       = "profile:manage"
+	PermFormsManage         = "forms:manage"
+	PermSubmissionsRead     = "submissions:read"
+	PermAnalyticsRead       = "analytics:read"
+	PermQueueManage         = "queue:manage"
+	PermPaymentsCreate      = "payments:create"
+	PermPaymentsManage      = "payments:manage"
+	PermSubscriptionsManage = "subscriptions:manage"
+	PermMetricsRead         = "metrics:read"
+	PermUsersManage         = "users:manage"
+
+	rbacCacheTTL = 10 * time.Minute
+)
+
+var ErrTwoFactorRequired = errors.New("two-factor authentication must be enabled for admin accounts")
+
+// defaultRolePermissions di-seed saat startup, admin bisa menambah baris baru langsung di tabel role_permissions
+var defaultRolePermissions = map[string][]string{
+	RoleUser: {
+		PermProfileManage, PermFormsManage, PermSubmissionsRead, PermAnalyticsRead,
+		PermQueueManage, PermPaymentsCreate,
+	},
+	RoleAdmin: {
+		PermSubmissionsRead, PermAnalyticsRead, PermQueueManage, PermPaymentsManage,
+		PermSubscriptionsManage, PermMetricsRead, PermUsersManage,
+	},
+}
+
+type RBACService interface {
+	ResolveRole(userID string) (string, error)
+	HasPermission(userID, permission string) (bool, string, error)
+	ChangeUserRole(actorID, userID, role string) error
+	GetRolePermissions() (map[string][]string, error)
+	SeedDefaults() error
+	BootstrapAdmin(email, password, fullname string) error
+}
+
+type rbacService struct {
+	repo     repositories.RBACRepository
+	authRepo repositories.AuthRepository
+}
+
+func NewRBACService(repo repositories.RBACRepository, authRepo repositories.AuthRepository) RBACService {
+	return &rbacService{repo, authRepo}
+}
+
+type userAccess struct {
+	Role      string `json:"role"`
+	TwoFactor bool   `json:"twoFactor"`
+}
+
+// resolveAccess membaca role & status 2FA terbaru user (cache Redis), sehingga promote/demote
+// langsung berlaku tanpa menunggu access token kedaluwarsa.
+func (s *rbacService) resolveAccess(userID string) (*userAccess, error) {
+	key := "rbac:access:" + userID
+	if cached, err := config.RedisClient.Get(config.Ctx, key).Bytes(); err == nil {
+		var access userAccess
+		if json.Unmarshal(cached, &access) == nil {
+			return &access, nil
+		}
+	}
+
+	user, err := s.repo.GetUserAccess(userID)
+	if err != nil {
+		return nil, err
+	}
+	access := &userAccess{Role: roleOf(user), TwoFactor: user.TwoFactorEnabled}
+	if data, err := json.Marshal(access); err == nil {
+		config.RedisClient.Set(config.Ctx, key, data, rbacCacheTTL)
+	}
+	return access, nil
+}
+
+// roleOf mengembalikan role user; akun lama yang belum terisi role-nya diperlakukan sebagai user biasa.
+func roleOf(user *models.User) string {
+	if user.Role == "" {
+		return RoleUser
+	}
+	return user.Role
+}
+
+func (s *rbacService) ResolveRole(userID string) (string, error) {
+	access, err := s.resolveAccess(userID)
+	if err != nil {
+		return "", err
+	}
+	return access.Role, nil
+}
+
+// HasPermission juga menegakkan kebijakan 2FA: admin tanpa 2FA aktif ditolak dengan ErrTwoFactorRequired.
+func (s *rbacService) HasPermission(userID, permission string) (bool, string, error) {
+	access, err := s.resolveAccess(userID)
+	if err != nil {
+		return false, "", err
+	}
+
+	if access.Role == RoleAdmin && !access.TwoFactor && utils.AdminTwoFactorRequired() {
+		return false, access.Role, ErrTwoFactorRequired
+	}
+
+	permissions, err := s.permissionsOf(access.Role)
+	if err != nil {
+		return false, access.Role, err
+	}
+	return slices.Contains(permissions, permission), access.Role, nil
+}
+
+// InvalidateUserAccessCache dipanggil setiap role atau status 2FA user berubah.
+func InvalidateUserAccessCache(userID string) {
+	config.RedisClient.Del(config.Ctx, "rbac:access:"+userID)
+}
+
+func (s *rbacService) permissionsOf(role string) ([]string, error) {
+	key := "rbac:perms:" + role
+	if cached, err := config.RedisClient.Get(config.Ctx, key).Bytes(); err == nil {
+		var permissions []string
+		if json.Unmarshal(cached, &permissions) == nil {
+			return permissions, nil
+		}
+	}
+
+	permissions, err := s.repo.GetRolePermissions(role)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(permissions); err == nil {
+		config.RedisClient.Set(config.Ctx, key, data, rbacCacheTTL)
+	}
+	return permissions, nil
+}
+
+func (s *rbacService) ChangeUserRole(actorID, userID, role string) error {
+	if actorID == userID {
+		return errors.New("you cannot change your own role")
+	}
+
+	exists, err := s.repo.RoleExists(role)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("role not found")
+	}
+
+	user, err := s.repo.GetUserAccess(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	current := roleOf(user)
+	if current == role {
+		return errors.New("user already has role " + role)
+	}
+
+	if current == RoleAdmin {
+		admins, err := s.repo.CountUsersByRole(RoleAdmin)
+		if err != nil {
+			return err
+		}
+		if admins <= 1 {
+			return errors.New("cannot demote the last admin")
+		}
+	}
+
+	if err := s.repo.UpdateUserRole(userID, role); err != nil {
+		return err
+	}
+	InvalidateUserAccessCache(userID)
+	return nil
+}
+
+func (s *rbacService) GetRolePermissions() (map[string][]string, error) {
+	rows, err := s.repo.GetAllRolePermissions()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]string)
+	for _, row := range rows {
+		result[row.Role] = append(result[row.Role], row.Permission)
+	}
+	return result, nil
+}
+
+func (s *rbacService) SeedDefaults() error {
+	var rows []models.RolePermission
+	for role, permissions := range defaultRolePermissions {
+		for _, permission := range permissions {
+			rows = append(rows, models.RolePermission{Role: role, Permission: permission})
+		}
+	}
+	if err := s.repo.SeedRolePermissions(rows); err != nil {
+		return err
+	}
+
+	backfilled, err := s.repo.BackfillEmptyRoles(RoleUser)
+	if err != nil {
+		return err
+	}
+	if backfilled > 0 {
+		log.Printf("rbac: assigned role %s to %d existing users", RoleUser, backfilled)
+	}
+
+	// cache lama bisa berisi daftar permission sebelum seed
+	for role := range defaultRolePermissions {
+		config.RedisClient.Del(config.Ctx, "rbac:perms:"+role)
+	}
+	return nil
+}
+
+// BootstrapAdmin memastikan akun admin awal ada. User yang sudah terdaftar dipromosikan,
+// jika belum ada akun dibuat dengan password yang diberikan.
+func (s *rbacService) BootstrapAdmin(email, password, fullname string) error {
+	email = normalizeEmail(email)
+	if user, err := s.authRepo.GetUserByEmail(email); err == nil {
+		if user.Role == RoleAdmin {
+			return nil
+		}
+		if err := s.repo.UpdateUserRole(user.ID.String(), RoleAdmin); err != nil {
+			return err
+		}
+		InvalidateUserAccessCache(user.ID.String())
+		log.Printf("rbac: promoted %s to admin", email)
+		return nil
+	}
+
+	if password == "" {
+		return errors.New("admin user does not exist and no password was provided")
+	}
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if fullname == "" {
+		fullname = "Administrator"
+	}
+
+	if err := s.authRepo.CreateNewUser(&models.User{
+		ID:       uuid.New(),
+		Email:    email,
+		Password: hashedPassword,
+		Role:     RoleAdmin,
+		Fullname: fullname,
+		Avatar:   utils.RandomUserAvatar(fullname),
+	}); err != nil {
+		return err
+	}
+	log.Printf("rbac: created admin account %s", email)
+	return nil
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"server/internal/models"
+	"server/internal/repositories"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeRBACRepo hanya mengimplementasikan method yang dipakai test di bawah.
+type fakeRBACRepo struct {
+	repositories.RBACRepository
+
+	roles       map[string]bool
+	users       map[string]string // userID -> role
+	admins      int64
+	permissions []models.RolePermission
+	updated     bool
+}
+
+func (r *fakeRBACRepo) RoleExists(role string) (bool, error) {
+	return r.roles[role], nil
+}
+
+func (r *fakeRBACRepo) GetUserAccess(userID string) (*models.User, error) {
+	role, ok := r.users[userID]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	return &models.User{Role: role}, nil
+}
+
+func (r *fakeRBACRepo) CountUsersByRole(string) (int64, error) {
+	return r.admins, nil
+}
+
+func (r *fakeRBACRepo) UpdateUserRole(string, string) error {
+	r.updated = true
+	return nil
+}
+
+func (r *fakeRBACRepo) GetAllRolePermissions() ([]models.RolePermission, error) {
+	return r.permissions, nil
+}
+
+func TestChangeUserRoleRejections(t *testing.T) {
+	tests := []struct {
+		name    string
+		actorID string
+		userID  string
+		role    string
+		admins  int64
+		wantErr string
+	}{
+		{"cannot change own role", "admin-1", "admin-1", RoleUser, 2, "your own role"},
+		{"unknown role", "admin-1", "user-1", "superuser", 2, "role not found"},
+		{"unknown user", "admin-1", "ghost", RoleAdmin, 2, "user not found"},
+		{"role unchanged", "admin-1", "user-1", RoleUser, 2, "already has role"},
+		{"empty role counts as user", "admin-1", "legacy-1", RoleUser, 2, "already has role"},
+		{"last admin cannot be demoted", "admin-1", "admin-2", RoleUser, 1, "last admin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRBACRepo{
+				roles:  map[string]bool{RoleUser: true, RoleAdmin: true},
+				users:  map[string]string{"admin-1": RoleAdmin, "admin-2": RoleAdmin, "user-1": RoleUser, "legacy-1": ""},
+				admins: tt.admins,
+			}
+			s := &rbacService{repo: repo}
+
+			err := s.ChangeUserRole(tt.actorID, tt.userID, tt.role)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+			if repo.updated {
+				t.Error("role was updated despite the error")
+			}
+		})
+	}
+}
+
+func TestRoleOf(t *testing.T) {
+	tests := []struct {
+		role string
+		want string
+	}{
+		{"", RoleUser},
+		{RoleUser, RoleUser},
+		{RoleAdmin, RoleAdmin},
+	}
+	for _, tt := range tests {
+		if got := roleOf(&models.User{Role: tt.role}); got != tt.want {
+			t.Errorf("roleOf(%q) = %q, want %q", tt.role, got, tt.want)
+		}
+	}
+}
+
+func TestGetRolePermissionsGroupsByRole(t *testing.T) {
+	repo := &fakeRBACRepo{permissions: []models.RolePermission{
+		{Role: RoleUser, Permission: PermFormsManage},
+		{Role: RoleAdmin, Permission: PermMetricsRead},
+		{Role: RoleUser, Permission: PermProfileManage},
+	}}
+	s := &rbacService{repo: repo}
+
+	got, err := s.GetRolePermissions()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := []struct {
+		role string
+		want []string
+	}{
+		{RoleUser, []string{PermFormsManage, PermProfileManage}},
+		{RoleAdmin, []string{PermMetricsRead}},
+	}
+	for _, tt := range tests {
+		if !slices.Equal(got[tt.role], tt.want) {
+			t.Errorf("%s permissions = %v, want %v", tt.role, got[tt.role], tt.want)
+		}
+	}
+}
+
+func TestDefaultRolePermissions(t *testing.T) {
+	tests := []struct {
+		role       string
+		permission string
+		want       bool
+	}{
+		{RoleUser, PermFormsManage, true},
+		{RoleUser, PermPaymentsCreate, true},
+		{RoleUser, PermUsersManage, false},
+		{RoleUser, PermMetricsRead, false},
+		{RoleAdmin, PermUsersManage, true},
+		{RoleAdmin, PermPaymentsManage, true},
+		{RoleAdmin, PermFormsManage, false},
+	}
+	for _, tt := range tests {
+		if got := slices.Contains(defaultRolePermissions[tt.role], tt.permission); got != tt.want {
+			t.Errorf("%s has %s = %v, want %v", tt.role, tt.permission, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"server/internal/dto"
+	"server/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExportSubmissions menghasilkan CSV dengan satu baris per jawaban. Setiap jawaban dirender terhadap
+// versi form yang diisi responden sehingga teks pertanyaan dan opsi sesuai saat submission dibuat.
+func (s *submissionService) ExportSubmissions(userID, formID string) ([]byte, string, error) {
+	form, err := s.formRepo.FindByID(formID)
+	if err != nil || form.UserID.String() != userID {
+		return nil, "", errors.New("form not found")
+	}
+	subs, err := s.repo.GetByFormIDWithAnswers(formID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{"submission_id", "version", "email", "score", "submitted_at", "flagged", "question", "answer"})
+
+	type rendered struct {
+		questions map[string]dto.SnapshotQuestion
+		version   int
+	}
+	cache := map[uuid.UUID]*rendered{}
+	var draft *rendered
+
+	for i := range subs {
+		sub := &subs[i]
+		var r *rendered
+		if sub.FormVersionID != nil {
+			r = cache[*sub.FormVersionID]
+		} else {
+			r = draft
+		}
+		if r == nil {
+			snapshot, version, err := s.submissionSnapshot(sub)
+			if err != nil {
+				return nil, "", err
+			}
+			r = &rendered{questions: make(map[string]dto.SnapshotQuestion, len(snapshot.Questions)), version: version}
+			for _, q := range snapshot.Questions {
+				r.questions[q.ID] = q
+			}
+			if sub.FormVersionID != nil {
+				cache[*sub.FormVersionID] = r
+			} else {
+				draft = r
+			}
+		}
+
+		version := strconv.Itoa(r.version)
+		if sub.FormVersionID == nil {
+			version = "unversioned"
+		}
+		score := ""
+		if sub.Score != nil {
+			score = strconv.FormatFloat(*sub.Score, 'f', -1, 64)
+		}
+		for _, a := range sub.Answers {
+			q := r.questions[a.QuestionID.String()]
+			question := q.Text
+			if question == "" {
+				question = a.QuestionID.String()
+			}
+			_ = w.Write([]string{
+				sub.ID.String(), version, csvSafe(sub.Email), score,
+				sub.SubmittedAt.Format(time.RFC3339), strconv.FormatBool(sub.Flagged),
+				csvSafe(question), csvSafe(exportAnswerText(q, a)),
+			})
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, "", err
+	}
+
+	filename := fmt.Sprintf("submissions-%s-%s.csv", formID, time.Now().Format("20060102"))
+	return buf.Bytes(), filename, nil
+}
+
+func exportAnswerText(q dto.SnapshotQuestion, a models.Answer) string {
+	if a.TextAnswer != nil {
+		return *a.TextAnswer
+	}
+	if a.OptionID == nil {
+		return ""
+	}
+	for _, o := range q.Options {
+		if o.ID == *a.OptionID {
+			return o.Text
+		}
+	}
+	return fmt.Sprintf("Option #%d", *a.OptionID)
+}
+
+// csvSafe mencegah formula injection saat CSV dibuka di spreadsheet: sel yang diawali karakter
+// pemicu formula diberi prefix ' sehingga dibaca sebagai teks biasa.
+func csvSafe(value string) string {
+	if value == "" {
+		return value
+	}
+	switch value[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"server/internal/config"
+	"server/internal/dto"
+	"server/internal/models"
+	"server/internal/utils"
+	"time"
+)
+
+const (
+	HoneypotField  = "website"
+	renderTokenTTL = 2 * time.Hour
+
+	FlagHoneypot        = "honeypot"
+	FlagTooFast         = "too_fast"
+	FlagInvalidToken    = "invalid_render_token"
+	FlagReplayedToken   = "replayed_render_token"
+	FlagProofOfWork     = "invalid_proof_of_work"
+	FlagTokenFormDiffer = "render_token_form_mismatch"
+)
+
+// submissionCheckInput berisi data yang dibutuhkan pengecekan; render token di-parse sekali di awal.
+type submissionCheckInput struct {
+	Request  *dto.SubmissionRequest
+	Setting  *models.FormSetting
+	Now      time.Time
+	TokenOK  bool
+	FormID   string
+	IssuedAt time.Time
+}
+
+// SubmissionCheck adalah satu lapis proteksi spam. Check mengembalikan alasan flag, atau "" jika lolos.
+type SubmissionCheck interface {
+	Enabled(setting *models.FormSetting) bool
+	Check(in *submissionCheckInput) string
+}
+
+// urutan pengecekan default; tambahkan implementasi SubmissionCheck baru di sini
+var defaultSubmissionChecks = []SubmissionCheck{
+	honeypotCheck{},
+	renderTokenCheck{},
+	minFillTimeCheck{},
+	proofOfWorkCheck{},
+}
+
+type honeypotCheck struct{}
+
+func (honeypotCheck) Enabled(setting *models.FormSetting) bool { return setting.HoneypotEnabled }
+
+func (honeypotCheck) Check(in *submissionCheckInput) string {
+	if in.Request.Website != "" {
+		return FlagHoneypot
+	}
+	return ""
+}
+
+// renderTokenCheck memastikan submission berasal dari form yang benar-benar dirender, dan token
+// hanya dipakai sekali. Min fill time dan proof-of-work juga bergantung pada token ini.
+type renderTokenCheck struct{}
+
+func (renderTokenCheck) Enabled(setting *models.FormSetting) bool {
+	return setting.RequireRenderToken || setting.MinFillSeconds > 0 || setting.ProofOfWorkDifficulty > 0
+}
+
+func (renderTokenCheck) Check(in *submissionCheckInput) string {
+	if !in.TokenOK || in.Now.Sub(in.IssuedAt) > renderTokenTTL {
+		return FlagInvalidToken
+	}
+	if in.FormID != in.Request.FormID {
+		return FlagTokenFormDiffer
+	}
+
+	fresh, err := config.RedisClient.SetNX(config.Ctx, "submission:render-token:"+utils.HashToken(in.Request.RenderToken), 1, renderTokenTTL).Result()
+	if err == nil && !fresh {
+		return FlagReplayedToken
+	}
+	return ""
+}
+
+type minFillTimeCheck struct{}
+
+func (minFillTimeCheck) Enabled(setting *models.FormSetting) bool { return setting.MinFillSeconds > 0 }
+
+func (minFillTimeCheck) Check(in *submissionCheckInput) string {
+	// tanpa token valid waktu render tidak diketahui; sudah ditandai oleh renderTokenCheck
+	if in.TokenOK && in.Now.Sub(in.IssuedAt) < time.Duration(in.Setting.MinFillSeconds)*time.Second {
+		return FlagTooFast
+	}
+	return ""
+}
+
+type proofOfWorkCheck struct{}
+
+func (proofOfWorkCheck) Enabled(setting *models.FormSetting) bool {
+	return setting.ProofOfWorkDifficulty > 0
+}
+
+func (proofOfWorkCheck) Check(in *submissionCheckInput) string {
+	if !utils.VerifyProofOfWork(in.Request.RenderToken, in.Request.PowNonce, in.Setting.ProofOfWorkDifficulty) {
+		return FlagProofOfWork
+	}
+	return ""
+}
+
+// evaluateSubmission menjalankan semua pengecekan aktif dan mengumpulkan alasan flag.
+func evaluateSubmission(req *dto.SubmissionRequest, setting *models.FormSetting, now time.Time) []string {
+	in := &submissionCheckInput{Request: req, Setting: setting, Now: now}
+	if formID, issuedAt, err := utils.ParseRenderToken(req.RenderToken); err == nil {
+		in.TokenOK, in.FormID, in.IssuedAt = true, formID, issuedAt
+	}
+
+	var reasons []string
+	for _, check := range defaultSubmissionChecks {
+		if !check.Enabled(setting) {
+			continue
+		}
+		if reason := check.Check(in); reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
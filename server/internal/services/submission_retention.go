@@ -0,0 +1,78 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"server/internal/dto"
+	"server/internal/models"
+	"time"
+)
+
+const (
+	RetentionAnonymize = "anonymize"
+	RetentionDelete    = "delete"
+)
+
+// EnforceRetention menjalankan kebijakan retensi setiap form: submission yang lebih lama dari
+// RetentionDays dianonimkan atau dihapus, dan setiap eksekusi yang mengubah data dicatat di RetentionLog.
+func (s *submissionService) EnforceRetention() (int64, error) {
+	settings, err := s.repo.GetRetentionSettings()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	now := time.Now()
+	for _, setting := range settings {
+		formID := setting.FormID.String()
+		cutoff := now.AddDate(0, 0, -setting.RetentionDays)
+
+		action := setting.RetentionAction
+		var affected int64
+		if action == RetentionDelete {
+			affected, err = s.repo.DeleteSubmissionsBefore(formID, cutoff)
+		} else {
+			action = RetentionAnonymize
+			affected, err = s.repo.AnonymizeSubmissionsBefore(formID, cutoff)
+		}
+		if err != nil {
+			log.Printf("retention form %s: %v", formID, err)
+			continue
+		}
+		if affected == 0 {
+			continue
+		}
+
+		if err := s.repo.CreateRetentionLog(&models.RetentionLog{
+			FormID:   setting.FormID,
+			Action:   action,
+			Affected: affected,
+			Cutoff:   cutoff,
+		}); err != nil {
+			log.Printf("retention form %s: write log: %v", formID, err)
+		}
+		total += affected
+	}
+	return total, nil
+}
+
+func (s *submissionService) GetRetentionLogs(userID, formID string) ([]dto.RetentionLogResponse, error) {
+	form, err := s.formRepo.FindByID(formID)
+	if err != nil || form.UserID.String() != userID {
+		return nil, errors.New("form not found")
+	}
+	logs, err := s.repo.GetRetentionLogs(formID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.RetentionLogResponse, 0, len(logs))
+	for _, l := range logs {
+		result = append(result, dto.RetentionLogResponse{
+			Action:    l.Action,
+			Affected:  l.Affected,
+			Cutoff:    l.Cutoff.Format("2006-01-02 15:04:05"),
+			CreatedAt: l.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	return result, nil
+}
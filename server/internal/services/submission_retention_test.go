@@ -0,0 +1,149 @@
+package services
+
+import (
+	"errors"
+	"server/internal/models"
+	"server/internal/repositories"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeRetentionRepo hanya mengimplementasikan method yang dipakai EnforceRetention.
+type fakeRetentionRepo struct {
+	repositories.SubmissionRepository
+
+	settings []models.FormSetting
+	affected map[string]int64 // per form ID
+	failing  map[string]bool
+
+	calls []string // "<action>:<formID>"
+	logs  []models.RetentionLog
+}
+
+func (r *fakeRetentionRepo) GetRetentionSettings() ([]models.FormSetting, error) {
+	return r.settings, nil
+}
+
+func (r *fakeRetentionRepo) run(action, formID string) (int64, error) {
+	r.calls = append(r.calls, action+":"+formID)
+	if r.failing[formID] {
+		return 0, errors.New("db error")
+	}
+	return r.affected[formID], nil
+}
+
+func (r *fakeRetentionRepo) AnonymizeSubmissionsBefore(formID string, _ time.Time) (int64, error) {
+	return r.run(RetentionAnonymize, formID)
+}
+
+func (r *fakeRetentionRepo) DeleteSubmissionsBefore(formID string, _ time.Time) (int64, error) {
+	return r.run(RetentionDelete, formID)
+}
+
+func (r *fakeRetentionRepo) CreateRetentionLog(entry *models.RetentionLog) error {
+	r.logs = append(r.logs, *entry)
+	return nil
+}
+
+func TestEnforceRetention(t *testing.T) {
+	deleteForm, anonForm, defaultForm := uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name      string
+		settings  []models.FormSetting
+		affected  map[string]int64
+		failing   map[string]bool
+		wantTotal int64
+		wantCalls []string
+		wantLogs  int
+	}{
+		{
+			name: "action follows the form setting",
+			settings: []models.FormSetting{
+				{FormID: deleteForm, RetentionDays: 30, RetentionAction: RetentionDelete},
+				{FormID: anonForm, RetentionDays: 7, RetentionAction: RetentionAnonymize},
+			},
+			affected:  map[string]int64{deleteForm.String(): 3, anonForm.String(): 2},
+			wantTotal: 5,
+			wantCalls: []string{"delete:" + deleteForm.String(), "anonymize:" + anonForm.String()},
+			wantLogs:  2,
+		},
+		{
+			name:      "unknown action falls back to anonymize",
+			settings:  []models.FormSetting{{FormID: defaultForm, RetentionDays: 1, RetentionAction: ""}},
+			affected:  map[string]int64{defaultForm.String(): 1},
+			wantTotal: 1,
+			wantCalls: []string{"anonymize:" + defaultForm.String()},
+			wantLogs:  1,
+		},
+		{
+			name:      "nothing affected writes no log",
+			settings:  []models.FormSetting{{FormID: anonForm, RetentionDays: 7, RetentionAction: RetentionAnonymize}},
+			wantCalls: []string{"anonymize:" + anonForm.String()},
+		},
+		{
+			name: "a failing form does not stop the others",
+			settings: []models.FormSetting{
+				{FormID: deleteForm, RetentionDays: 30, RetentionAction: RetentionDelete},
+				{FormID: anonForm, RetentionDays: 7, RetentionAction: RetentionAnonymize},
+			},
+			affected:  map[string]int64{anonForm.String(): 4},
+			failing:   map[string]bool{deleteForm.String(): true},
+			wantTotal: 4,
+			wantCalls: []string{"delete:" + deleteForm.String(), "anonymize:" + anonForm.String()},
+			wantLogs:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRetentionRepo{settings: tt.settings, affected: tt.affected, failing: tt.failing}
+			s := &submissionService{repo: repo}
+
+			total, err := s.EnforceRetention()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("total = %d, want %d", total, tt.wantTotal)
+			}
+			if len(repo.calls) != len(tt.wantCalls) {
+				t.Fatalf("calls = %v, want %v", repo.calls, tt.wantCalls)
+			}
+			for i := range tt.wantCalls {
+				if repo.calls[i] != tt.wantCalls[i] {
+					t.Errorf("call %d = %s, want %s", i, repo.calls[i], tt.wantCalls[i])
+				}
+			}
+			if len(repo.logs) != tt.wantLogs {
+				t.Errorf("logs = %d, want %d", len(repo.logs), tt.wantLogs)
+			}
+		})
+	}
+}
+
+func TestEnforceRetentionCutoff(t *testing.T) {
+	form := uuid.New()
+	repo := &fakeRetentionRepo{
+		settings: []models.FormSetting{{FormID: form, RetentionDays: 30, RetentionAction: RetentionDelete}},
+		affected: map[string]int64{form.String(): 1},
+	}
+	s := &submissionService{repo: repo}
+
+	start := time.Now()
+	if _, err := s.EnforceRetention(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.logs) != 1 {
+		t.Fatalf("logs = %d, want 1", len(repo.logs))
+	}
+	want := start.AddDate(0, 0, -30)
+	if diff := repo.logs[0].Cutoff.Sub(want); diff < 0 || diff > time.Minute {
+		t.Errorf("cutoff = %v, want about %v", repo.logs[0].Cutoff, want)
+	}
+	if repo.logs[0].Action != RetentionDelete || repo.logs[0].Affected != 1 || repo.logs[0].FormID != form {
+		t.Errorf("unexpected log entry: %+v", repo.logs[0])
+	}
+}
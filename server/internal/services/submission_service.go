@@ -1,88 +1,256 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"server/internal/dto"
+	"server/internal/models"
+	"server/internal/repositories"
+	"server/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
 type SubmissionService interface {
-	SendSubmission(req *dto.SubmissionRequest) error
-	GetFormSubmissions(formID string) ([]dto.SubmissionResponse, error)
-	GetSubmissionResult(subID string) (*dto.SubmissionResultResponse, error)
+	GetSubmissionChallenge(formID string) (*dto.SubmissionChallengeResponse, error)
+	GetLiveForm(formID string) (*dto.LiveFormResponse, error)
+	SendSubmission(req *dto.SubmissionRequest, meta dto.RequestMeta) error
+	GetFormSubmissions(userID, formID string) ([]dto.SubmissionResponse, error)
+	GetSubmissionResult(userID, subID string) (*dto.SubmissionResultResponse, error)
+	GetRetentionLogs(userID, formID string) ([]dto.RetentionLogResponse, error)
+	ExportSubmissions(userID, formID string) ([]byte, string, error)
+	EnforceRetention() (int64, error)
 }
 
 type submissionService struct {
 	repo     repositories.SubmissionRepository
 	formRepo repositories.FormRepository
+	quota    QuotaService
 }
 
-func NewSubmissionService(repo repositories.SubmissionRepository, formRepo repositories.FormRepository) SubmissionService {
-	return &submissionService{repo, formRepo}
+func NewSubmissionService(
+	repo repositories.SubmissionRepository,
+	formRepo repositories.FormRepository,
+	quota QuotaService,
+) SubmissionService {
+	return &submissionService{repo, formRepo, quota}
 }
 
-func (s *submissionService) SendSubmission(req *dto.SubmissionRequest) error {
+// GetSubmissionChallenge menerbitkan render token dan parameter proteksi spam untuk form publik.
+func (s *submissionService) GetSubmissionChallenge(formID string) (*dto.SubmissionChallengeResponse, error) {
+	if _, err := s.formRepo.FindByID(formID); err != nil {
+		return nil, errors.New("form not found")
+	}
+	setting, err := s.formRepo.GetFormSetting(formID)
+	if err != nil {
+		return nil, errors.New("form not found")
+	}
+
+	token, err := utils.SignRenderToken(formID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	res := &dto.SubmissionChallengeResponse{
+		RenderToken:    token,
+		MinFillSeconds: setting.MinFillSeconds,
+		PowDifficulty:  setting.ProofOfWorkDifficulty,
+	}
+	if setting.HoneypotEnabled {
+		res.HoneypotField = HoneypotField
+	}
+	return res, nil
+}
+
+// SendSubmission menyimpan submission publik. Submission yang gagal pengecekan spam tetap disimpan
+// dengan tanda Flagged (tidak dibebankan ke token pemilik form) agar bisa ditinjau pemilik form.
+func (s *submissionService) SendSubmission(req *dto.SubmissionRequest, meta dto.RequestMeta) error {
+	form, err := s.formRepo.FindByID(req.FormID)
+	if err != nil {
+		return errors.New("form not found")
+	}
+	setting, err := s.formRepo.GetFormSetting(req.FormID)
+	if err != nil {
+		return errors.New("form not found")
+	}
+	if !acceptingSubmissions(form, setting, time.Now()) {
+		return ErrFormNotAccepting
+	}
+
+	// submission selalu terikat ke versi live; form lama yang belum pernah dipublish tetap diterima
+	// dan jawabannya divalidasi terhadap draft saat ini
+	var version *models.FormVersion
+	var snapshot *dto.FormSnapshot
+	if form.PublishedVersionID != nil {
+		version, err = s.formRepo.GetVersionByID(form.PublishedVersionID.String())
+		if err != nil {
+			return err
+		}
+		if snapshot, err = decodeFormSnapshot(version); err != nil {
+			return err
+		}
+	} else {
+		draft, err := s.formRepo.GetFormStructure(req.FormID)
+		if err != nil {
+			return errors.New("form not found")
+		}
+		snapshot = buildFormSnapshot(draft)
+	}
+	if err := validateAnswers(snapshot, req.Answers); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	reasons := evaluateSubmission(req, setting, now)
+
 	sub := &models.Submission{
 		ID:           uuid.New(),
-		FormID:       uuid.MustParse(req.FormID),
+		FormID:       form.ID,
 		Email:        req.Email,
-		IPAddress:    req.IPAddress,
-		UserAgent:    req.UserAgent,
+		IPAddress:    &meta.IPAddress,
+		UserAgent:    &meta.UserAgent,
 		SessionToken: req.SessionToken,
-		SubmittedAt:  time.Now(),
+		SubmittedAt:  now,
+		Flagged:      len(reasons) > 0,
+		FlagReasons:  strings.Join(reasons, ","),
+	}
+	if version != nil {
+		sub.FormVersionID = &version.ID
+	}
+	// form anonim: identitas responden tidak pernah disimpan
+	if setting.AnonymousResponses {
+		sub.Email = ""
+		sub.IPAddress = nil
+		sub.UserAgent = nil
+		sub.SessionToken = nil
+		sub.AnonymizedAt = &now
 	}
 
 	var answers []models.Answer
 	for _, a := range req.Answers {
+		questionID, err := uuid.Parse(a.QuestionID)
+		if err != nil {
+			return fmt.Errorf("invalid question id %q", a.QuestionID)
+		}
 		ans := models.Answer{
-			QuestionID: uuid.MustParse(a.QuestionID),
+			QuestionID: questionID,
 			OptionID:   a.OptionID,
 			TextAnswer: a.TextAnswer,
 		}
 		answers = append(answers, ans)
 	}
 
-	return s.repo.Create(sub, answers)
+	if sub.Flagged {
+		return s.repo.Create(sub, answers)
+	}
+
+	// setiap submission yang diterima dibebankan ke token pemilik form
+	usageID, err := s.quota.Charge(form.UserID.String(), ActionSubmissionReceive, sub.ID.String())
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Create(sub, answers); err != nil {
+		_ = s.quota.Refund(usageID)
+		return err
+	}
+	return nil
 }
 
-func (s *submissionService) GetFormSubmissions(formID string) ([]dto.SubmissionResponse, error) {
+func (s *submissionService) GetFormSubmissions(userID, formID string) ([]dto.SubmissionResponse, error) {
+	form, err := s.formRepo.FindByID(formID)
+	if err != nil || form.UserID.String() != userID {
+		return nil, errors.New("form not found")
+	}
 	data, err := s.repo.GetByFormID(formID)
 	if err != nil {
 		return nil, err
 	}
+	versions, err := s.formRepo.GetVersions(formID)
+	if err != nil {
+		return nil, err
+	}
+	versionNumbers := make(map[uuid.UUID]int, len(versions))
+	for _, v := range versions {
+		versionNumbers[v.ID] = v.Version
+	}
+
 	var result []dto.SubmissionResponse
 	for _, d := range data {
-		result = append(result, dto.SubmissionResponse{
+		res := dto.SubmissionResponse{
 			ID:        d.ID.String(),
 			FormID:    d.FormID.String(),
 			Email:     d.Email,
 			Score:     d.Score,
 			Timestamp: d.SubmittedAt.Format("2006-01-02 15:04:05"),
-		})
+			Flagged:   d.Flagged,
+		}
+		if d.FormVersionID != nil {
+			res.Version = versionNumbers[*d.FormVersionID]
+		}
+		if d.FlagReasons != "" {
+			res.FlagReasons = strings.Split(d.FlagReasons, ",")
+		}
+		result = append(result, res)
 	}
 	return result, nil
 }
 
-func (s *submissionService) GetSubmissionResult(subID string) (*dto.SubmissionResultResponse, error) {
+// GetSubmissionResult menampilkan jawaban terhadap versi form yang diisi responden, sehingga
+// perubahan draft atau versi baru tidak mengubah arti jawaban lama.
+func (s *submissionService) GetSubmissionResult(userID, subID string) (*dto.SubmissionResultResponse, error) {
 	sub, err := s.repo.GetWithAnswers(subID)
 	if err != nil {
 		return nil, err
 	}
-
 	form, err := s.formRepo.FindByID(sub.FormID.String())
+	if err != nil || form.UserID.String() != userID {
+		return nil, errors.New("submission not found")
+	}
+
+	snapshot, version, err := s.submissionSnapshot(sub)
 	if err != nil {
 		return nil, err
 	}
 
+	questions := make(map[string]dto.SnapshotQuestion, len(snapshot.Questions))
+	for _, q := range snapshot.Questions {
+		questions[q.ID] = q
+	}
+	graded := snapshot.Type == "quiz" || snapshot.Type == "exam"
+
 	var answers []dto.AnswerResponse
 	for _, a := range sub.Answers {
-		answerText := ""
+		res := dto.AnswerResponse{Question: a.QuestionID.String()}
+		q, ok := questions[a.QuestionID.String()]
+		if ok {
+			res.Question = q.Text
+		}
+
 		if a.TextAnswer != nil {
-			answerText = *a.TextAnswer
+			res.Answer = *a.TextAnswer
 		} else if a.OptionID != nil {
-			answerText = fmt.Sprintf("Option #%d", *a.OptionID)
+			res.Answer = fmt.Sprintf("Option #%d", *a.OptionID)
+			for _, o := range q.Options {
+				if o.ID == *a.OptionID {
+					res.Answer = o.Text
+					if graded {
+						res.Correct = o.IsCorrect
+					}
+					break
+				}
+			}
 		}
-		answers = append(answers, dto.AnswerResponse{
-			Question: a.QuestionID.String(), // idealnya preload pertanyaan
-			Answer:   answerText,
-		})
+		answers = append(answers, res)
 	}
 
 	return &dto.SubmissionResultResponse{
-		FormTitle:  form.Title,
-		TotalScore: sub.Score,
-		Answers:    answers,
+		FormTitle:   snapshot.Title,
+		Version:     version,
+		Unversioned: sub.FormVersionID == nil,
+		TotalScore:  sub.Score,
+		Answers:     answers,
 	}, nil
 }
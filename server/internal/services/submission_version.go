@@ -0,0 +1,108 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"server/internal/dto"
+	"server/internal/models"
+	"time"
+)
+
+// GetLiveForm mengembalikan versi form yang sedang live untuk dirender ke responden.
+// Draft yang belum dipublish tidak pernah terlihat, begitu juga kunci jawaban.
+func (s *submissionService) GetLiveForm(formID string) (*dto.LiveFormResponse, error) {
+	form, err := s.formRepo.FindByID(formID)
+	if err != nil || !form.IsActive {
+		return nil, errors.New("form not found")
+	}
+	if form.PublishedVersionID == nil {
+		return nil, errors.New("form has not been published")
+	}
+
+	version, err := s.formRepo.GetVersionByID(form.PublishedVersionID.String())
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := decodeFormSnapshot(version)
+	if err != nil {
+		return nil, err
+	}
+	for i := range snapshot.Questions {
+		for j := range snapshot.Questions[i].Options {
+			snapshot.Questions[i].Options[j].IsCorrect = nil
+		}
+	}
+
+	res := &dto.LiveFormResponse{
+		FormID:  form.ID.String(),
+		Version: version.Version,
+		Form:    snapshot,
+	}
+	if setting, err := s.formRepo.GetFormSetting(formID); err == nil && setting.EndAt != nil {
+		res.ClosesAt = setting.EndAt.Format(time.RFC3339)
+		res.ClosingSoon = closingSoon(setting, time.Now())
+	}
+	return res, nil
+}
+
+// submissionSnapshot memuat struktur form yang dipakai saat submission dibuat. Submission lama yang
+// dibuat sebelum versioning tidak punya snapshot sehingga dirender terhadap draft saat ini; pemanggil
+// wajib menandainya sebagai unversioned.
+func (s *submissionService) submissionSnapshot(sub *models.Submission) (*dto.FormSnapshot, int, error) {
+	if sub.FormVersionID != nil {
+		version, err := s.formRepo.GetVersionByID(sub.FormVersionID.String())
+		if err != nil {
+			return nil, 0, err
+		}
+		snapshot, err := decodeFormSnapshot(version)
+		if err != nil {
+			return nil, 0, err
+		}
+		return snapshot, version.Version, nil
+	}
+
+	form, err := s.formRepo.GetFormStructure(sub.FormID.String())
+	if err != nil {
+		return nil, 0, err
+	}
+	return buildFormSnapshot(form), 0, nil
+}
+
+// validateAnswers memastikan jawaban sesuai versi live: pertanyaan dan opsi harus ada di versi
+// tersebut dan pertanyaan wajib harus dijawab.
+func validateAnswers(snapshot *dto.FormSnapshot, answers []dto.AnswerRequest) error {
+	questions := make(map[string]dto.SnapshotQuestion, len(snapshot.Questions))
+	for _, q := range snapshot.Questions {
+		questions[q.ID] = q
+	}
+
+	answered := make(map[string]bool, len(answers))
+	for _, a := range answers {
+		q, ok := questions[a.QuestionID]
+		if !ok {
+			return fmt.Errorf("question %s is not part of this form", a.QuestionID)
+		}
+		if a.OptionID != nil {
+			valid := false
+			for _, o := range q.Options {
+				if o.ID == *a.OptionID {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return fmt.Errorf("option %d is not valid for question %s", *a.OptionID, a.QuestionID)
+			}
+		}
+		if a.OptionID != nil || (a.TextAnswer != nil && *a.TextAnswer != "") {
+			answered[a.QuestionID] = true
+		}
+	}
+
+	for _, q := range snapshot.Questions {
+		if q.IsRequired && !answered[q.ID] {
+			return fmt.Errorf("question %q is required", q.Text)
+		}
+	}
+	return nil
+}
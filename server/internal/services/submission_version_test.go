@@ -0,0 +1,89 @@
+package services
+
+import (
+	"server/internal/dto"
+	"strings"
+	"testing"
+)
+
+func TestValidateAnswers(t *testing.T) {
+	snapshot := &dto.FormSnapshot{
+		Questions: []dto.SnapshotQuestion{
+			{QuestionResponse: dto.QuestionResponse{
+				ID: "q1", Text: "Favourite colour", IsRequired: true,
+				Options: []dto.Option{{ID: 1, Text: "Red"}, {ID: 2, Text: "Blue"}},
+			}},
+			{QuestionResponse: dto.QuestionResponse{ID: "q2", Text: "Comments"}},
+			{QuestionResponse: dto.QuestionResponse{ID: "q3", Text: "Name", IsRequired: true}},
+		},
+	}
+	option := func(id uint) *uint { return &id }
+	text := func(s string) *string { return &s }
+
+	tests := []struct {
+		name    string
+		answers []dto.AnswerRequest
+		wantErr string
+	}{
+		{
+			name: "all required answered",
+			answers: []dto.AnswerRequest{
+				{QuestionID: "q1", OptionID: option(2)},
+				{QuestionID: "q3", TextAnswer: text("Ada")},
+			},
+		},
+		{
+			name: "optional question answered too",
+			answers: []dto.AnswerRequest{
+				{QuestionID: "q1", OptionID: option(1)},
+				{QuestionID: "q2", TextAnswer: text("")},
+				{QuestionID: "q3", TextAnswer: text("Ada")},
+			},
+		},
+		{
+			name: "question from another form or version",
+			answers: []dto.AnswerRequest{
+				{QuestionID: "q1", OptionID: option(1)},
+				{QuestionID: "q3", TextAnswer: text("Ada")},
+				{QuestionID: "q9", TextAnswer: text("x")},
+			},
+			wantErr: "question q9 is not part of this form",
+		},
+		{
+			name: "option not in question",
+			answers: []dto.AnswerRequest{
+				{QuestionID: "q1", OptionID: option(7)},
+				{QuestionID: "q3", TextAnswer: text("Ada")},
+			},
+			wantErr: "option 7 is not valid for question q1",
+		},
+		{
+			name:    "required question missing",
+			answers: []dto.AnswerRequest{{QuestionID: "q1", OptionID: option(1)}},
+			wantErr: `question "Name" is required`,
+		},
+		{
+			name: "empty text does not satisfy required",
+			answers: []dto.AnswerRequest{
+				{QuestionID: "q1", OptionID: option(1)},
+				{QuestionID: "q3", TextAnswer: text("")},
+			},
+			wantErr: `question "Name" is required`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAnswers(snapshot, tt.answers)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
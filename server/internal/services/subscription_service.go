@@ -1,29 +1,44 @@
 package services
 
 import (
+	"errors"
+	"fmt"
 	"server/internal/dto"
 	"server/internal/models"
 	"server/internal/repositories"
+	"server/internal/utils"
+	"strings"
+	"time"
 )
 
 type SubscriptionService interface {
-	CreateTier(req *dto.CreateTierRequest) error
-	UpdateTier(req *dto.UpdateTierRequest) error
-	DeleteTier(id uint) error
-	ResetAllUserTokens() error
+	CreateTier(req *dto.CreateTierRequest, meta dto.RequestMeta) error
+	UpdateTier(req *dto.UpdateTierRequest, meta dto.RequestMeta) error
+	DeleteTier(id uint, meta dto.RequestMeta) error
+	ResetAllUserTokens(meta dto.RequestMeta) error
+	ResetMonthlyTokens() (int64, error)
+	DeactivateExpiredSubscriptions() (int64, error)
+	ApplyScheduledTierChanges() (int64, error)
 	GetAllSubscriptions() ([]dto.UserSubscriptionResponse, error)
 	GetSubscriptionByUserID(userID string) (*dto.UserSubscriptionResponse, error)
+
+	CreateVoucher(req *dto.CreateVoucherRequest, meta dto.RequestMeta) error
+	UpdateVoucher(req *dto.UpdateVoucherRequest, meta dto.RequestMeta) error
+	DeleteVoucher(id uint, meta dto.RequestMeta) error
+	GetAllVouchers() ([]dto.VoucherResponse, error)
+	GetVoucherDetail(id uint) (*dto.VoucherResponse, error)
 }
 
 type subscriptionService struct {
-	repo repositories.SubscriptionRepository
+	repo  repositories.SubscriptionRepository
+	audit AuditService
 }
 
-func NewAdminSubscriptionService(repo repositories.SubscriptionRepository) SubscriptionService {
-	return &subscriptionService{repo}
+func NewAdminSubscriptionService(repo repositories.SubscriptionRepository, audit AuditService) SubscriptionService {
+	return &subscriptionService{repo, audit}
 }
 
-func (s *subscriptionService) CreateTier(req *dto.CreateTierRequest) error {
+func (s *subscriptionService) CreateTier(req *dto.CreateTierRequest, meta dto.RequestMeta) error {
 	tier := &models.SubscriptionTier{
 		Name:        req.Name,
 		TokenLimit:  req.TokenLimit,
@@ -31,10 +46,21 @@ func (s *subscriptionService) CreateTier(req *dto.CreateTierRequest) error {
 		Duration:    req.Duration,
 		Description: req.Description,
 	}
-	return s.repo.CreateTier(tier)
+	if err := s.repo.CreateTier(tier); err != nil {
+		return err
+	}
+	s.audit.Record(meta, AuditEntry{
+		Action: "tier.created", EntityType: AuditEntityTier, EntityID: fmt.Sprint(tier.ID), After: tier,
+	})
+	return nil
 }
 
-func (s *subscriptionService) UpdateTier(req *dto.UpdateTierRequest) error {
+func (s *subscriptionService) UpdateTier(req *dto.UpdateTierRequest, meta dto.RequestMeta) error {
+	before, err := s.repo.GetTierByID(req.ID)
+	if err != nil {
+		return errors.New("subscription tier not found")
+	}
+
 	tier := &models.SubscriptionTier{
 		ID:          req.ID,
 		Name:        req.Name,
@@ -43,15 +69,81 @@ func (s *subscriptionService) UpdateTier(req *dto.UpdateTierRequest) error {
 		Duration:    req.Duration,
 		Description: req.Description,
 	}
-	return s.repo.UpdateTier(tier)
+	if err := s.repo.UpdateTier(tier); err != nil {
+		return err
+	}
+
+	after, err := s.repo.GetTierByID(req.ID)
+	if err != nil {
+		after = tier
+	}
+	s.audit.Record(meta, AuditEntry{
+		Action: "tier.updated", EntityType: AuditEntityTier, EntityID: fmt.Sprint(req.ID),
+		Before: before, After: after,
+	})
+	return nil
+}
+
+func (s *subscriptionService) DeleteTier(id uint, meta dto.RequestMeta) error {
+	before, err := s.repo.GetTierByID(id)
+	if err != nil {
+		return errors.New("subscription tier not found")
+	}
+	if err := s.repo.DeleteTier(id); err != nil {
+		return err
+	}
+	s.audit.Record(meta, AuditEntry{
+		Action: "tier.deleted", EntityType: AuditEntityTier, EntityID: fmt.Sprint(id), Before: before,
+	})
+	return nil
+}
+
+func (s *subscriptionService) ResetAllUserTokens(meta dto.RequestMeta) error {
+	if err := s.repo.ResetAllUserToken(); err != nil {
+		return err
+	}
+	s.audit.Record(meta, AuditEntry{
+		Action: "subscription.tokens_reset_all", EntityType: AuditEntitySubscription, EntityID: "*",
+	})
+	return nil
+}
+
+func (s *subscriptionService) ResetMonthlyTokens() (int64, error) {
+	return s.repo.ResetMonthlyTokens(time.Now())
 }
 
-func (s *subscriptionService) DeleteTier(id uint) error {
-	return s.repo.DeleteTier(id)
+func (s *subscriptionService) DeactivateExpiredSubscriptions() (int64, error) {
+	return s.repo.DeactivateExpiredSubscriptions(time.Now())
 }
 
-func (s *subscriptionService) ResetAllUserTokens() error {
-	return s.repo.ResetAllUserToken()
+// ApplyScheduledTierChanges menjalankan downgrade yang sudah dibayar begitu periode paket lama berakhir.
+func (s *subscriptionService) ApplyScheduledTierChanges() (int64, error) {
+	subs, err := s.repo.FindDueScheduledChanges(time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	var applied int64
+	for i := range subs {
+		sub := &subs[i]
+		if sub.ScheduledTier != nil {
+			// periode paket baru dimulai tepat saat periode lama berakhir
+			sub.SubscriptionTierID = sub.ScheduledTier.ID
+			sub.StartedAt = sub.ExpiresAt
+			sub.ExpiresAt = sub.ExpiresAt.AddDate(0, 0, sub.ScheduledTier.Duration)
+			sub.IsActive = true
+			sub.RemainingTokens = nextTokenBalance(sub.RemainingTokens, sub.ScheduledTier.TokenLimit)
+			sub.TokensResetAt = &sub.StartedAt
+		}
+		sub.ScheduledTierID = nil
+		sub.ScheduledTier = nil
+
+		if err := s.repo.UpdateUserSubscription(sub); err != nil {
+			return applied, err
+		}
+		applied++
+	}
+	return applied, nil
 }
 
 func (s *subscriptionService) GetAllSubscriptions() ([]dto.UserSubscriptionResponse, error) {
@@ -83,13 +175,217 @@ func (s *subscriptionService) GetSubscriptionByUserID(userID string) (*dto.UserS
 	}
 
 	return &dto.UserSubscriptionResponse{
-		UserID:    sub.User.ID.String(),
-		Email:     sub.User.Email,
-		Fullname:  sub.User.Fullname,
-		Avatar:    sub.User.Avatar,
-		TierName:  sub.SubscriptionTier.Name,
-		IsActive:  sub.IsActive,
-		ExpiresAt: sub.ExpiresAt.Format("2006-01-02"),
-		Remaining: sub.RemainingTokens,
+		UserID:        sub.User.ID.String(),
+		Email:         sub.User.Email,
+		Fullname:      sub.User.Fullname,
+		Avatar:        sub.User.Avatar,
+		TierName:      sub.SubscriptionTier.Name,
+		IsActive:      sub.IsActive,
+		ExpiresAt:     sub.ExpiresAt.Format("2006-01-02"),
+		Remaining:     sub.RemainingTokens,
+		ScheduledTier: scheduledTierName(sub),
 	}, nil
 }
+
+func (s *subscriptionService) CreateVoucher(req *dto.CreateVoucherRequest, meta dto.RequestMeta) error {
+	startsAt, expiresAt := parseTimePointer(req.StartsAt), parseTimePointer(req.ExpiresAt)
+	if err := validateVoucherInput(req.DiscountType, req.DiscountValue, startsAt, expiresAt); err != nil {
+		return err
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	voucher := &models.Voucher{
+		Code:                  normalizeVoucherCode(req.Code),
+		Description:           req.Description,
+		DiscountType:          req.DiscountType,
+		DiscountValue:         req.DiscountValue,
+		MaxDiscount:           req.MaxDiscount,
+		StartsAt:              startsAt,
+		ExpiresAt:             expiresAt,
+		MaxRedemptions:        req.MaxRedemptions,
+		MaxRedemptionsPerUser: req.MaxRedemptionsPerUser,
+		IsActive:              isActive,
+	}
+	if err := s.repo.CreateVoucher(voucher, req.TierIDs); err != nil {
+		return err
+	}
+
+	after, err := s.repo.GetVoucherByID(voucher.ID)
+	if err != nil {
+		after = voucher
+	}
+	s.audit.Record(meta, AuditEntry{
+		Action: "voucher.created", EntityType: AuditEntityVoucher, EntityID: fmt.Sprint(voucher.ID), After: after,
+	})
+	return nil
+}
+
+func (s *subscriptionService) UpdateVoucher(req *dto.UpdateVoucherRequest, meta dto.RequestMeta) error {
+	existing, err := s.repo.GetVoucherByID(req.ID)
+	if err != nil {
+		return errors.New("voucher not found")
+	}
+
+	startsAt, expiresAt := parseTimePointer(req.StartsAt), parseTimePointer(req.ExpiresAt)
+	if err := validateVoucherInput(req.DiscountType, req.DiscountValue, startsAt, expiresAt); err != nil {
+		return err
+	}
+
+	isActive := existing.IsActive
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	voucher := &models.Voucher{
+		ID:                    req.ID,
+		Code:                  normalizeVoucherCode(req.Code),
+		Description:           req.Description,
+		DiscountType:          req.DiscountType,
+		DiscountValue:         req.DiscountValue,
+		MaxDiscount:           req.MaxDiscount,
+		StartsAt:              startsAt,
+		ExpiresAt:             expiresAt,
+		MaxRedemptions:        req.MaxRedemptions,
+		MaxRedemptionsPerUser: req.MaxRedemptionsPerUser,
+		IsActive:              isActive,
+	}
+	if err := s.repo.UpdateVoucher(voucher, req.TierIDs); err != nil {
+		return err
+	}
+
+	after, err := s.repo.GetVoucherByID(req.ID)
+	if err != nil {
+		after = voucher
+	}
+	s.audit.Record(meta, AuditEntry{
+		Action: "voucher.updated", EntityType: AuditEntityVoucher, EntityID: fmt.Sprint(req.ID),
+		Before: existing, After: after,
+	})
+	return nil
+}
+
+func (s *subscriptionService) DeleteVoucher(id uint, meta dto.RequestMeta) error {
+	before, err := s.repo.GetVoucherByID(id)
+	if err != nil {
+		return errors.New("voucher not found")
+	}
+	if err := s.repo.DeleteVoucher(id); err != nil {
+		return err
+	}
+	s.audit.Record(meta, AuditEntry{
+		Action: "voucher.deleted", EntityType: AuditEntityVoucher, EntityID: fmt.Sprint(id), Before: before,
+	})
+	return nil
+}
+
+func (s *subscriptionService) GetAllVouchers() ([]dto.VoucherResponse, error) {
+	vouchers, err := s.repo.FindAllVouchers()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []dto.VoucherResponse
+	for _, v := range vouchers {
+		result = append(result, toVoucherResponse(&v))
+	}
+	return result, nil
+}
+
+func (s *subscriptionService) GetVoucherDetail(id uint) (*dto.VoucherResponse, error) {
+	voucher, err := s.repo.GetVoucherByID(id)
+	if err != nil {
+		return nil, err
+	}
+	res := toVoucherResponse(voucher)
+	return &res, nil
+}
+
+// quotePlanChange menentukan jenis perubahan paket terhadap langganan yang sedang berjalan.
+// Untuk upgrade, sisa hari paket lama dihitung prorata menjadi kredit terhadap harga tier baru.
+func quotePlanChange(current *models.UserSubscription, tier *models.SubscriptionTier, now time.Time) (string, float64) {
+	if current == nil || !current.IsActive || !current.ExpiresAt.After(now) {
+		return "new", 0
+	}
+	if current.SubscriptionTierID == tier.ID {
+		return "renewal", 0
+	}
+
+	currentTier := current.SubscriptionTier
+	if tier.Price < currentTier.Price {
+		return "downgrade", 0
+	}
+
+	credit := 0.0
+	if currentTier.Duration > 0 {
+		remainingDays := current.ExpiresAt.Sub(now).Hours() / 24
+		credit = currentTier.Price * remainingDays / float64(currentTier.Duration)
+	}
+	credit = min(credit, currentTier.Price, tier.Price)
+	return "upgrade", credit
+}
+
+// nextTokenBalance menghitung saldo token untuk periode baru sesuai utils.GetTokenPolicy.
+func nextTokenBalance(remaining, limit int) int {
+	if utils.GetTokenPolicy() == "reset" || remaining < 0 {
+		return limit
+	}
+	return remaining + limit
+}
+
+func validateVoucherInput(discountType string, value float64, startsAt, expiresAt *time.Time) error {
+	if discountType == "percentage" && value > 100 {
+		return errors.New("percentage discount cannot exceed 100")
+	}
+	if startsAt != nil && expiresAt != nil && !expiresAt.After(*startsAt) {
+		return errors.New("voucher expiry must be after its start date")
+	}
+	return nil
+}
+
+func normalizeVoucherCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func toVoucherResponse(v *models.Voucher) dto.VoucherResponse {
+	var startsAt, expiresAt *string
+	if v.StartsAt != nil {
+		s := v.StartsAt.Format(time.RFC3339)
+		startsAt = &s
+	}
+	if v.ExpiresAt != nil {
+		e := v.ExpiresAt.Format(time.RFC3339)
+		expiresAt = &e
+	}
+
+	tierIDs := make([]uint, 0, len(v.Tiers))
+	for _, t := range v.Tiers {
+		tierIDs = append(tierIDs, t.ID)
+	}
+
+	return dto.VoucherResponse{
+		ID:                    v.ID,
+		Code:                  v.Code,
+		Description:           v.Description,
+		DiscountType:          v.DiscountType,
+		DiscountValue:         v.DiscountValue,
+		MaxDiscount:           v.MaxDiscount,
+		StartsAt:              startsAt,
+		ExpiresAt:             expiresAt,
+		MaxRedemptions:        v.MaxRedemptions,
+		MaxRedemptionsPerUser: v.MaxRedemptionsPerUser,
+		RedeemedCount:         v.RedeemedCount,
+		TierIDs:               tierIDs,
+		IsActive:              v.IsActive,
+	}
+}
+
+func scheduledTierName(sub *models.UserSubscription) string {
+	if sub.ScheduledTier == nil {
+		return ""
+	}
+	return sub.ScheduledTier.Name
+}
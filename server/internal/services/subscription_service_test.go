@@ -0,0 +1,55 @@
+package services
+
+import (
+	"math"
+	"server/internal/models"
+	"testing"
+	"time"
+)
+
+func TestQuotePlanChange(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	basic := models.SubscriptionTier{ID: 1, Price: 30000, Duration: 30}
+	pro := models.SubscriptionTier{ID: 2, Price: 90000, Duration: 30}
+	cheap := models.SubscriptionTier{ID: 3, Price: 10000, Duration: 30}
+
+	subscription := func(tier models.SubscriptionTier, active bool, remaining time.Duration) *models.UserSubscription {
+		return &models.UserSubscription{
+			SubscriptionTierID: tier.ID,
+			SubscriptionTier:   tier,
+			IsActive:           active,
+			ExpiresAt:          now.Add(remaining),
+		}
+	}
+	days := func(n int) time.Duration { return time.Duration(n) * 24 * time.Hour }
+
+	tests := []struct {
+		name       string
+		current    *models.UserSubscription
+		tier       models.SubscriptionTier
+		wantType   string
+		wantCredit float64
+	}{
+		{"no subscription", nil, basic, "new", 0},
+		{"inactive subscription", subscription(basic, false, days(10)), pro, "new", 0},
+		{"expired subscription", subscription(basic, true, -time.Hour), pro, "new", 0},
+		{"same tier", subscription(basic, true, days(10)), basic, "renewal", 0},
+		{"cheaper tier", subscription(pro, true, days(10)), basic, "downgrade", 0},
+		{"upgrade credits unused days", subscription(basic, true, days(15)), pro, "upgrade", 15000},
+		{"upgrade on the last day", subscription(basic, true, days(1)), pro, "upgrade", 1000},
+		{"credit capped by current price", subscription(basic, true, days(60)), pro, "upgrade", 30000},
+		{"same price on another tier", subscription(cheap, true, days(60)), models.SubscriptionTier{ID: 4, Price: 10000, Duration: 30}, "upgrade", 10000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotType, gotCredit := quotePlanChange(tt.current, &tt.tier, now)
+			if gotType != tt.wantType {
+				t.Errorf("type = %q, want %q", gotType, tt.wantType)
+			}
+			if math.Abs(gotCredit-tt.wantCredit) > 0.01 {
+				t.Errorf("credit = %v, want %v", gotCredit, tt.wantCredit)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"server/internal/config"
+	"server/internal/dto"
+	"server/internal/models"
+	"server/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	twoFactorPendingTTL    = 10 * time.Minute
+	twoFactorChallengeTTL  = 5 * time.Minute
+	twoFactorMaxAttempts   = 5
+	twoFactorRecoveryCodes = 10
+	twoFactorReplayWindow  = 3 * utils.TOTPPeriod * time.Second
+
+	// batas kegagalan per user lintas challenge, karena setiap login dengan password membuat challenge baru
+	twoFactorUserMaxFailures = 10
+	twoFactorLockDuration    = 15 * time.Minute
+)
+
+var (
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	ErrTwoFactorLocked      = errors.New("too many invalid two-factor codes, please try again later")
+)
+
+type twoFactorChallenge struct {
+	UserID string `json:"userId"`
+}
+
+// createTwoFactorChallenge menyimpan challenge login sementara; session baru diterbitkan setelah kode 2FA valid.
+func (s *authService) createTwoFactorChallenge(user *models.User) (*dto.TwoFactorChallengeResponse, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(twoFactorChallenge{UserID: user.ID.String()})
+	if err != nil {
+		return nil, err
+	}
+	if err := config.RedisClient.Set(config.Ctx, "2fa:challenge:"+utils.HashToken(token), data, twoFactorChallengeTTL).Err(); err != nil {
+		return nil, err
+	}
+
+	return &dto.TwoFactorChallengeResponse{
+		ChallengeToken: token,
+		ExpiresIn:      int(twoFactorChallengeTTL.Seconds()),
+	}, nil
+}
+
+// CompleteTwoFactorLogin menukar challenge token + kode TOTP (atau recovery code) dengan session.
+func (s *authService) CompleteTwoFactorLogin(req *dto.TwoFactorLoginRequest, meta dto.RequestMeta) (*dto.AuthResponse, error) {
+	key := "2fa:challenge:" + utils.HashToken(req.ChallengeToken)
+	data, err := config.RedisClient.Get(config.Ctx, key).Bytes()
+	if err != nil {
+		return nil, errors.New("login challenge expired or invalid")
+	}
+
+	var challenge twoFactorChallenge
+	if err := json.Unmarshal(data, &challenge); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(challenge.UserID)
+	if err != nil || !user.TwoFactorEnabled {
+		config.RedisClient.Del(config.Ctx, key)
+		return nil, errors.New("login challenge expired or invalid")
+	}
+	if twoFactorLocked(user.ID.String()) {
+		return nil, ErrTwoFactorLocked
+	}
+
+	var valid bool
+	switch {
+	case req.Code != "":
+		if valid, err = verifyStoredTOTP(user, req.Code); err != nil {
+			return nil, err
+		}
+	case req.RecoveryCode != "":
+		valid, err = s.repo.UseRecoveryCode(user.ID.String(), hashRecoveryCode(req.RecoveryCode))
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("code or recovery code is required")
+	}
+
+	if !valid {
+		if err := s.recordTwoFactorFailure(user, meta); err != nil {
+			config.RedisClient.Del(config.Ctx, key, key+":attempts")
+			return nil, err
+		}
+		attemptsKey := key + ":attempts"
+		attempts, _ := config.RedisClient.Incr(config.Ctx, attemptsKey).Result()
+		config.RedisClient.Expire(config.Ctx, attemptsKey, twoFactorChallengeTTL)
+		if attempts >= twoFactorMaxAttempts {
+			config.RedisClient.Del(config.Ctx, key, attemptsKey)
+			return nil, errors.New("too many invalid codes, please login again")
+		}
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	config.RedisClient.Del(config.Ctx, key, key+":attempts", "2fa:fail:"+user.ID.String())
+	s.logSecurityEvent(models.SecurityEvent{UserID: &user.ID, Email: user.Email, Event: SecurityEventLoginSucceeded}, meta)
+	return s.issueTokens(user, uuid.New(), time.Now(), meta)
+}
+
+// EnrollTwoFactor membuat secret baru yang menunggu konfirmasi kode pertama dari aplikasi authenticator.
+func (s *authService) EnrollTwoFactor(userID string) (*dto.TwoFactorEnrollResponse, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if user.TwoFactorEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := config.RedisClient.Set(config.Ctx, "2fa:pending:"+userID, secret, twoFactorPendingTTL).Err(); err != nil {
+		return nil, err
+	}
+
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "Forms"
+	}
+	uri := utils.TOTPProvisioningURI(issuer, user.Email, secret)
+	qr, err := utils.QRCodeDataURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TwoFactorEnrollResponse{
+		Secret:     secret,
+		OTPAuthURI: uri,
+		QRCode:     qr,
+		ExpiresIn:  int(twoFactorPendingTTL.Seconds()),
+	}, nil
+}
+
+// ConfirmTwoFactor mengaktifkan 2FA setelah kode pertama valid dan mengembalikan recovery code (hanya sekali).
+func (s *authService) ConfirmTwoFactor(userID string, req *dto.TwoFactorCodeRequest, meta dto.RequestMeta) (*dto.RecoveryCodesResponse, error) {
+	secret, err := config.RedisClient.Get(config.Ctx, "2fa:pending:"+userID).Result()
+	if err != nil {
+		return nil, errors.New("no pending enrollment, please start again")
+	}
+	if !verifyUserTOTP(userID, secret, req.Code) {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, rows, err := newRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := utils.EncryptTOTPSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.EnableTwoFactor(userID, encrypted, rows); err != nil {
+		return nil, err
+	}
+
+	config.RedisClient.Del(config.Ctx, "2fa:pending:"+userID)
+	InvalidateUserAccessCache(userID)
+
+	if id, err := uuid.Parse(userID); err == nil {
+		s.logSecurityEvent(models.SecurityEvent{UserID: &id, Event: SecurityEventTwoFactorOn}, meta)
+	}
+	return &dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (s *authService) DisableTwoFactor(userID string, req *dto.TwoFactorDisableRequest, meta dto.RequestMeta) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	if !user.TwoFactorEnabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+	if user.Role == RoleAdmin && utils.AdminTwoFactorRequired() {
+		return errors.New("two-factor authentication is required for admin accounts")
+	}
+	if !utils.CheckPasswordHash(req.Password, user.Password) {
+		return errors.New("password is incorrect")
+	}
+	if twoFactorLocked(userID) {
+		return ErrTwoFactorLocked
+	}
+	if valid, err := verifyStoredTOTP(user, req.Code); err != nil {
+		return err
+	} else if !valid {
+		if err := s.recordTwoFactorFailure(user, meta); err != nil {
+			return err
+		}
+		return ErrInvalidTwoFactorCode
+	}
+
+	if err := s.repo.DisableTwoFactor(userID); err != nil {
+		return err
+	}
+	InvalidateUserAccessCache(userID)
+	s.logSecurityEvent(models.SecurityEvent{UserID: &user.ID, Email: user.Email, Event: SecurityEventTwoFactorOff}, meta)
+	return nil
+}
+
+func (s *authService) RegenerateRecoveryCodes(userID string, req *dto.TwoFactorCodeRequest) (*dto.RecoveryCodesResponse, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if !user.TwoFactorEnabled {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+	if twoFactorLocked(userID) {
+		return nil, ErrTwoFactorLocked
+	}
+	if valid, err := verifyStoredTOTP(user, req.Code); err != nil {
+		return nil, err
+	} else if !valid {
+		if err := s.recordTwoFactorFailure(user, dto.RequestMeta{}); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, rows, err := newRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(userID, rows); err != nil {
+		return nil, err
+	}
+	return &dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// MigrateTwoFactorSecrets mengenkripsi secret TOTP yang masih tersimpan sebagai plaintext. Dijalankan
+// saat startup; secret lama tetap bisa dipakai selama migrasi karena DecryptTOTPSecret menerimanya.
+func (s *authService) MigrateTwoFactorSecrets() (int64, error) {
+	users, err := s.repo.FindPlaintextTwoFactorSecrets()
+	if err != nil {
+		return 0, err
+	}
+	var migrated int64
+	for _, u := range users {
+		encrypted, err := utils.EncryptTOTPSecret(u.TwoFactorSecret)
+		if err != nil {
+			return migrated, err
+		}
+		if err := s.repo.UpdateTwoFactorSecret(u.ID.String(), encrypted); err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+	return migrated, nil
+}
+
+func twoFactorLocked(userID string) bool {
+	locked, _ := config.RedisClient.Exists(config.Ctx, "2fa:lock:"+userID).Result()
+	return locked > 0
+}
+
+// recordTwoFactorFailure menghitung kode salah per user lintas challenge dan mengunci verifikasi 2FA
+// selama twoFactorLockDuration setelah twoFactorUserMaxFailures kali gagal.
+func (s *authService) recordTwoFactorFailure(user *models.User, meta dto.RequestMeta) error {
+	failKey := "2fa:fail:" + user.ID.String()
+	failures, err := config.RedisClient.Incr(config.Ctx, failKey).Result()
+	if err != nil {
+		return err
+	}
+	if failures == 1 {
+		config.RedisClient.Expire(config.Ctx, failKey, twoFactorLockDuration)
+	}
+	if failures < twoFactorUserMaxFailures {
+		return nil
+	}
+
+	config.RedisClient.Set(config.Ctx, "2fa:lock:"+user.ID.String(), 1, twoFactorLockDuration)
+	config.RedisClient.Del(config.Ctx, failKey)
+	s.logSecurityEvent(models.SecurityEvent{UserID: &user.ID, Email: user.Email, Event: SecurityEventTwoFactorLocked}, meta)
+	return ErrTwoFactorLocked
+}
+
+// verifyStoredTOTP mendekripsi secret milik user lalu memvalidasi kode.
+func verifyStoredTOTP(user *models.User, code string) (bool, error) {
+	secret, err := utils.DecryptTOTPSecret(user.TwoFactorSecret)
+	if err != nil {
+		return false, err
+	}
+	return verifyUserTOTP(user.ID.String(), secret, code), nil
+}
+
+// verifyUserTOTP memvalidasi kode dan menolak kode dari time step yang sudah pernah dipakai (replay).
+func verifyUserTOTP(userID, secret, code string) bool {
+	counter, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return false
+	}
+	fresh, err := config.RedisClient.SetNX(config.Ctx, fmt.Sprintf("2fa:used:%s:%d", userID, counter), 1, twoFactorReplayWindow).Result()
+	return err == nil && fresh
+}
+
+func newRecoveryCodes(userID string) ([]string, []models.TwoFactorRecoveryCode, error) {
+	codes, err := utils.GenerateRecoveryCodes(twoFactorRecoveryCodes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows := make([]models.TwoFactorRecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		rows = append(rows, models.TwoFactorRecoveryCode{
+			UserID:   uuid.MustParse(userID),
+			CodeHash: hashRecoveryCode(code),
+		})
+	}
+	return codes, rows, nil
+}
+
+func hashRecoveryCode(code string) string {
+	return utils.HashToken(strings.ToLower(strings.TrimSpace(code)))
+}
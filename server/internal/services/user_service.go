@@ -1,19 +1,29 @@
 package services
 
 import (
+	"errors"
 	"server/internal/dto"
 	"server/internal/repositories"
 	"server/internal/utils"
+	"time"
 )
 
 type UserService interface {
 	GetProfile(userID string) (*dto.UserProfileResponse, error)
 	UpdateProfile(userID string, req *dto.UpdateProfileRequest) error
 	GetMySubscription(userID string) (*dto.UserSubscriptionResponse, error)
-	UpdateUserSubscriptionToken(userID string, used int) error
+	CancelSubscription(userID string) (*dto.UserSubscriptionResponse, error)
+	ResumeSubscription(userID string) (*dto.UserSubscriptionResponse, error)
+	GetTokenUsage(userID string, page, limit int) (*dto.TokenUsageListResponse, error)
 	GetTransactionHistory(userID string) ([]dto.PaymentResponse, error)
+	GetInvoices(userID string) ([]dto.InvoiceResponse, error)
+	GetInvoicePDF(userID, invoiceID string) ([]byte, string, error)
 	GetMyForms(userID string) ([]dto.MyFormResponse, error)
 	GetMyFormDetail(formID string) (*dto.MyFormDetailResponse, error)
+	ExportData(userID string) ([]byte, string, error)
+	ScheduleAccountDeletion(userID string, req *dto.AccountDeletionRequest) (*dto.AccountDeletionResponse, error)
+	CancelAccountDeletion(userID string) error
+	PurgeScheduledAccounts() (int64, error)
 }
 
 type userService struct {
@@ -29,12 +39,16 @@ func (s *userService) GetProfile(userID string) (*dto.UserProfileResponse, error
 	if err != nil {
 		return nil, err
 	}
-	return &dto.UserProfileResponse{
+	res := &dto.UserProfileResponse{
 		ID:       user.ID.String(),
 		Email:    user.Email,
 		Fullname: user.Fullname,
 		Avatar:   user.Avatar,
-	}, nil
+	}
+	if user.DeletionScheduledAt != nil {
+		res.DeletionScheduledAt = user.DeletionScheduledAt.Format("2006-01-02 15:04:05")
+	}
+	return res, nil
 }
 
 func (s *userService) UpdateProfile(userID string, req *dto.UpdateProfileRequest) error {
@@ -61,20 +75,89 @@ func (s *userService) GetMySubscription(userID string) (*dto.UserSubscriptionRes
 	if err != nil {
 		return nil, err
 	}
+	canceledAt := ""
+	if sub.CanceledAt != nil {
+		canceledAt = sub.CanceledAt.Format("2006-01-02 15:04:05")
+	}
 	return &dto.UserSubscriptionResponse{
-		UserID:    sub.User.ID.String(),
-		Email:     sub.User.Email,
-		Fullname:  sub.User.Fullname,
-		Avatar:    sub.User.Avatar,
-		TierName:  sub.SubscriptionTier.Name,
-		IsActive:  sub.IsActive,
-		ExpiresAt: sub.ExpiresAt.Format("2006-01-02"),
-		Remaining: sub.RemainingTokens,
+		UserID:        sub.User.ID.String(),
+		Email:         sub.User.Email,
+		Fullname:      sub.User.Fullname,
+		Avatar:        sub.User.Avatar,
+		TierName:      sub.SubscriptionTier.Name,
+		IsActive:      sub.IsActive,
+		ExpiresAt:     sub.ExpiresAt.Format("2006-01-02"),
+		Remaining:     sub.RemainingTokens,
+		ScheduledTier: scheduledTierName(sub),
+		AutoRenew:     sub.AutoRenew,
+		CanceledAt:    canceledAt,
 	}, nil
 }
 
-func (s *userService) UpdateUserSubscriptionToken(userID string, used int) error {
-	return s.repo.UpdateUserTokenUsage(userID, used)
+// CancelSubscription menghentikan perpanjangan otomatis, akses tetap berlaku sampai ExpiresAt.
+func (s *userService) CancelSubscription(userID string) (*dto.UserSubscriptionResponse, error) {
+	sub, err := s.repo.GetUserSubscription(userID)
+	if err != nil {
+		return nil, errors.New("subscription not found")
+	}
+	if !sub.IsActive || !sub.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("subscription is not active")
+	}
+	if !sub.AutoRenew {
+		return nil, errors.New("subscription is already canceled")
+	}
+
+	now := time.Now()
+	if err := s.repo.SetSubscriptionAutoRenew(userID, false, &now); err != nil {
+		return nil, err
+	}
+	return s.GetMySubscription(userID)
+}
+
+func (s *userService) ResumeSubscription(userID string) (*dto.UserSubscriptionResponse, error) {
+	sub, err := s.repo.GetUserSubscription(userID)
+	if err != nil {
+		return nil, errors.New("subscription not found")
+	}
+	if !sub.IsActive || !sub.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("subscription is not active")
+	}
+	if sub.AutoRenew {
+		return nil, errors.New("subscription is not canceled")
+	}
+
+	if err := s.repo.SetSubscriptionAutoRenew(userID, true, nil); err != nil {
+		return nil, err
+	}
+	return s.GetMySubscription(userID)
+}
+
+func (s *userService) GetTokenUsage(userID string, page, limit int) (*dto.TokenUsageListResponse, error) {
+	offset := (page - 1) * limit
+
+	usages, total, err := s.repo.GetTokenUsages(userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []dto.TokenUsageResponse{}
+	for _, u := range usages {
+		results = append(results, dto.TokenUsageResponse{
+			ID:           u.ID,
+			Action:       u.Action,
+			Tokens:       u.Tokens,
+			BalanceAfter: u.BalanceAfter,
+			ReferenceID:  u.ReferenceID,
+			CreatedAt:    u.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	return &dto.TokenUsageListResponse{
+		Usages: results,
+		Total:  total,
+		Page:   page,
+		Limit:  limit,
+	}, nil
 }
 
 func (s *userService) GetTransactionHistory(userID string) ([]dto.PaymentResponse, error) {
@@ -84,6 +167,11 @@ func (s *userService) GetTransactionHistory(userID string) ([]dto.PaymentRespons
 	}
 	var result []dto.PaymentResponse
 	for _, p := range payments {
+		var invoiceID, invoiceNumber string
+		if p.Invoice != nil {
+			invoiceID = p.Invoice.ID.String()
+			invoiceNumber = p.Invoice.Number
+		}
 		result = append(result, dto.PaymentResponse{
 			ID:            p.ID.String(),
 			UserID:        p.UserID.String(),
@@ -91,17 +179,63 @@ func (s *userService) GetTransactionHistory(userID string) ([]dto.PaymentRespons
 			Fullname:      p.User.Fullname,
 			TierID:        p.TierID,
 			TierName:      p.Tier.Name,
+			Type:          p.Type,
 			Subtotal:      p.Subtotal,
+			Credit:        p.Credit,
+			Discount:      p.Discount,
 			Tax:           p.Tax,
 			Total:         p.Total,
 			PaymentMethod: p.Method,
 			Status:        p.Status,
-			PaidAt:        p.PaidAt.Format("2006-01-02 15:04:05"),
+			PaidAt:        formatPaidAt(p.PaidAt),
+			InvoiceID:     invoiceID,
+			InvoiceNumber: invoiceNumber,
 		})
 	}
 	return result, nil
 }
 
+func (s *userService) GetInvoices(userID string) ([]dto.InvoiceResponse, error) {
+	invoices, err := s.repo.GetInvoices(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []dto.InvoiceResponse
+	for _, inv := range invoices {
+		result = append(result, dto.InvoiceResponse{
+			ID:            inv.ID.String(),
+			Number:        inv.Number,
+			PaymentID:     inv.PaymentID.String(),
+			CustomerName:  inv.CustomerName,
+			CustomerEmail: inv.CustomerEmail,
+			TierName:      inv.TierName,
+			Method:        inv.Method,
+			Subtotal:      inv.Subtotal,
+			Credit:        inv.Credit,
+			Discount:      inv.Discount,
+			TaxRate:       inv.TaxRate,
+			Tax:           inv.Tax,
+			Total:         inv.Total,
+			IssuedAt:      inv.IssuedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	return result, nil
+}
+
+func (s *userService) GetInvoicePDF(userID, invoiceID string) ([]byte, string, error) {
+	invoice, err := s.repo.GetInvoiceByID(userID, invoiceID)
+	if err != nil {
+		return nil, "", errors.New("invoice not found")
+	}
+
+	pdf, err := utils.GenerateInvoicePDF(invoice)
+	if err != nil {
+		return nil, "", err
+	}
+	return pdf, invoice.Number + ".pdf", nil
+}
+
 func (s *userService) GetMyForms(userID string) ([]dto.MyFormResponse, error) {
 	forms, err := s.repo.GetFormsByUser(userID)
 	if err != nil {
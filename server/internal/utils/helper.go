@@ -1,8 +1,13 @@
 package utils
 
 import (
+	"bufio"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"math/rand"
 	"net/http"
 	"os"
@@ -11,6 +16,8 @@ import (
 	"strings"
 	"time"
 
+	"server/internal/dto"
+
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/datatypes"
@@ -38,6 +45,17 @@ func MustGetUserID(c *gin.Context) string {
 	return idStr
 }
 
+// GetRequestMeta mengambil IP, user agent, user yang login dan request ID, dipakai untuk metadata
+// sesi dan audit log.
+func GetRequestMeta(c *gin.Context) dto.RequestMeta {
+	return dto.RequestMeta{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		ActorID:   c.GetString("userID"),
+		RequestID: c.GetString("requestID"),
+	}
+}
+
 func MustGetRole(c *gin.Context) string {
 	role, exists := c.Get("role")
 	if !exists {
@@ -105,6 +123,39 @@ func GetTaxRate() float64 {
 	return rate
 }
 
+// GetTokenPolicy menentukan perlakuan sisa token saat perpanjangan/perubahan paket:
+// "carryover" (default) menambahkan sisa token ke kuota paket baru, "reset" membuang sisa token.
+func GetTokenPolicy() string {
+	val := strings.ToLower(os.Getenv("SUBSCRIPTION_TOKEN_POLICY"))
+	if val == "reset" {
+		return "reset"
+	}
+	return "carryover"
+}
+
+// GetReconcileDelay adalah umur minimal payment pending sebelum dicek ulang ke gateway.
+func GetReconcileDelay() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("PAYMENT_RECONCILE_AFTER_MINUTES"))
+	if err != nil || minutes <= 0 {
+		return 15 * time.Minute
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// GetAccountDeletionGrace adalah masa tenggang sebelum akun yang diminta dihapus benar-benar dihapus.
+func GetAccountDeletionGrace() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("ACCOUNT_DELETION_GRACE_DAYS"))
+	if err != nil || days < 0 {
+		return 14 * 24 * time.Hour
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// AdminTwoFactorRequired mewajibkan 2FA untuk admin kecuali REQUIRE_ADMIN_2FA=false.
+func AdminTwoFactorRequired() bool {
+	return strings.ToLower(os.Getenv("REQUIRE_ADMIN_2FA")) != "false"
+}
+
 func ParseDayOfWeek(day string) time.Weekday {
 	switch strings.ToLower(day) {
 	case "sunday":
@@ -154,15 +205,35 @@ func RandomUserAvatar(fullname string) string {
 	return fmt.Sprintf("https://api.dicebear.com/6.x/initials/svg?seed=%s", fullname)
 }
 
-func GenerateOTP(length int) string {
+// GenerateOTP membuat kode numerik dari crypto/rand, math/rand mudah ditebak dan tidak boleh dipakai untuk OTP.
+func GenerateOTP(length int) (string, error) {
 	digits := "0123456789"
 	var sb strings.Builder
 
 	for i := 0; i < length; i++ {
-		sb.WriteByte(digits[rand.Intn(len(digits))])
+		n, err := crand.Int(crand.Reader, big.NewInt(int64(len(digits))))
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(digits[n.Int64()])
 	}
 
-	return sb.String()
+	return sb.String(), nil
+}
+
+// GenerateRandomToken membuat token acak hex sepanjang 2*size karakter untuk link/verifikasi sekali pakai.
+func GenerateRandomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashToken menyimpan token sekali pakai sebagai SHA-256 agar kebocoran database/redis tidak membocorkan token aslinya.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 func GenerateSlug(input string) string {
@@ -204,3 +275,44 @@ func StringToInt(s string) int {
 	}
 	return i
 }
+
+// GetTrustedProxies membaca TRUSTED_PROXIES (IP/CIDR dipisah koma) untuk gin; nil berarti tidak ada
+// proxy yang dipercaya.
+func GetTrustedProxies() []string {
+	var proxies []string
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
+
+// LoadEnv membaca file .env di working directory bila ada. Variabel yang sudah diset dari
+// environment (mis. env_file docker-compose) tidak ditimpa.
+func LoadEnv() {
+	file, err := os.Open(".env")
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, val, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			continue
+		}
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		if len(val) >= 2 && (val[0] == '"' || val[0] == '\'') && val[len(val)-1] == val[0] {
+			val = val[1 : len(val)-1]
+		}
+		if _, exists := os.LookupEnv(key); !exists {
+			os.Setenv(key, val)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"server/internal/models"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// GenerateInvoicePDF membuat dokumen invoice satu halaman berisi data customer dan rincian pajak.
+func GenerateInvoicePDF(inv *models.Invoice) ([]byte, error) {
+	company := os.Getenv("INVOICE_COMPANY_NAME")
+	if company == "" {
+		company = "Form Builder"
+	}
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Invoice "+inv.Number, false)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.Cell(0, 10, company)
+	pdf.Ln(12)
+
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.Cell(0, 6, "Invoice No : "+inv.Number)
+	pdf.Ln(6)
+	pdf.Cell(0, 6, "Issued At  : "+inv.IssuedAt.Format("02 January 2006 15:04"))
+	pdf.Ln(6)
+	pdf.Cell(0, 6, "Payment ID : "+inv.PaymentID.String())
+	pdf.Ln(12)
+
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.Cell(0, 6, "Billed To")
+	pdf.Ln(6)
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.Cell(0, 6, inv.CustomerName)
+	pdf.Ln(6)
+	pdf.Cell(0, 6, inv.CustomerEmail)
+	pdf.Ln(12)
+
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(120, 8, "Description", "1", 0, "L", false, 0, "")
+	pdf.CellFormat(60, 8, "Amount", "1", 1, "R", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", 11)
+	row := func(label string, amount float64) {
+		pdf.CellFormat(120, 8, label, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(60, 8, FormatRupiah(amount), "1", 1, "R", false, 0, "")
+	}
+
+	row("Subscription "+inv.TierName, inv.Subtotal)
+	if inv.Credit > 0 {
+		row("Proration credit", -inv.Credit)
+	}
+	if inv.Discount > 0 {
+		row("Voucher discount", -inv.Discount)
+	}
+	row(fmt.Sprintf("Tax (%.2f%%)", inv.TaxRate*100), inv.Tax)
+
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(120, 8, "Total", "1", 0, "L", false, 0, "")
+	pdf.CellFormat(60, 8, FormatRupiah(inv.Total), "1", 1, "R", false, 0, "")
+
+	if inv.Method != "" {
+		pdf.Ln(6)
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.Cell(0, 6, "Paid via "+inv.Method)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// FormatRupiah memformat nominal menjadi "Rp 150.000" (dibulatkan ke rupiah terdekat).
+func FormatRupiah(amount float64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	digits := fmt.Sprintf("%.0f", amount)
+	var sb strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteByte('.')
+		}
+		sb.WriteRune(d)
+	}
+	return sign + "Rp " + sb.String()
+}
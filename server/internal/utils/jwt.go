@@ -2,77 +2,243 @@ package utils
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
-var accessTokenSecret = []byte(os.Getenv("JWT_ACCESS_SECRET"))
-var refreshTokenSecret = []byte(os.Getenv("JWT_REFRESH_SECRET"))
+const (
+	AccessTokenTTL  = 60 * time.Minute
+	RefreshTokenTTL = 7 * 24 * time.Hour
+)
+
+// signingKeys menyimpan semua key HMAC yang masih diterima (per kid); token baru selalu
+// ditandatangani dengan key aktif sehingga key lama bisa dipensiunkan setelah token-nya kedaluwarsa.
+// kid untuk key dari JWT_*_SECRET, juga dipakai untuk token lama tanpa kid
+const legacyKid = "default"
+
+type signingKeys struct {
+	activeKid string
+	keys      map[string][]byte
+}
+
+var (
+	accessKeys  *signingKeys
+	refreshKeys *signingKeys
+	jwtIssuer   string
+	jwtAudience string
+
+	// token tanpa kid hanya diterima sebelum waktu ini; zero value berarti selalu ditolak
+	legacyTokenCutoff time.Time
+)
 
 type Claims struct {
 	UserID string `json:"userId"`
+	Role   string `json:"role"`
 	jwt.RegisteredClaims
 }
 
-func GenerateAccessToken(userID string) (string, error) {
-	claims := Claims{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(60 * time.Minute)),
-		},
+// InitJWTKeys membaca key setelah env dimuat (bukan saat package init). Format:
+// JWT_ACCESS_KEYS="2024-01:secretA,2024-06:secretB" + JWT_ACCESS_ACTIVE_KID="2024-06",
+// atau cukup JWT_ACCESS_SECRET untuk satu key (kid "default"). Sama untuk JWT_REFRESH_*.
+// JWT_LEGACY_TOKEN_CUTOFF (RFC 3339) menentukan sampai kapan token tanpa kid masih diterima,
+// biasanya waktu deploy ditambah umur refresh token (7 hari).
+func InitJWTKeys() error {
+	var err error
+	if accessKeys, err = loadSigningKeys("JWT_ACCESS"); err != nil {
+		return err
+	}
+	if refreshKeys, err = loadSigningKeys("JWT_REFRESH"); err != nil {
+		return err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(accessTokenSecret)
+	jwtIssuer = os.Getenv("JWT_ISSUER")
+	if jwtIssuer == "" {
+		jwtIssuer = "forms-api"
+	}
+	jwtAudience = os.Getenv("JWT_AUDIENCE")
+	if jwtAudience == "" {
+		jwtAudience = "forms-app"
+	}
+
+	legacyTokenCutoff = time.Time{}
+	if cutoff := os.Getenv("JWT_LEGACY_TOKEN_CUTOFF"); cutoff != "" {
+		if legacyTokenCutoff, err = time.Parse(time.RFC3339, cutoff); err != nil {
+			return fmt.Errorf("JWT_LEGACY_TOKEN_CUTOFF: %w", err)
+		}
+	}
+	return nil
 }
 
-func GenerateRefreshToken(userID string) (string, error) {
-	claims := jwt.RegisteredClaims{
-		Subject:   userID,
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)),
+func loadSigningKeys(prefix string) (*signingKeys, error) {
+	set := &signingKeys{keys: make(map[string][]byte)}
+
+	for _, pair := range strings.Split(os.Getenv(prefix+"_KEYS"), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kid, secret, ok := strings.Cut(pair, ":")
+		if !ok || kid == "" || secret == "" {
+			return nil, fmt.Errorf("%s_KEYS: invalid entry %q, expected kid:secret", prefix, pair)
+		}
+		set.keys[kid] = []byte(secret)
+		set.activeKid = kid
 	}
 
+	if secret := os.Getenv(prefix + "_SECRET"); secret != "" {
+		if _, exists := set.keys[legacyKid]; !exists {
+			set.keys[legacyKid] = []byte(secret)
+		}
+		if set.activeKid == "" {
+			set.activeKid = legacyKid
+		}
+	}
+
+	if active := os.Getenv(prefix + "_ACTIVE_KID"); active != "" {
+		set.activeKid = active
+	}
+	if _, ok := set.keys[set.activeKid]; !ok {
+		return nil, fmt.Errorf("%s: no signing key configured for active kid %q", prefix, set.activeKid)
+	}
+	return set, nil
+}
+
+func (s *signingKeys) sign(claims jwt.Claims) (string, error) {
+	if s == nil {
+		return "", errors.New("jwt keys are not initialized")
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(refreshTokenSecret)
+	token.Header["kid"] = s.activeKid
+	return token.SignedString(s.keys[s.activeKid])
+}
+
+func (s *signingKeys) keyFunc(token *jwt.Token) (interface{}, error) {
+	if s == nil {
+		return nil, errors.New("jwt keys are not initialized")
+	}
+	if token.Method != jwt.SigningMethodHS256 {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		// token yang terbit sebelum rotasi key tidak punya kid dan ditandatangani dengan *_SECRET;
+		// diterima sampai JWT_LEGACY_TOKEN_CUTOFF agar deploy tidak memaksa semua user login ulang
+		if !time.Now().Before(legacyTokenCutoff) {
+			return nil, errors.New("tokens without kid are no longer accepted")
+		}
+		kid = legacyKid
+	}
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+func newRegisteredClaims(subject string, ttl time.Duration) jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		ID:        uuid.NewString(),
+		Issuer:    jwtIssuer,
+		Audience:  jwt.ClaimStrings{jwtAudience},
+		Subject:   subject,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+}
+
+// verifyRegisteredClaims memeriksa issuer dan audience. Token lama tanpa kid belum membawa kedua
+// claim tersebut, sehingga selama masa transisi claim yang kosong dibiarkan tetapi yang terisi
+// tetap harus cocok.
+func verifyRegisteredClaims(token *jwt.Token, claims *jwt.RegisteredClaims) error {
+	kid, _ := token.Header["kid"].(string)
+	required := kid != ""
+	if !claims.VerifyIssuer(jwtIssuer, required) {
+		return errors.New("invalid token issuer")
+	}
+	if !claims.VerifyAudience(jwtAudience, required) {
+		return errors.New("invalid token audience")
+	}
+	return nil
+}
+
+func GenerateAccessToken(userID, role string) (string, error) {
+	return accessKeys.sign(Claims{
+		UserID:           userID,
+		Role:             role,
+		RegisteredClaims: newRegisteredClaims(userID, AccessTokenTTL),
+	})
+}
+
+func GenerateRefreshToken(userID string) (string, error) {
+	// jti unik agar dua refresh token yang terbit di detik yang sama tetap punya hash berbeda
+	claims := newRegisteredClaims(userID, RefreshTokenTTL)
+	return refreshKeys.sign(&claims)
 }
 
 func SetRefreshTokenCookie(c *gin.Context, refreshToken string) {
 	domain := os.Getenv("COOKIE_DOMAIN")
-	c.SetCookie("refreshToken", refreshToken, 7*24*3600, "/", domain, true, true)
+	c.SetCookie("refreshToken", refreshToken, int(RefreshTokenTTL.Seconds()), "/", domain, true, true)
 }
 
 func SetAccessTokenCookie(c *gin.Context, accessToken string) {
 	domain := os.Getenv("COOKIE_DOMAIN")
-	c.SetCookie("accessToken", accessToken, 3600, "/", domain, true, true)
+	c.SetCookie("accessToken", accessToken, int(AccessTokenTTL.Seconds()), "/", domain, true, true)
+}
+
+func ClearTokenCookies(c *gin.Context) {
+	domain := os.Getenv("COOKIE_DOMAIN")
+	c.SetCookie("accessToken", "", -1, "/", domain, true, true)
+	c.SetCookie("refreshToken", "", -1, "/", domain, true, true)
+}
+
+// BearerToken mengambil token dari header "Authorization: Bearer <token>".
+func BearerToken(c *gin.Context) string {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// WantsTokenResponse bernilai true untuk client non-browser (mobile/CLI) yang meminta token
+// di response body lewat header "X-Auth-Mode: token" atau sudah memakai Bearer token.
+func WantsTokenResponse(c *gin.Context) bool {
+	return strings.EqualFold(c.GetHeader("X-Auth-Mode"), "token") || BearerToken(c) != ""
 }
 
 func DecodeRefreshToken(tokenStr string) (string, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return refreshTokenSecret, nil
-	})
+	claims := &jwt.RegisteredClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, refreshKeys.keyFunc)
 	if err != nil {
 		return "", err
 	}
-
-	if claims, ok := token.Claims.(*jwt.RegisteredClaims); ok && token.Valid {
-		return claims.Subject, nil
+	if !token.Valid {
+		return "", errors.New("invalid refresh token")
+	}
+	if err := verifyRegisteredClaims(token, claims); err != nil {
+		return "", err
 	}
-	return "", errors.New("invalid refresh token")
+	return claims.Subject, nil
 }
 
 func DecodeAccessToken(tokenStr string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return accessTokenSecret, nil
-	})
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, accessKeys.keyFunc)
 	if err != nil {
 		return nil, err
 	}
-
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	if !token.Valid {
+		return nil, errors.New("invalid access token")
+	}
+	if err := verifyRegisteredClaims(token, &claims.RegisteredClaims); err != nil {
+		return nil, err
 	}
-	return nil, errors.New("invalid access token")
+	return claims, nil
 }
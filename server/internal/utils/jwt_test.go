@@ -0,0 +1,224 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestLoadSigningKeys(t *testing.T) {
+	tests := []struct {
+		name       string
+		keys       string
+		secret     string
+		activeKid  string
+		wantActive string
+		wantKids   []string
+		wantErr    bool
+	}{
+		{name: "single secret uses default kid", secret: "s1", wantActive: legacyKid, wantKids: []string{legacyKid}},
+		{name: "last listed key is active", keys: "2024-01:a,2024-06:b", wantActive: "2024-06", wantKids: []string{"2024-01", "2024-06"}},
+		{name: "explicit active kid", keys: "2024-01:a,2024-06:b", activeKid: "2024-01", wantActive: "2024-01", wantKids: []string{"2024-01", "2024-06"}},
+		{name: "listed keys win over legacy secret", keys: "2024-06:b", secret: "s1", wantActive: "2024-06", wantKids: []string{"2024-06", legacyKid}},
+		{name: "malformed entry", keys: "2024-01", wantErr: true},
+		{name: "active kid without key", keys: "2024-01:a", activeKid: "2025-01", wantErr: true},
+		{name: "nothing configured", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JWT_TEST_KEYS", tt.keys)
+			t.Setenv("JWT_TEST_SECRET", tt.secret)
+			t.Setenv("JWT_TEST_ACTIVE_KID", tt.activeKid)
+
+			set, err := loadSigningKeys("JWT_TEST")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if set.activeKid != tt.wantActive {
+				t.Errorf("active kid = %q, want %q", set.activeKid, tt.wantActive)
+			}
+			if len(set.keys) != len(tt.wantKids) {
+				t.Errorf("got %d keys, want %d", len(set.keys), len(tt.wantKids))
+			}
+			for _, kid := range tt.wantKids {
+				if _, ok := set.keys[kid]; !ok {
+					t.Errorf("missing key %q", kid)
+				}
+			}
+		})
+	}
+}
+
+// setJWTEnv memuat key access & refresh dari env lalu mengembalikan state global setelah test.
+// Token tanpa kid diterima sampai satu jam ke depan.
+func setJWTEnv(t *testing.T, keys, activeKid, secret string) {
+	t.Helper()
+	prevAccess, prevRefresh, prevIssuer, prevAudience, prevCutoff := accessKeys, refreshKeys, jwtIssuer, jwtAudience, legacyTokenCutoff
+	t.Cleanup(func() {
+		accessKeys, refreshKeys, jwtIssuer, jwtAudience, legacyTokenCutoff = prevAccess, prevRefresh, prevIssuer, prevAudience, prevCutoff
+	})
+
+	for _, prefix := range []string{"JWT_ACCESS", "JWT_REFRESH"} {
+		t.Setenv(prefix+"_KEYS", keys)
+		t.Setenv(prefix+"_ACTIVE_KID", activeKid)
+		t.Setenv(prefix+"_SECRET", secret)
+	}
+	t.Setenv("JWT_ISSUER", "")
+	t.Setenv("JWT_AUDIENCE", "")
+	t.Setenv("JWT_LEGACY_TOKEN_CUTOFF", time.Now().Add(time.Hour).Format(time.RFC3339))
+	if err := InitJWTKeys(); err != nil {
+		t.Fatalf("init jwt keys: %v", err)
+	}
+}
+
+func TestAccessTokenKeyRotation(t *testing.T) {
+	setJWTEnv(t, "old:old-secret", "", "legacy-secret")
+	oldToken, err := GenerateAccessToken("user-1", "user")
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	// token tanpa kid dari sebelum rotasi: ditandatangani *_SECRET dan belum membawa iss/aud
+	legacyToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		UserID: "user-2",
+		Role:   "user",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}).SignedString([]byte("legacy-secret"))
+	if err != nil {
+		t.Fatalf("sign legacy token: %v", err)
+	}
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		UserID:           "user-3",
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	})
+	forged.Header["kid"] = "unknown"
+	forgedToken, err := forged.SignedString([]byte("old-secret"))
+	if err != nil {
+		t.Fatalf("sign forged token: %v", err)
+	}
+
+	// rotasi: key baru aktif, key lama masih diterima
+	setJWTEnv(t, "old:old-secret,new:new-secret", "new", "legacy-secret")
+	newToken, err := GenerateAccessToken("user-4", "admin")
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		wantUser string
+		wantErr  bool
+	}{
+		{name: "token from the active key", token: newToken, wantUser: "user-4"},
+		{name: "token from a retired but configured key", token: oldToken, wantUser: "user-1"},
+		{name: "kid-less token from the legacy secret", token: legacyToken, wantUser: "user-2"},
+		{name: "unknown kid", token: forgedToken, wantErr: true},
+		{name: "garbage", token: "not-a-token", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := DecodeAccessToken(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && claims.UserID != tt.wantUser {
+				t.Errorf("user = %q, want %q", claims.UserID, tt.wantUser)
+			}
+		})
+	}
+
+	// setelah key lama dipensiunkan token-nya ditolak
+	setJWTEnv(t, "new:new-secret", "new", "")
+	if _, err := DecodeAccessToken(oldToken); err == nil {
+		t.Error("expected token from a removed key to be rejected")
+	}
+}
+
+func TestLegacyTokenCutoff(t *testing.T) {
+	setJWTEnv(t, "k1:secret", "", "legacy-secret")
+
+	sign := func(claims jwt.RegisteredClaims) string {
+		t.Helper()
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &claims).SignedString([]byte("legacy-secret"))
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name    string
+		claims  jwt.RegisteredClaims
+		cutoff  time.Time
+		wantErr bool
+	}{
+		{name: "before cutoff without iss/aud", cutoff: time.Now().Add(time.Hour)},
+		{name: "before cutoff with matching iss/aud", claims: jwt.RegisteredClaims{Issuer: "forms-api", Audience: jwt.ClaimStrings{"forms-app"}}, cutoff: time.Now().Add(time.Hour)},
+		{name: "before cutoff with wrong issuer", claims: jwt.RegisteredClaims{Issuer: "someone-else"}, cutoff: time.Now().Add(time.Hour), wantErr: true},
+		{name: "before cutoff with wrong audience", claims: jwt.RegisteredClaims{Audience: jwt.ClaimStrings{"other-app"}}, cutoff: time.Now().Add(time.Hour), wantErr: true},
+		{name: "after cutoff", cutoff: time.Now().Add(-time.Minute), wantErr: true},
+		{name: "no cutoff configured", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			legacyTokenCutoff = tt.cutoff
+			if _, err := DecodeRefreshToken(sign(tt.claims)); (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInitJWTKeysRejectsInvalidCutoff(t *testing.T) {
+	setJWTEnv(t, "k1:secret", "", "")
+	t.Setenv("JWT_LEGACY_TOKEN_CUTOFF", "next week")
+	if err := InitJWTKeys(); err == nil {
+		t.Error("expected an invalid cutoff to be rejected")
+	}
+}
+
+func TestKidTokenRequiresIssuerAndAudience(t *testing.T) {
+	setJWTEnv(t, "k1:secret", "", "")
+
+	tests := []struct {
+		name     string
+		issuer   string
+		audience string
+		wantErr  bool
+	}{
+		{"matching claims", "forms-api", "forms-app", false},
+		{"wrong issuer", "someone-else", "forms-app", true},
+		{"wrong audience", "forms-api", "other-app", true},
+		{"missing claims", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registered := jwt.RegisteredClaims{
+				Issuer:    tt.issuer,
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			}
+			if tt.audience != "" {
+				registered.Audience = jwt.ClaimStrings{tt.audience}
+			}
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, &registered)
+			token.Header["kid"] = "k1"
+			signed, err := token.SignedString([]byte("secret"))
+			if err != nil {
+				t.Fatalf("sign: %v", err)
+			}
+			if _, err := DecodeRefreshToken(signed); (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"server/internal/config"
@@ -32,3 +33,24 @@ func SendEmail(subject, toEmail, plainTextBody, htmlBody string) error {
 
 	return nil
 }
+
+func SendEmailWithAttachment(subject, toEmail, plainTextBody, htmlBody, filename string, attachment []byte) error {
+	m := gomail.NewMessage()
+	from := os.Getenv("USER_EMAIL")
+
+	m.SetHeader("From", fmt.Sprintf("fitness_app <%s>", from))
+	m.SetHeader("To", toEmail)
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/plain", plainTextBody)
+	m.AddAlternative("text/html", htmlBody)
+	m.Attach(filename, gomail.SetCopyFunc(func(w io.Writer) error {
+		_, err := w.Write(attachment)
+		return err
+	}))
+
+	if err := config.MailDialer.DialAndSend(m); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/bits"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRenderToken = errors.New("invalid form render token")
+
+var renderTokenSecret []byte
+
+// InitRenderTokenSecret memuat SUBMISSION_TOKEN_SECRET (minimal 32 karakter) untuk menandatangani
+// render token. Dipanggil saat startup agar server tidak berjalan dengan kunci HMAC kosong.
+func InitRenderTokenSecret() error {
+	raw := os.Getenv("SUBMISSION_TOKEN_SECRET")
+	if len(raw) < 32 {
+		return errors.New("SUBMISSION_TOKEN_SECRET must be set and at least 32 characters long")
+	}
+	renderTokenSecret = []byte(raw)
+	return nil
+}
+
+// render token = base64url("<formID>.<issuedAtUnix>.<nonce>") + "." + base64url(HMAC-SHA256).
+// Diterbitkan saat form dirender dan wajib dikirim kembali bersama submission.
+func SignRenderToken(formID string, issuedAt time.Time) (string, error) {
+	if renderTokenSecret == nil {
+		return "", errors.New("render token secret is not initialized")
+	}
+	nonce, err := GenerateRandomToken(8)
+	if err != nil {
+		return "", err
+	}
+	payload := fmt.Sprintf("%s.%d.%s", formID, issuedAt.Unix(), nonce)
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + signRenderPayload(encoded), nil
+}
+
+// ParseRenderToken memverifikasi signature dan mengembalikan form ID serta waktu render.
+func ParseRenderToken(token string) (string, time.Time, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok || renderTokenSecret == nil || !hmac.Equal([]byte(sig), []byte(signRenderPayload(encoded))) {
+		return "", time.Time{}, ErrInvalidRenderToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", time.Time{}, ErrInvalidRenderToken
+	}
+	parts := strings.Split(string(payload), ".")
+	if len(parts) != 3 {
+		return "", time.Time{}, ErrInvalidRenderToken
+	}
+	issuedAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", time.Time{}, ErrInvalidRenderToken
+	}
+	return parts[0], time.Unix(issuedAt, 0), nil
+}
+
+func signRenderPayload(encoded string) string {
+	mac := hmac.New(sha256.New, renderTokenSecret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyProofOfWork mengecek sha256("<challenge>:<nonce>") diawali minimal `difficulty` bit nol.
+func VerifyProofOfWork(challenge, nonce string, difficulty int) bool {
+	if nonce == "" || len(nonce) > 64 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(challenge + ":" + nonce))
+
+	zeros := 0
+	for _, b := range sum {
+		if b == 0 {
+			zeros += 8
+			continue
+		}
+		zeros += bits.LeadingZeros8(b)
+		break
+	}
+	return zeros >= difficulty
+}
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func initTestRenderSecret(t *testing.T) {
+	t.Helper()
+	t.Setenv("SUBMISSION_TOKEN_SECRET", strings.Repeat("s", 32))
+	if err := InitRenderTokenSecret(); err != nil {
+		t.Fatalf("init render token secret: %v", err)
+	}
+	t.Cleanup(func() { renderTokenSecret = nil })
+}
+
+func TestInitRenderTokenSecret(t *testing.T) {
+	t.Cleanup(func() { renderTokenSecret = nil })
+
+	tests := []struct {
+		name    string
+		secret  string
+		wantErr bool
+	}{
+		{"missing", "", true},
+		{"too short", "short-secret", true},
+		{"long enough", strings.Repeat("x", 32), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SUBMISSION_TOKEN_SECRET", tt.secret)
+			if err := InitRenderTokenSecret(); (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRenderTokenRoundTrip(t *testing.T) {
+	initTestRenderSecret(t)
+
+	issuedAt := time.Unix(1700000000, 0)
+	token, err := SignRenderToken("form-1", issuedAt)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	formID, gotIssuedAt, err := ParseRenderToken(token)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if formID != "form-1" || !gotIssuedAt.Equal(issuedAt) {
+		t.Errorf("got (%s, %v), want (form-1, %v)", formID, gotIssuedAt, issuedAt)
+	}
+}
+
+func TestParseRenderTokenRejectsInvalid(t *testing.T) {
+	initTestRenderSecret(t)
+
+	token, err := SignRenderToken("form-1", time.Now())
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	encoded, sig, _ := strings.Cut(token, ".")
+	other, err := SignRenderToken("form-2", time.Now())
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	otherEncoded, _, _ := strings.Cut(other, ".")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"no signature", encoded},
+		{"payload swapped", otherEncoded + "." + sig},
+		{"signature truncated", encoded + "." + sig[:len(sig)-1]},
+		{"garbage", "abc.def"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := ParseRenderToken(tt.token); !errors.Is(err, ErrInvalidRenderToken) {
+				t.Fatalf("err = %v, want ErrInvalidRenderToken", err)
+			}
+		})
+	}
+}
+
+func TestRenderTokenRequiresSecret(t *testing.T) {
+	renderTokenSecret = nil
+	if _, err := SignRenderToken("form-1", time.Now()); err == nil {
+		t.Fatal("expected sign to fail without a secret")
+	}
+}
+
+func TestVerifyProofOfWork(t *testing.T) {
+	const challenge = "challenge"
+	// cari nonce dengan minimal 8 bit nol di awal agar kasus valid tidak bergantung pada nilai hard-coded
+	nonce := ""
+	for i := 0; i < 1<<16; i++ {
+		if VerifyProofOfWork(challenge, strconv.Itoa(i), 8) {
+			nonce = strconv.Itoa(i)
+			break
+		}
+	}
+	if nonce == "" {
+		t.Fatal("no nonce found for difficulty 8")
+	}
+
+	tests := []struct {
+		name       string
+		challenge  string
+		nonce      string
+		difficulty int
+		want       bool
+	}{
+		{"difficulty zero accepts any nonce", challenge, "x", 0, true},
+		{"solved nonce", challenge, nonce, 8, true},
+		{"impossible difficulty", challenge, nonce, 257, false},
+		{"empty nonce", challenge, "", 0, false},
+		{"nonce too long", challenge, strings.Repeat("1", 65), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyProofOfWork(tt.challenge, tt.nonce, tt.difficulty); got != tt.want {
+				t.Errorf("VerifyProofOfWork = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
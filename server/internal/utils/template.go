@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var emailTemplates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+// RenderEmailTemplate merender template HTML email dari folder templates, contoh: RenderEmailTemplate("otp.html", data).
+func RenderEmailTemplate(name string, data any) (string, error) {
+	var buf bytes.Buffer
+	if err := emailTemplates.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
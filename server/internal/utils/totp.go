@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// TOTP sesuai RFC 6238: HMAC-SHA1, periode 30 detik, 6 digit (default Google Authenticator dkk).
+const (
+	TOTPPeriod = 30
+	totpDigits = 6
+	totpSkew   = 1 // toleransi satu periode sebelum/sesudah untuk selisih jam perangkat
+)
+
+// prefix secret TOTP yang tersimpan terenkripsi; nilai tanpa prefix adalah secret lama (plaintext)
+const totpSecretPrefix = "enc:v1:"
+
+var (
+	totpEncoding  = base32.StdEncoding.WithPadding(base32.NoPadding)
+	totpSecretKey cipher.AEAD
+)
+
+// InitTwoFactorKey menyiapkan kunci AES-256-GCM dari TWO_FACTOR_ENCRYPTION_KEY (minimal 32 karakter)
+// untuk mengenkripsi secret TOTP di database. Dipanggil saat startup.
+func InitTwoFactorKey() error {
+	raw := os.Getenv("TWO_FACTOR_ENCRYPTION_KEY")
+	if len(raw) < 32 {
+		return errors.New("TWO_FACTOR_ENCRYPTION_KEY must be set and at least 32 characters long")
+	}
+	key := sha256.Sum256([]byte(raw))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return err
+	}
+	totpSecretKey, err = cipher.NewGCM(block)
+	return err
+}
+
+func EncryptTOTPSecret(secret string) (string, error) {
+	if totpSecretKey == nil {
+		return "", errors.New("two-factor encryption key is not initialized")
+	}
+	nonce := make([]byte, totpSecretKey.NonceSize())
+	if _, err := crand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := totpSecretKey.Seal(nonce, nonce, []byte(secret), nil)
+	return totpSecretPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptTOTPSecret membuka secret tersimpan; secret lama tanpa prefix dikembalikan apa adanya.
+func DecryptTOTPSecret(stored string) (string, error) {
+	if !IsEncryptedTOTPSecret(stored) {
+		return stored, nil
+	}
+	if totpSecretKey == nil {
+		return "", errors.New("two-factor encryption key is not initialized")
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(stored, totpSecretPrefix))
+	if err != nil || len(sealed) < totpSecretKey.NonceSize() {
+		return "", errors.New("invalid encrypted two-factor secret")
+	}
+	nonce, ciphertext := sealed[:totpSecretKey.NonceSize()], sealed[totpSecretKey.NonceSize():]
+	plain, err := totpSecretKey.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("invalid encrypted two-factor secret")
+	}
+	return string(plain), nil
+}
+
+func IsEncryptedTOTPSecret(stored string) bool {
+	return strings.HasPrefix(stored, totpSecretPrefix)
+}
+
+func GenerateTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+func TOTPCode(secret string, counter uint64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation (RFC 4226 bagian 5.3)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod), nil
+}
+
+// ValidateTOTP mengembalikan counter (time step) yang cocok agar pemanggil bisa menolak kode yang dipakai ulang.
+func ValidateTOTP(secret, code string, now time.Time) (uint64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := uint64(now.Unix()) / TOTPPeriod
+	for i := -totpSkew; i <= totpSkew; i++ {
+		counter := uint64(int64(current) + int64(i))
+		expected, err := TOTPCode(secret, counter)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return counter, true
+		}
+	}
+	return 0, false
+}
+
+// TOTPProvisioningURI membuat URI otpauth:// untuk didaftarkan di aplikasi authenticator.
+func TOTPProvisioningURI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(TOTPPeriod))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// QRCodeDataURI merender teks menjadi PNG QR code dalam bentuk data URI yang bisa langsung dipakai di <img>.
+func QRCodeDataURI(content string) (string, error) {
+	png, err := qrcode.Encode(content, qrcode.Medium, 256)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(png), nil
+}
+
+// GenerateRecoveryCodes membuat kode pemulihan format xxxxx-xxxxx (huruf kecil & angka).
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	const alphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		b := make([]byte, 10)
+		if _, err := crand.Read(b); err != nil {
+			return nil, err
+		}
+		for j := range b {
+			b[j] = alphabet[int(b[j])%len(alphabet)]
+		}
+		codes = append(codes, string(b[:5])+"-"+string(b[5:]))
+	}
+	return codes, nil
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// secret RFC 6238 lampiran B ("12345678901234567890") dalam base32
+const rfcTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// nilai acuan RFC 6238 (SHA1), dipotong ke 6 digit terakhir
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := TOTPCode(rfcTOTPSecret, uint64(tt.unix)/TOTPPeriod)
+		if err != nil {
+			t.Fatalf("unix %d: unexpected error: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("unix %d: code = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestTOTPCodeLowercaseSecret(t *testing.T) {
+	got, err := TOTPCode(strings.ToLower(rfcTOTPSecret), 1)
+	if err != nil || got != "287082" {
+		t.Fatalf("code = %q, err = %v, want 287082", got, err)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := uint64(now.Unix()) / TOTPPeriod
+	codeAt := func(counter uint64) string {
+		code, err := TOTPCode(rfcTOTPSecret, counter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name        string
+		secret      string
+		code        string
+		wantOK      bool
+		wantCounter uint64
+	}{
+		{"current step", rfcTOTPSecret, codeAt(current), true, current},
+		{"previous step within skew", rfcTOTPSecret, codeAt(current - 1), true, current - 1},
+		{"next step within skew", rfcTOTPSecret, codeAt(current + 1), true, current + 1},
+		{"outside skew", rfcTOTPSecret, codeAt(current - 2), false, 0},
+		{"surrounding whitespace", rfcTOTPSecret, " " + codeAt(current) + " ", true, current},
+		{"wrong length", rfcTOTPSecret, "12345", false, 0},
+		{"invalid secret", "not-base32!", "123456", false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter, ok := ValidateTOTP(tt.secret, tt.code, now)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if counter != tt.wantCounter {
+				t.Errorf("counter = %d, want %d", counter, tt.wantCounter)
+			}
+		})
+	}
+}
+
+func TestTOTPSecretEncryption(t *testing.T) {
+	t.Cleanup(func() { totpSecretKey = nil })
+
+	t.Setenv("TWO_FACTOR_ENCRYPTION_KEY", "too-short")
+	if err := InitTwoFactorKey(); err == nil {
+		t.Fatal("expected error for a short key")
+	}
+
+	t.Setenv("TWO_FACTOR_ENCRYPTION_KEY", strings.Repeat("k", 32))
+	if err := InitTwoFactorKey(); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+
+	encrypted, err := EncryptTOTPSecret(rfcTOTPSecret)
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if !IsEncryptedTOTPSecret(encrypted) || strings.Contains(encrypted, rfcTOTPSecret) {
+		t.Fatalf("secret is not encrypted: %q", encrypted)
+	}
+	if len(encrypted) > 255 {
+		t.Errorf("encrypted secret is %d chars, column holds 255", len(encrypted))
+	}
+
+	// ubah satu karakter di tengah ciphertext agar tag GCM tidak lagi cocok
+	mid := len(encrypted) / 2
+	flipped := byte('A')
+	if encrypted[mid] == 'A' {
+		flipped = 'B'
+	}
+	tampered := encrypted[:mid] + string(flipped) + encrypted[mid+1:]
+
+	tests := []struct {
+		name    string
+		stored  string
+		want    string
+		wantErr bool
+	}{
+		{"encrypted secret", encrypted, rfcTOTPSecret, false},
+		{"legacy plaintext secret", rfcTOTPSecret, rfcTOTPSecret, false},
+		{"tampered ciphertext", tampered, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecryptTOTPSecret(tt.stored)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("secret = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}